@@ -0,0 +1,356 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArchiveFormat identifies how a backup is stored on disk.
+type ArchiveFormat string
+
+const (
+	FormatPlain  ArchiveFormat = "plain"   // a straight copy: <name>.sav or a <name>/ folder
+	FormatZip    ArchiveFormat = "zip"     // <name>.zip
+	FormatTarGz  ArchiveFormat = "tar.gz"  // <name>.tar.gz
+	FormatTarZst ArchiveFormat = "tar.zst" // <name>.tar.zst
+	FormatStore  ArchiveFormat = "store"   // <name>.snap pointing at deduplicated blobs in .blobs/
+)
+
+// archiveFormats lists every supported format, in the order shown to users.
+var archiveFormats = []ArchiveFormat{FormatPlain, FormatZip, FormatTarGz, FormatTarZst, FormatStore}
+
+// parseArchiveFormat validates a format name from the config or command line.
+// An empty string means plain.
+func parseArchiveFormat(value string) (ArchiveFormat, error) {
+	if value == "" {
+		return FormatPlain, nil
+	}
+	for _, f := range archiveFormats {
+		if string(f) == value {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown backup format %q (use plain, zip, tar.gz, tar.zst or store)", value)
+}
+
+// archiveExtension returns the file extension used for an archive format.
+// Plain backups use ".sav" for files and no extension for folders.
+func archiveExtension(format ArchiveFormat) string {
+	switch format {
+	case FormatPlain:
+		return ".sav"
+	case FormatStore:
+		return ".snap"
+	}
+	return "." + string(format)
+}
+
+// parseBackupFileName splits a directory entry in the backup directory into a
+// backup name and format. ok is false for entries that aren't backups.
+func parseBackupFileName(fileName string, isDir bool) (name string, format ArchiveFormat, ok bool) {
+	// Hidden entries are never backups.
+	if strings.HasPrefix(fileName, ".") {
+		return "", "", false
+	}
+	if isDir {
+		return fileName, FormatPlain, true
+	}
+	// Check longer extensions first so "x.tar.gz" isn't mistaken for something shorter.
+	for _, f := range []ArchiveFormat{FormatTarZst, FormatTarGz, FormatZip, FormatStore, FormatPlain} {
+		if ext := archiveExtension(f); strings.HasSuffix(fileName, ext) {
+			return strings.TrimSuffix(fileName, ext), f, true
+		}
+	}
+	return "", "", false
+}
+
+// archiveWriter adds files to an archive being written.
+type archiveWriter interface {
+	add(name string, info os.FileInfo, r io.Reader) error
+	Close() error
+}
+
+type zipArchive struct{ w *zip.Writer }
+
+func (a zipArchive) add(name string, info os.FileInfo, r io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	w, err := a.w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (a zipArchive) Close() error { return a.w.Close() }
+
+type tarArchive struct {
+	w          *tar.Writer
+	compressor io.WriteCloser
+}
+
+func (a tarArchive) add(name string, info os.FileInfo, r io.Reader) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := a.w.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(a.w, r)
+	return err
+}
+
+func (a tarArchive) Close() error {
+	if err := a.w.Close(); err != nil {
+		a.compressor.Close()
+		return err
+	}
+	return a.compressor.Close()
+}
+
+func newArchiveWriter(format ArchiveFormat, w io.Writer) (archiveWriter, error) {
+	switch format {
+	case FormatZip:
+		return zipArchive{w: zip.NewWriter(w)}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return tarArchive{w: tar.NewWriter(gz), compressor: gz}, nil
+	case FormatTarZst:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return tarArchive{w: tar.NewWriter(zw), compressor: zw}, nil
+	}
+	return nil, fmt.Errorf("format %s is not an archive format", format)
+}
+
+// writeArchive packs the save into an archive at dst. A save file is stored as
+// a single entry named after the file; a save directory is stored under a
+// top-level folder named after the directory, like "tar -C parent dir".
+func writeArchive(profile Profile, format ArchiveFormat, dst string) (err error) {
+	info, err := os.Stat(profile.SavePath)
+	if err != nil {
+		return fmt.Errorf("failed to read save: %w", err)
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+
+	buffered := bufio.NewWriter(out)
+	archive, err := newArchiveWriter(format, buffered)
+	if err != nil {
+		return err
+	}
+
+	base := filepath.Base(profile.SavePath)
+	if info.IsDir() {
+		err = addTreeToArchive(archive, profile, base)
+	} else {
+		err = addFileToArchive(archive, profile.SavePath, base)
+	}
+	if err != nil {
+		archive.Close()
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func addTreeToArchive(archive archiveWriter, profile Profile, base string) error {
+	files, err := collectSaveFiles(profile, profile.SavePath)
+	if err != nil {
+		return err
+	}
+	for _, rel := range files {
+		if err := addFileToArchive(archive, filepath.Join(profile.SavePath, filepath.FromSlash(rel)), base+"/"+rel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addFileToArchive(archive archiveWriter, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := archive.add(name, info, f); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// Magic numbers used to detect the archive type regardless of file extension.
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// extractArchive unpacks an archive backup into dir and returns the path of the
+// single top-level entry, which is either the save file or the save folder.
+func extractArchive(src, dir string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		info, err := f.Stat()
+		if err != nil {
+			return "", err
+		}
+		err = extractZip(f, info.Size(), dir)
+		if err != nil {
+			return "", err
+		}
+	case bytes.HasPrefix(header, gzipMagic):
+		gz, err := gzip.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return "", fmt.Errorf("failed to open backup: %w", err)
+		}
+		defer gz.Close()
+		if err := extractTar(gz, dir); err != nil {
+			return "", err
+		}
+	case bytes.HasPrefix(header, zstdMagic):
+		zr, err := zstd.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return "", fmt.Errorf("failed to open backup: %w", err)
+		}
+		defer zr.Close()
+		if err := extractTar(zr, dir); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unrecognised archive format: %s", filepath.Base(src))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) != 1 {
+		return "", fmt.Errorf("archive %s should contain exactly one top-level entry, found %d", filepath.Base(src), len(entries))
+	}
+	return filepath.Join(dir, entries[0].Name()), nil
+}
+
+// archiveTarget resolves an archive entry name inside dir, rejecting names that
+// would escape it.
+func archiveTarget(dir, name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("archive contains an unsafe path: %s", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+func extractZip(r io.ReaderAt, size int64, dir string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		target, err := archiveTarget(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = writeExtractedFile(target, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, err := archiveTarget(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := writeExtractedFile(target, tr); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+		}
+	}
+}
+
+func writeExtractedFile(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRoundTrip(t *testing.T) {
+	for _, format := range archiveFormats {
+		t.Run(string(format), func(t *testing.T) {
+			t.Run("single file", func(t *testing.T) {
+				profile := newTestProfile(t)
+				profile.Format = string(format)
+				backup, err := writeBackup(profile, "file", "", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeTestFile(t, profile.SavePath, "slot 2")
+				if _, err := applyBackup(profile, backup, false); err != nil {
+					t.Fatal(err)
+				}
+				if got := readTestFile(t, profile.SavePath); got != "slot 1" {
+					t.Errorf("restored %q, want %q", got, "slot 1")
+				}
+			})
+
+			t.Run("nested folder", func(t *testing.T) {
+				profile := newTestProfile(t)
+				profile.Format = string(format)
+				profile.SavePath = filepath.Join(t.TempDir(), "SaveData")
+				want := map[string]string{
+					"profile.dat":          "options",
+					"slots/1/slot.sav":     "level 12",
+					"slots/2/slot.sav":     "level 3",
+					"slots/2/deep/map.bin": "fog",
+				}
+				for rel, content := range want {
+					writeTestFile(t, filepath.Join(profile.SavePath, filepath.FromSlash(rel)), content)
+				}
+				backup, err := writeBackup(profile, "tree", "", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				writeTestFile(t, filepath.Join(profile.SavePath, "slots/1/slot.sav"), "level 13")
+				writeTestFile(t, filepath.Join(profile.SavePath, "slots/3/slot.sav"), "new")
+				if _, err := applyBackup(profile, backup, false); err != nil {
+					t.Fatal(err)
+				}
+				if got := readTestTree(t, profile.SavePath); !maps.Equal(got, want) {
+					t.Errorf("restored %v, want %v", got, want)
+				}
+			})
+
+			t.Run("empty folder", func(t *testing.T) {
+				profile := newTestProfile(t)
+				profile.Format = string(format)
+				profile.SavePath = filepath.Join(t.TempDir(), "SaveData")
+				if err := os.MkdirAll(filepath.Join(profile.SavePath, "slots"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := writeBackup(profile, "empty", "", nil); !errors.Is(err, errEmptySave) {
+					t.Errorf("got %v, want errEmptySave", err)
+				}
+
+				writeTestFile(t, filepath.Join(profile.SavePath, "slots", "slot.bak"), "old")
+				profile.Exclude = []string{"*.bak"}
+				if _, err := writeBackup(profile, "filtered", "", nil); !errors.Is(err, errEmptySave) {
+					t.Errorf("every file filtered out: got %v, want errEmptySave", err)
+				}
+				if got := backupNames(t, profile); len(got) != 0 {
+					t.Errorf("got backups %v, want none", got)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,1557 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// errUsage marks errors caused by bad command-line usage rather than a failed operation.
+var errUsage = errors.New("invalid usage")
+
+const cliUsage = `Usage: backup_manager [command] [arguments]
+
+Run without a command to open the full-screen interface; press ? in it for
+the keys.
+
+Commands:
+  create [--name NAME] [--note TEXT] [--tags A,B] [--format FORMAT] [--ignore-game]
+         [--allow-duplicate]
+                                  Create a backup of the save file
+  restore NAME [--yes] [--force] [--ignore-game] [--mirror MIRROR] [--to PATH] [--diff]
+                                  Restore a backup over the save file; --force restores
+                                  even if the backup fails verification or the
+                                  auto-backup of the current save fails; --to writes it
+                                  to PATH (or into PATH if it is a folder) instead;
+                                  --diff shows what would change before asking
+  diff NAME [OTHER]               Compare a backup with another backup or, without
+                                  OTHER, with the current save
+  undo [--yes] [--ignore-game]    Put back the save that the last restore overwrote
+  history                         List recent restores, newest first
+  list [--mirror MIRROR] [--output FORMAT]
+                                  List all backups, newest first
+  show NAME [--mirror MIRROR] [--output FORMAT]
+                                  Print a backup's metadata
+  status [--output FORMAT]        Summarize the save, the backups, the last restore
+                                  and the mirrors
+  verify [--output FORMAT]        Re-hash every backup and report damaged or missing ones
+  export NAME... --output FILE    Bundle backups and their metadata into a zip archive
+  import FILE                     Add the backups in an exported archive
+  prune [--dry-run]               Delete backups not kept by the retention policy
+  delete NAME... [--yes]          Permanently delete one or more backups; pinned
+                                  backups are skipped
+  rename NAME NEW_NAME            Rename a backup, keeping its metadata; a numeric
+                                  suffix is added if NEW_NAME is taken
+  edit NAME [--note TEXT] [--tags A,B]
+                                  Replace a backup's note or tags
+  pin NAME...                     Protect backups from pruning and bulk delete
+  unpin NAME...                   Remove that protection again
+  watch [--debounce D] [--min-interval D] [--poll] [--poll-interval D]
+                                  Keep running and back up the save whenever it changes
+  daemon                          Keep running and back up every profile with a
+                                  schedule when it is due; with --game, only that one
+  serve [--listen ADDR]           Serve a REST API and web UI for managing backups
+                                  from another device (default 127.0.0.1:8765)
+  sync [--dry-run] [--mirror MIRROR]
+                                  Copy backups missing on either side between the
+                                  backup directory and its mirrors
+  mirror list                     List mirrors and whether they are in sync
+  mirror add NAME TARGET          Add a mirror: a directory, s3://bucket/prefix or
+                                  sftp://user@host/path
+  mirror set NAME KEY VALUE       Change a mirror's storage_* setting
+  mirror remove NAME              Remove a mirror (the backups on it are kept)
+  config show                     Print the current configuration
+  config set KEY VALUE            Change a setting (save_path, backup_dir, auto_backup,
+                                  include, exclude, format, encryption, key_file,
+                                  stable_for, duplicates, game_executable,
+                                  game_restore, game_backup, watch_debounce,
+                                  watch_min_interval, schedule, the storage keys
+                                  storage_type, storage_path, storage_endpoint,
+                                  storage_bucket, storage_region,
+                                  storage_access_key, storage_secret_key,
+                                  storage_insecure, storage_host, storage_user,
+                                  storage_password, storage_key_file,
+                                  storage_known_hosts and the
+                                  retention keys keep_last, keep_days, keep_daily,
+                                  keep_weekly, keep_monthly; prefix a retention key
+                                  with auto_ to set the limit for auto-backups)
+  profile list                    List game profiles
+  profile add NAME --save-path PATH --backup-dir DIR
+                                  Add a game profile
+  profile use NAME                Make a game profile the active one
+  profile remove NAME             Remove a game profile (its backups are kept)
+  menu                            Open the classic numbered menus instead of the
+                                  full-screen interface
+  help                            Show this help
+
+Encrypted backups read their passphrase from $BACKUP_MANAGER_PASSPHRASE, then
+from the profile's key_file, and otherwise prompt for it.
+
+storage_type keeps backups in backup_dir (local), an S3-compatible bucket (s3)
+or a folder on an SFTP server (sftp). S3 credentials fall back to
+$AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY; the SFTP password can come from
+$BACKUP_MANAGER_SFTP_PASSWORD.
+
+Every new backup is copied to the profile's mirrors, and deleted or pruned
+backups are removed from them. If the backup directory is lost, list and
+restore with --mirror read straight from a mirror, and sync copies everything
+back.
+
+game_restore and game_backup choose what happens while the game is running:
+off, warn, block or wait. --ignore-game skips the check for one command.
+
+Every restore is recorded in the restore history together with the backup
+that holds the save it overwrote. That backup is never pruned while undo needs
+it; running undo twice restores the backup again.
+
+schedule is an interval (15m, every 2h), daily HH:MM, a five-field cron
+expression ("0 */6 * * *") or @hourly, @daily, @weekly or @monthly; off
+clears it. daemon skips a run when the save is unchanged since the latest
+backup, applies retention after every backup and logs each outcome.
+
+serve requires the token it prints (or $BACKUP_MANAGER_TOKEN) as an
+"Authorization: Bearer TOKEN" header on every API request. Use
+--listen 0.0.0.0:8765 to reach it from a phone or another PC on the LAN.
+
+list, show, status and verify print a table by default; --output json prints
+full records (name, path, timestamps, size, hashes, tags, profile) and
+--output csv the same fields as rows, for scripts and dashboards.
+
+The create, restore, undo, history, list, show, status, diff, export, import,
+verify, prune, delete, rename, edit, pin, unpin, watch, daemon, serve, sync, mirror
+and config commands accept --game PROFILE to act on a profile other than the active one.
+`
+
+// runCLI executes a single subcommand and returns the process exit code.
+func runCLI(args []string) int {
+	var err error
+	switch args[0] {
+	case "create":
+		err = cmdCreate(args[1:])
+	case "restore":
+		err = cmdRestore(args[1:])
+	case "undo":
+		err = cmdUndo(args[1:])
+	case "history":
+		err = cmdHistory(args[1:])
+	case "list":
+		err = cmdList(args[1:])
+	case "show":
+		err = cmdShow(args[1:])
+	case "status":
+		err = cmdStatus(args[1:])
+	case "diff":
+		err = cmdDiff(args[1:])
+	case "verify":
+		err = cmdVerify(args[1:])
+	case "export":
+		err = cmdExport(args[1:])
+	case "import":
+		err = cmdImport(args[1:])
+	case "prune":
+		err = cmdPrune(args[1:])
+	case "delete":
+		err = cmdDelete(args[1:])
+	case "rename":
+		err = cmdRename(args[1:])
+	case "edit":
+		err = cmdEdit(args[1:])
+	case "pin":
+		err = cmdPin(args[1:], true)
+	case "unpin":
+		err = cmdPin(args[1:], false)
+	case "watch":
+		err = cmdWatch(args[1:])
+	case "daemon":
+		err = cmdDaemon(args[1:])
+	case "serve":
+		err = cmdServe(args[1:])
+	case "sync":
+		err = cmdSync(args[1:])
+	case "mirror":
+		err = cmdMirror(args[1:])
+	case "config":
+		err = cmdConfig(args[1:])
+	case "profile":
+		err = cmdProfile(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(cliUsage)
+		return 0
+	default:
+		err = fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+	}
+
+	if err == nil {
+		return 0
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%s %s %v\n", iconError, red("ERROR:"), err)
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(os.Stderr, "\n"+cliUsage)
+		return 2
+	}
+	return 1
+}
+
+// newFlagSet returns a flag set that reports errors to the caller instead of exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() { fmt.Print(cliUsage) }
+	return fs
+}
+
+// parseArgs parses flags that may appear before, between or after positional
+// arguments, and returns the positional arguments in order.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				fs.Usage()
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// gameFlag registers the --game flag shared by every command.
+func gameFlag(fs *flag.FlagSet) *string {
+	return fs.String("game", "", "game profile to use instead of the active one")
+}
+
+// mirrorFlag registers the --mirror flag of the commands that can read backups
+// from a mirror instead of the profile's own storage.
+func mirrorFlag(fs *flag.FlagSet) *string {
+	return fs.String("mirror", "", "read backups from this mirror instead of the backup directory")
+}
+
+// loadCLIConfig loads the existing configuration. Unlike loadConfig it never
+// starts the interactive first-time setup.
+func loadCLIConfig() (Config, string, error) {
+	configPath, err := defaultConfigPath()
+	if err != nil {
+		return Config{}, "", err
+	}
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return Config{}, "", fmt.Errorf("no configuration found at %s - run without arguments once to complete setup", configPath)
+	}
+	config, err := readConfig(configPath)
+	if err != nil {
+		return Config{}, "", err
+	}
+	return config, configPath, nil
+}
+
+// loadCLIProfile loads the configuration and resolves the profile selected by
+// --game, falling back to the active profile when game is empty. The returned
+// pointer refers into the returned config so edits can be saved back.
+func loadCLIProfile(game string) (*Config, string, *Profile, error) {
+	config, configPath, err := loadCLIConfig()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if game == "" {
+		game = config.ActiveProfile
+	}
+	profile, err := config.profile(game)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return &config, configPath, profile, nil
+}
+
+// confirmCLI asks for a y/N confirmation unless assumeYes is set.
+func confirmCLI(prompt string, assumeYes bool) bool {
+	if assumeYes {
+		return true
+	}
+	answer, err := promptForInput(prompt)
+	return err == nil && strings.ToLower(answer) == "y"
+}
+
+func cmdCreate(args []string) error {
+	fs := newFlagSet("create")
+	game := gameFlag(fs)
+	name := fs.String("name", "", "backup name")
+	note := fs.String("note", "", "free-text note stored with the backup")
+	tags := fs.String("tags", "", "comma-separated tags stored with the backup")
+	format := fs.String("format", "", "archive format for this backup (plain, zip, tar.gz, tar.zst, store)")
+	ignoreGame := fs.Bool("ignore-game", false, "don't check whether the game is running")
+	allowDuplicate := fs.Bool("allow-duplicate", false, "create the backup even if the save is unchanged")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: create takes no arguments, use --name", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(profile.SavePath); os.IsNotExist(err) {
+		return fmt.Errorf("save file not found at: %s", profile.SavePath)
+	}
+
+	if *format != "" {
+		if err := setProfileValue(profile, "format", *format); err != nil {
+			return err
+		}
+	}
+
+	var confirm func(prompt string) bool
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		confirm = func(prompt string) bool { return confirmCLI(prompt, false) }
+	}
+	if !*allowDuplicate && !checkDuplicate(*profile, confirm) {
+		return nil
+	}
+	if !*ignoreGame {
+		if err := checkGameRunning(*profile, profile.GameCheck.backupPolicy(), "back up"); err != nil {
+			return err
+		}
+	}
+
+	backup, err := writeBackup(*profile, strings.TrimSpace(*name), strings.TrimSpace(*note), parseTags(*tags))
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	fmt.Printf("%s %s Backup created: %s\n", iconSuccess, green("SUCCESS:"), backup.Name)
+	reportAutoPrune(*profile)
+	reportMirrors(*profile)
+	return nil
+}
+
+func cmdRestore(args []string) error {
+	fs := newFlagSet("restore")
+	game := gameFlag(fs)
+	mirror := mirrorFlag(fs)
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	force := fs.Bool("force", false, "restore even if the backup fails verification or the current save can't be backed up first")
+	ignoreGame := fs.Bool("ignore-game", false, "don't check whether the game is running")
+	to := fs.String("to", "", "write the backup to this path instead of over the save")
+	showDiff := fs.Bool("diff", false, "show what the restore would change before asking")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("%w: restore needs exactly one backup name", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	st, err := backupSource(*profile, *mirror)
+	if err != nil {
+		return err
+	}
+	backup, err := findBackupIn(st, rest[0])
+	if err != nil {
+		return err
+	}
+
+	if *to != "" {
+		// Nothing is overwritten, so there is nothing to confirm or undo.
+		dest, err := filepath.Abs(*to)
+		if err != nil {
+			return err
+		}
+		target, err := restoreTo(*profile, backup, dest, *force)
+		if err != nil {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+		fmt.Printf("%s %s Backup %s written to: %s\n", iconSuccess, green("SUCCESS:"), backup.Name, target)
+		return nil
+	}
+
+	if !*ignoreGame {
+		if err := checkGameRunning(*profile, profile.GameCheck.restorePolicy(), "restore"); err != nil {
+			return err
+		}
+	}
+
+	if *showDiff {
+		if err := compareWithSave(*profile, backup); err != nil {
+			return fmt.Errorf("failed to compare: %w", err)
+		}
+		fmt.Println()
+	}
+
+	if !confirmCLI(fmt.Sprintf("Overwrite %s with backup %s? (y/N)", profile.SavePath, backup.Name), *yes) {
+		return errors.New("restore cancelled")
+	}
+
+	autoBackupName, err := applyBackup(*profile, backup, *force)
+	if autoBackupName != "" {
+		fmt.Printf("%s %s Auto-backup of current save created: %s\n", iconSuccess, green("SUCCESS:"), autoBackupName)
+		reportAutoPrune(*profile)
+		reportMirrors(*profile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+	fmt.Printf("%s %s Backup restored: %s\n", iconSuccess, green("SUCCESS:"), backup.Name)
+	return nil
+}
+
+func cmdUndo(args []string) error {
+	fs := newFlagSet("undo")
+	game := gameFlag(fs)
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	ignoreGame := fs.Bool("ignore-game", false, "don't check whether the game is running")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: undo takes no arguments", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	last, backup, err := planUndo(*profile)
+	if err != nil {
+		return err
+	}
+
+	if !*ignoreGame {
+		if err := checkGameRunning(*profile, profile.GameCheck.restorePolicy(), "restore"); err != nil {
+			return err
+		}
+	}
+
+	if !confirmCLI(fmt.Sprintf("Undo the restore of %s by restoring %s? (y/N)", last.Backup, backup.Name), *yes) {
+		return errors.New("undo cancelled")
+	}
+
+	autoBackupName, err := undoLastRestore(*profile)
+	if autoBackupName != "" {
+		fmt.Printf("%s %s Auto-backup of current save created: %s\n", iconSuccess, green("SUCCESS:"), autoBackupName)
+		reportAutoPrune(*profile)
+		reportMirrors(*profile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to undo the restore: %w", err)
+	}
+	fmt.Printf("%s %s Restore of %s undone, save restored from %s\n", iconSuccess, green("SUCCESS:"), last.Backup, backup.Name)
+	return nil
+}
+
+func cmdHistory(args []string) error {
+	fs := newFlagSet("history")
+	game := gameFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: history takes no arguments", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	history, err := readHistory(*profile)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESTORED AT\tBACKUP\tPREVIOUS SAVE\tSTATUS")
+	for i := len(history) - 1; i >= 0; i-- {
+		r := history[i]
+		savedAs, status := r.SavedAs, ""
+		if savedAs == "" {
+			savedAs = "-"
+		}
+		switch {
+		case r.Undone:
+			status = "undone"
+		case r.Undo:
+			status = "undo"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Time.Format("01/02/2006 03:04:05 PM"), r.Backup, savedAs, status)
+	}
+	return w.Flush()
+}
+
+func cmdList(args []string) error {
+	fs := newFlagSet("list")
+	game := gameFlag(fs)
+	mirror := mirrorFlag(fs)
+	output := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: list takes no arguments", errUsage)
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return err
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	st, err := backupSource(*profile, *mirror)
+	if err != nil {
+		return err
+	}
+	backups, err := listBackupsIn(st)
+	if err != nil {
+		return err
+	}
+
+	records := make([]BackupRecord, len(backups))
+	for i, b := range backups {
+		records[i] = newBackupRecord(*profile, b)
+	}
+	switch format {
+	case OutputJSON:
+		return writeJSON(records)
+	case OutputCSV:
+		rows := make([][]string, len(records))
+		for i, r := range records {
+			rows[i] = r.csvRow()
+		}
+		return writeRows(format, backupCSVHeader, rows)
+	}
+
+	rows := make([][]string, len(backups))
+	for i, b := range backups {
+		size, tags, note := "-", "", ""
+		if b.Meta != nil {
+			size = formatSize(b.Meta.Size)
+			tags = strings.Join(b.Meta.Tags, ",")
+			note = b.Meta.Note
+		}
+		rows[i] = []string{b.Name, b.CreatedAt.Format("01/02/2006 03:04:05 PM"), b.formatLabel(), size, formatPinned(b), tags, note}
+	}
+	return writeRows(format, []string{"NAME", "CREATED", "FORMAT", "SIZE", "PINNED", "TAGS", "NOTE"}, rows)
+}
+
+func cmdShow(args []string) error {
+	fs := newFlagSet("show")
+	game := gameFlag(fs)
+	mirror := mirrorFlag(fs)
+	output := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("%w: show needs exactly one backup name", errUsage)
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return err
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	st, err := backupSource(*profile, *mirror)
+	if err != nil {
+		return err
+	}
+	backup, err := findBackupIn(st, rest[0])
+	if err != nil {
+		return err
+	}
+	switch format {
+	case OutputJSON:
+		return writeJSON(newBackupRecord(*profile, backup))
+	case OutputCSV:
+		return writeRows(format, backupCSVHeader, [][]string{newBackupRecord(*profile, backup).csvRow()})
+	}
+	printBackupDetails(backup)
+	return nil
+}
+
+func cmdStatus(args []string) error {
+	fs := newFlagSet("status")
+	game := gameFlag(fs)
+	output := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: status takes no arguments", errUsage)
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return err
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	report, err := profileStatus(*profile)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case OutputJSON:
+		return writeJSON(report)
+	case OutputCSV:
+		fields := report.fields(false)
+		header, row := make([]string, len(fields)), make([]string, len(fields))
+		for i, f := range fields {
+			header[i], row[i] = f[0], f[1]
+		}
+		return writeRows(format, header, [][]string{row})
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, f := range report.fields(true) {
+		if f[1] == "" {
+			f[1] = "-"
+		}
+		fmt.Fprintf(w, "%s:\t%s\n", f[0], f[1])
+	}
+	return w.Flush()
+}
+
+func cmdDiff(args []string) error {
+	fs := newFlagSet("diff")
+	game := gameFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 || len(rest) > 2 {
+		return fmt.Errorf("%w: diff needs one or two backup names", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	backup, err := findBackup(*profile, rest[0])
+	if err != nil {
+		return err
+	}
+	var other *Backup
+	if len(rest) == 2 {
+		b, err := findBackup(*profile, rest[1])
+		if err != nil {
+			return err
+		}
+		other = &b
+	}
+	return compareBackups(*profile, backup, other)
+}
+
+func cmdExport(args []string) error {
+	fs := newFlagSet("export")
+	game := gameFlag(fs)
+	output := fs.String("output", "", "path of the zip archive to create")
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%w: export needs at least one backup name", errUsage)
+	}
+	if *output == "" {
+		return fmt.Errorf("%w: export needs --output FILE", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	backups := make([]Backup, 0, len(names))
+	for _, name := range names {
+		backup, err := findBackup(*profile, name)
+		if err != nil {
+			return err
+		}
+		backups = append(backups, backup)
+	}
+	dst, err := filepath.Abs(*output)
+	if err != nil {
+		return err
+	}
+	if err := exportBackups(*profile, backups, dst); err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	fmt.Printf("%s %s Exported %d backup(s) to: %s\n", iconSuccess, green("SUCCESS:"), len(backups), dst)
+	return nil
+}
+
+func cmdImport(args []string) error {
+	fs := newFlagSet("import")
+	game := gameFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("%w: import needs exactly one archive", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	result, err := importBackups(*profile, rest[0])
+	printImportResult(result)
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+	if len(result.Imported) > 0 {
+		reportMirrors(*profile)
+	}
+	if len(result.Skipped) > 0 {
+		return fmt.Errorf("%d backup(s) were not imported", len(result.Skipped))
+	}
+	return nil
+}
+
+func cmdVerify(args []string) error {
+	fs := newFlagSet("verify")
+	game := gameFlag(fs)
+	output := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: verify takes no arguments", errUsage)
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return err
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	results, err := verifyStore(*profile)
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		rows[i] = []string{r.Name, string(r.Status), r.Detail}
+		if !r.healthy() {
+			problems++
+		}
+	}
+	switch format {
+	case OutputJSON:
+		if results == nil {
+			results = []VerifyResult{}
+		}
+		err = writeJSON(results)
+	case OutputCSV:
+		for i, r := range results {
+			rows[i] = []string{r.Name, r.Path, string(r.Status), r.Detail}
+		}
+		err = writeRows(format, []string{"name", "path", "status", "detail"}, rows)
+	default:
+		err = writeRows(format, []string{"NAME", "STATUS", "DETAIL"}, rows)
+	}
+	if err != nil {
+		return err
+	}
+	if problems > 0 {
+		return fmt.Errorf("%d of %d backup(s) are damaged or missing", problems, len(results))
+	}
+	return nil
+}
+
+func cmdPrune(args []string) error {
+	fs := newFlagSet("prune")
+	game := gameFlag(fs)
+	dryRun := fs.Bool("dry-run", false, "list what would be deleted without deleting anything")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: prune takes no arguments", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		doomed, err := planPrune(*profile, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, b := range doomed {
+			fmt.Printf("would delete: %s\n", b.Name)
+		}
+		fmt.Printf("%s %s %d backup(s) would be pruned.\n", iconInfo, white("INFO:"), len(doomed))
+		return nil
+	}
+
+	removed, err := pruneBackups(*profile)
+	for _, b := range removed {
+		fmt.Printf("%s %s Deleted: %s\n", iconDelete, green("SUCCESS:"), b.Name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s %d backup(s) pruned.\n", iconSuccess, green("SUCCESS:"), len(removed))
+	return nil
+}
+
+func cmdDelete(args []string) error {
+	fs := newFlagSet("delete")
+	game := gameFlag(fs)
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%w: delete needs at least one backup name", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+
+	// Resolve every name first so a typo doesn't leave a partial deletion.
+	backups := make([]Backup, 0, len(names))
+	for _, name := range names {
+		backup, err := findBackup(*profile, name)
+		if err != nil {
+			return err
+		}
+		backups = append(backups, backup)
+	}
+	unpinned := pinnedSkipped(backups)
+	skipped := len(backups) - len(unpinned)
+	backups = unpinned
+	if len(backups) == 0 {
+		return fmt.Errorf("%d pinned backup(s) were not deleted", skipped)
+	}
+
+	if !confirmCLI(fmt.Sprintf("Permanently delete %d backup(s)? (y/N)", len(backups)), *yes) {
+		return errors.New("deletion cancelled")
+	}
+
+	failed := 0
+	var deleted []Backup
+	for _, backup := range backups {
+		if err := removeBackup(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s Failed to delete %s: %v\n", iconError, red("ERROR:"), backup.Name, err)
+			failed++
+			continue
+		}
+		deleted = append(deleted, backup)
+		fmt.Printf("%s %s Deleted: %s\n", iconDelete, green("SUCCESS:"), backup.Name)
+	}
+	reportGarbage(*profile)
+	reportMirrorRemoval(*profile, deleted)
+	if failed > 0 {
+		return fmt.Errorf("%d backup(s) could not be deleted", failed)
+	}
+	if skipped > 0 {
+		return fmt.Errorf("%d pinned backup(s) were not deleted", skipped)
+	}
+	return nil
+}
+
+func cmdRename(args []string) error {
+	fs := newFlagSet("rename")
+	game := gameFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 {
+		return fmt.Errorf("%w: rename needs a backup name and a new name", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	backup, err := findBackup(*profile, rest[0])
+	if err != nil {
+		return err
+	}
+	renamed, err := renameBackup(*profile, backup, rest[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s Renamed %s to %s\n", iconSuccess, green("SUCCESS:"), backup.Name, renamed.Name)
+	reportMirrorUpdate(*profile, backup.Key, renamed)
+	return nil
+}
+
+func cmdEdit(args []string) error {
+	fs := newFlagSet("edit")
+	game := gameFlag(fs)
+	note := fs.String("note", "", "replace the backup's note (empty clears it)")
+	tags := fs.String("tags", "", "replace the backup's comma-separated tags (empty clears them)")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("%w: edit needs exactly one backup name", errUsage)
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if !set["note"] && !set["tags"] {
+		return fmt.Errorf("%w: edit needs --note or --tags", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	backup, err := findBackup(*profile, rest[0])
+	if err != nil {
+		return err
+	}
+	updated, err := editBackup(*profile, backup, func(m *Manifest) {
+		if set["note"] {
+			m.Note = strings.TrimSpace(*note)
+		}
+		if set["tags"] {
+			m.Tags = parseTags(*tags)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s Updated %s\n", iconSuccess, green("SUCCESS:"), updated.Name)
+	reportMirrorUpdate(*profile, updated.Key, updated)
+	return nil
+}
+
+// cmdPin pins or unpins backups.
+func cmdPin(args []string, pinned bool) error {
+	command := "pin"
+	if !pinned {
+		command = "unpin"
+	}
+	fs := newFlagSet(command)
+	game := gameFlag(fs)
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%w: %s needs at least one backup name", errUsage, command)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	backups := make([]Backup, 0, len(names))
+	for _, name := range names {
+		backup, err := findBackup(*profile, name)
+		if err != nil {
+			return err
+		}
+		backups = append(backups, backup)
+	}
+	for _, backup := range backups {
+		updated, err := editBackup(*profile, backup, func(m *Manifest) { m.Pinned = pinned })
+		if err != nil {
+			return err
+		}
+		if pinned {
+			fmt.Printf("%s %s Pinned: %s\n", iconSuccess, green("SUCCESS:"), updated.Name)
+		} else {
+			fmt.Printf("%s %s Unpinned: %s\n", iconSuccess, green("SUCCESS:"), updated.Name)
+		}
+		reportMirrorUpdate(*profile, updated.Key, updated)
+	}
+	return nil
+}
+
+func cmdWatch(args []string) error {
+	fs := newFlagSet("watch")
+	game := gameFlag(fs)
+	debounce := fs.String("debounce", "", "quiet time after the last change before backing up")
+	minInterval := fs.String("min-interval", "", "minimum time between two backups")
+	poll := fs.Bool("poll", false, "poll for changes instead of using file notifications")
+	pollInterval := fs.Duration("poll-interval", defaultWatchPollInterval, "how often to check for changes when polling")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: watch takes no arguments", errUsage)
+	}
+	if *pollInterval <= 0 {
+		return fmt.Errorf("%w: --poll-interval must be positive", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	settings := profile.Watch
+	if *debounce != "" {
+		settings.Debounce = *debounce
+	}
+	if *minInterval != "" {
+		settings.MinInterval = *minInterval
+	}
+	opts, err := settings.options()
+	if err != nil {
+		return err
+	}
+	opts.Poll = *poll
+	opts.PollInterval = *pollInterval
+	if profile.Encryption.Enabled {
+		// Ask now rather than at the first change.
+		if _, err := backupPassphrase(*profile, true); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return watchSave(ctx, *profile, opts)
+}
+
+func cmdDaemon(args []string) error {
+	fs := newFlagSet("daemon")
+	game := gameFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: daemon takes no arguments", errUsage)
+	}
+
+	config, _, err := loadCLIConfig()
+	if err != nil {
+		return err
+	}
+	candidates := config.Profiles
+	if *game != "" {
+		profile, err := config.profile(*game)
+		if err != nil {
+			return err
+		}
+		candidates = []Profile{*profile}
+	}
+	var profiles []Profile
+	for _, p := range candidates {
+		if p.Schedule != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	if len(profiles) == 0 {
+		return errors.New(`no profile has a backup schedule; set one with "config set schedule VALUE", e.g. "daily 03:00"`)
+	}
+	for _, p := range profiles {
+		if p.Encryption.Enabled {
+			// Ask now rather than at the first scheduled run.
+			if _, err := backupPassphrase(p, true); err != nil {
+				return fmt.Errorf("profile %s: %w", p.Name, err)
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return runDaemon(ctx, profiles)
+}
+
+func cmdServe(args []string) error {
+	fs := newFlagSet("serve")
+	game := gameFlag(fs)
+	listen := fs.String("listen", defaultServeAddr, "address to serve the API and web UI on")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: serve takes no arguments", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	if profile.Encryption.Enabled {
+		// Ask now; requests can't prompt.
+		if _, err := backupPassphrase(*profile, true); err != nil {
+			return err
+		}
+	}
+	token, err := serveToken()
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+
+	if !isLoopback(*listen) {
+		fmt.Printf("%s %s The API is reachable from other machines on the network; keep the token secret.\n", iconError, yellow("WARNING:"))
+	}
+	fmt.Printf("%s %s Serving %s on http://%s (Ctrl+C to stop)\n", iconSuccess, green("INFO:"), profile.Name, ln.Addr())
+	fmt.Printf("%s %s Token: %s\n", iconInfo, white("INFO:"), token)
+	fmt.Printf("%s %s Web UI: http://%s/#token=%s\n", iconInfo, white("INFO:"), ln.Addr(), token)
+
+	server := &http.Server{Handler: newAPIServer(*game, token), ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func cmdSync(args []string) error {
+	fs := newFlagSet("sync")
+	game := gameFlag(fs)
+	dryRun := fs.Bool("dry-run", false, "list what would be copied without copying anything")
+	only := fs.String("mirror", "", "sync only this mirror")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: sync takes no arguments", errUsage)
+	}
+
+	_, _, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+	mirrors := profile.Mirrors
+	if *only != "" {
+		m, err := findMirror(*profile, *only)
+		if err != nil {
+			return err
+		}
+		mirrors = []Mirror{*m}
+	}
+	if len(mirrors) == 0 {
+		return fmt.Errorf("%s has no mirrors - add one with mirror add", profile.Name)
+	}
+
+	failed := 0
+	for _, m := range mirrors {
+		status := syncMirror(*profile, m, true, *dryRun)
+		printSyncStatus(status, *dryRun)
+		if status.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d mirror(s) could not be synced", failed)
+	}
+	return nil
+}
+
+func cmdMirror(args []string) error {
+	fs := newFlagSet("mirror")
+	game := gameFlag(fs)
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%w: mirror needs a subcommand (list, add, set or remove)", errUsage)
+	}
+
+	config, configPath, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+
+	var done string
+	switch args[0] {
+	case "list":
+		if len(args) != 1 {
+			return fmt.Errorf("%w: mirror list takes no arguments", errUsage)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tLOCATION\tSTATUS")
+		for _, m := range profile.Mirrors {
+			status := syncMirror(*profile, m, true, true)
+			state := "in sync"
+			switch {
+			case status.Err != nil:
+				state = status.Err.Error()
+			case len(status.Pushed) > 0 || len(status.Pulled) > 0 || len(status.Removed) > 0:
+				state = fmt.Sprintf("%d missing on the mirror, %d only on the mirror", len(status.Pushed), len(status.Pulled)+len(status.Removed))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", m.Name, describeMirror(m), state)
+		}
+		return w.Flush()
+	case "add":
+		if len(args) != 3 {
+			return fmt.Errorf("%w: mirror add needs a name and a target", errUsage)
+		}
+		if _, err := findMirror(*profile, args[1]); err == nil {
+			return fmt.Errorf("a mirror named %s already exists", args[1])
+		}
+		settings, err := parseMirrorTarget(args[2])
+		if err != nil {
+			return err
+		}
+		if settings.Type == "" && filepath.Clean(settings.Path) == filepath.Clean(profile.BackupDir) {
+			return errors.New("a mirror can't be the backup directory itself")
+		}
+		profile.Mirrors = append(profile.Mirrors, Mirror{Name: args[1], StorageSettings: settings})
+		done = "added"
+	case "set":
+		if len(args) != 4 {
+			return fmt.Errorf("%w: mirror set needs a name, a key and a value", errUsage)
+		}
+		m, err := findMirror(*profile, args[1])
+		if err != nil {
+			return err
+		}
+		ok, err := setStorageValue(&m.StorageSettings, args[2], strings.TrimSpace(args[3]))
+		if !ok {
+			return fmt.Errorf("%w: unknown mirror key %q", errUsage, args[2])
+		}
+		if err != nil {
+			return err
+		}
+		done = "updated"
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: mirror remove needs a name", errUsage)
+		}
+		index := slices.IndexFunc(profile.Mirrors, func(m Mirror) bool { return m.Name == args[1] })
+		if index < 0 {
+			return fmt.Errorf("mirror not found: %s", args[1])
+		}
+		profile.Mirrors = slices.Delete(profile.Mirrors, index, index+1)
+		done = "removed (the backups on it are kept)"
+	default:
+		return fmt.Errorf("%w: unknown mirror subcommand %q", errUsage, args[0])
+	}
+
+	if err := saveConfig(*config, configPath); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("%s %s Mirror %s %s\n", iconSuccess, green("SUCCESS:"), args[1], done)
+	return nil
+}
+
+func cmdConfig(args []string) error {
+	fs := newFlagSet("config")
+	game := gameFlag(fs)
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%w: config needs a subcommand (show or set)", errUsage)
+	}
+
+	config, configPath, profile, err := loadCLIProfile(*game)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "show":
+		if len(args) != 1 {
+			return fmt.Errorf("%w: config show takes no arguments", errUsage)
+		}
+		fmt.Printf("config_file:    %s\n", configPath)
+		fmt.Printf("active_profile: %s\n", config.ActiveProfile)
+		fmt.Printf("profile:        %s\n", profile.Name)
+		fmt.Printf("save_path:      %s\n", profile.SavePath)
+		fmt.Printf("backup_dir:     %s\n", profile.BackupDir)
+		fmt.Printf("storage:        %s\n", describeStorage(*profile))
+		fmt.Printf("mirrors:        %s\n", describeMirrors(profile.Mirrors))
+		fmt.Printf("auto_backup:    %v\n", profile.AutoBackup)
+		fmt.Printf("retention:      %s\n", describeRetention(profile.Retention))
+		fmt.Printf("format:         %s\n", profileFormat(*profile))
+		fmt.Printf("include:        %s\n", strings.Join(profile.Include, ","))
+		fmt.Printf("exclude:        %s\n", strings.Join(profile.Exclude, ","))
+		fmt.Printf("stable_for:     %s\n", describeStableFor(*profile))
+		fmt.Printf("encryption:     %s\n", describeEncryption(profile.Encryption))
+		fmt.Printf("duplicates:     %s\n", duplicatePolicy(*profile))
+		fmt.Printf("game_check:     %s\n", describeGameCheck(profile.GameCheck))
+		fmt.Printf("watch:          %s\n", describeWatch(profile.Watch))
+		fmt.Printf("schedule:       %s\n", describeSchedule(profile.Schedule))
+		return nil
+	case "set":
+		if len(args) != 3 {
+			return fmt.Errorf("%w: config set needs a key and a value", errUsage)
+		}
+		if err := setProfileValue(profile, args[1], args[2]); err != nil {
+			return err
+		}
+		if err := saveConfig(*config, configPath); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("%s %s %s updated for %s\n", iconSuccess, green("SUCCESS:"), args[1], profile.Name)
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown config subcommand %q", errUsage, args[0])
+	}
+}
+
+func cmdProfile(args []string) error {
+	fs := newFlagSet("profile")
+	savePath := fs.String("save-path", "", "save file path for a new profile")
+	backupDir := fs.String("backup-dir", "", "backup directory for a new profile")
+	noAutoBackup := fs.Bool("no-auto-backup", false, "disable auto-backup on restore for a new profile")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%w: profile needs a subcommand (list, add, use or remove)", errUsage)
+	}
+
+	config, configPath, err := loadCLIConfig()
+	if err != nil {
+		return err
+	}
+
+	var done string
+	switch args[0] {
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ACTIVE\tNAME\tSAVE PATH\tBACKUP DIR")
+		for _, p := range config.Profiles {
+			active := ""
+			if p.Name == config.ActiveProfile {
+				active = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", active, p.Name, p.SavePath, p.BackupDir)
+		}
+		return w.Flush()
+	case "add":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: profile add needs a name", errUsage)
+		}
+		if err := validateProfileName(config, args[1]); err != nil {
+			return err
+		}
+		profile := Profile{Name: args[1], AutoBackup: !*noAutoBackup}
+		if err := setProfileValue(&profile, "save_path", *savePath); err != nil {
+			return err
+		}
+		if err := setProfileValue(&profile, "backup_dir", *backupDir); err != nil {
+			return err
+		}
+		config.Profiles = append(config.Profiles, profile)
+		done = "added"
+	case "use":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: profile use needs a name", errUsage)
+		}
+		if _, err := config.profile(args[1]); err != nil {
+			return err
+		}
+		config.ActiveProfile = args[1]
+		done = "is now active"
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: profile remove needs a name", errUsage)
+		}
+		if len(config.Profiles) < 2 {
+			return errors.New("the last game profile cannot be removed")
+		}
+		index := slices.IndexFunc(config.Profiles, func(p Profile) bool { return p.Name == args[1] })
+		if index < 0 {
+			return fmt.Errorf("game profile not found: %s", args[1])
+		}
+		config.Profiles = slices.Delete(config.Profiles, index, index+1)
+		if config.ActiveProfile == args[1] {
+			config.ActiveProfile = config.Profiles[0].Name
+		}
+		done = "removed"
+	default:
+		return fmt.Errorf("%w: unknown profile subcommand %q", errUsage, args[0])
+	}
+
+	if err := saveConfig(config, configPath); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("%s %s Game profile %s %s\n", iconSuccess, green("SUCCESS:"), args[1], done)
+	return nil
+}
+
+// setProfileValue applies a single key=value change using the config.json key names.
+func setProfileValue(profile *Profile, key, value string) error {
+	value = strings.TrimSpace(value)
+	switch key {
+	case "save_path":
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("save_path must be an absolute path")
+		}
+		profile.SavePath = value
+	case "backup_dir":
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("backup_dir must be an absolute path")
+		}
+		if err := os.MkdirAll(value, 0755); err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		profile.BackupDir = value
+	case "auto_backup":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("auto_backup must be true or false")
+		}
+		profile.AutoBackup = enabled
+	case "format":
+		format, err := parseArchiveFormat(value)
+		if err != nil {
+			return err
+		}
+		profile.Format = string(format)
+	case "include":
+		profile.Include = parsePatterns(value)
+	case "exclude":
+		profile.Exclude = parsePatterns(value)
+	case "encryption":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("encryption must be true or false")
+		}
+		profile.Encryption.Enabled = enabled
+	case "key_file":
+		if value != "" && !filepath.IsAbs(value) {
+			return fmt.Errorf("key_file must be an absolute path")
+		}
+		profile.Encryption.KeyFile = value
+	case "duplicates":
+		policy, err := parseDuplicatePolicy(value)
+		if err != nil {
+			return err
+		}
+		profile.Duplicates = policy
+	case "game_executable":
+		profile.GameCheck.Executable = value
+	case "game_restore", "game_backup":
+		policy, err := parseGamePolicy(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if key == "game_restore" {
+			profile.GameCheck.Restore = policy
+		} else {
+			profile.GameCheck.Backup = policy
+		}
+	case "stable_for":
+		if _, err := parseWatchDuration(value, 0); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		profile.StableFor = value
+	case "watch_debounce", "watch_min_interval":
+		if _, err := parseWatchDuration(value, 0); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if key == "watch_debounce" {
+			profile.Watch.Debounce = value
+		} else {
+			profile.Watch.MinInterval = value
+		}
+	case "schedule":
+		schedule, err := normalizeSchedule(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		profile.Schedule = schedule
+	default:
+		if ok, err := setStorageValue(&profile.Storage, key, value); ok {
+			return err
+		}
+		field, ok := retentionField(&profile.Retention, key)
+		if !ok {
+			return fmt.Errorf("%w: unknown config key %q", errUsage, key)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s must be a whole number, 0 or greater", key)
+		}
+		*field = n
+	}
+	return nil
+}
+
+// setStorageValue applies a storage_* key to storage settings. It reports
+// whether key is a storage key at all.
+func setStorageValue(s *StorageSettings, key, value string) (bool, error) {
+	switch key {
+	case "storage_type":
+		storageType, err := parseStorageType(value)
+		if err != nil {
+			return true, err
+		}
+		s.Type = storageType
+	case "storage_insecure":
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, fmt.Errorf("storage_insecure must be true or false")
+		}
+		s.Insecure = insecure
+	default:
+		field, ok := storageField(s, key)
+		if !ok {
+			return false, nil
+		}
+		*field = value
+	}
+	return true, nil
+}
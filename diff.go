@@ -0,0 +1,693 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits that keep a comparison readable on a terminal.
+const (
+	maxDiffFiles   = 10      // changed files shown in detail
+	maxDiffLines   = 40      // output lines per text diff
+	maxDiffChanges = 50      // structured changes per file
+	maxDiffRegions = 5       // hex regions per binary file
+	maxDiffRead    = 8 << 20 // larger files only get sizes and hashes
+	maxLCSCells    = 4_000_000
+)
+
+// diffSide is one side of a comparison: a backup unpacked to disk or the live
+// save. path is "" when there is nothing there, e.g. no save yet.
+type diffSide struct {
+	label string
+	path  string
+}
+
+// saveSide returns the live save as a comparison side.
+func saveSide(profile Profile) diffSide {
+	side := diffSide{label: "current save"}
+	if _, err := os.Stat(profile.SavePath); err == nil {
+		side.path = profile.SavePath
+	}
+	return side
+}
+
+// backupSide unpacks a backup into staging for comparison.
+func backupSide(profile Profile, b Backup, staging string) (diffSide, error) {
+	dir, err := os.MkdirTemp(staging, "side-")
+	if err != nil {
+		return diffSide{}, err
+	}
+	p, err := unpackBackup(profile, b, dir)
+	if err != nil {
+		return diffSide{}, err
+	}
+	return diffSide{label: "backup " + b.Name, path: p}, nil
+}
+
+// FileChange is one file that differs between two sides.
+type FileChange struct {
+	Path     string // relative, slash-separated; the file name for single-file saves
+	Kind     string // added, removed or changed
+	Old, New string // full paths on disk; "" on the side that lacks the file
+}
+
+// sideFiles lists the files of a side by relative path, applying the
+// profile's folder filters so excluded files don't show up as differences.
+func sideFiles(profile Profile, side diffSide) (map[string]string, bool, error) {
+	files := make(map[string]string)
+	if side.path == "" {
+		return files, false, nil
+	}
+	info, err := os.Stat(side.path)
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.IsDir() {
+		files[filepath.Base(side.path)] = side.path
+		return files, false, nil
+	}
+	rels, err := collectSaveFiles(profile, side.path)
+	if err != nil {
+		return nil, true, err
+	}
+	for _, rel := range rels {
+		files[rel] = filepath.Join(side.path, filepath.FromSlash(rel))
+	}
+	return files, true, nil
+}
+
+// compareSides returns the files that were added, removed or changed going
+// from one side to the other, sorted by path, and how many files are equal.
+func compareSides(profile Profile, from, to diffSide) ([]FileChange, int, error) {
+	oldFiles, oldDir, err := sideFiles(profile, from)
+	if err != nil {
+		return nil, 0, err
+	}
+	newFiles, newDir, err := sideFiles(profile, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from.path != "" && to.path != "" && oldDir != newDir {
+		return nil, 0, fmt.Errorf("%s and %s can't be compared: one is a file, the other a folder", from.label, to.label)
+	}
+	// Single files are compared with each other under the save's name, since
+	// unpacked backups are named after the backup.
+	if !oldDir && !newDir {
+		name := filepath.Base(profile.SavePath)
+		if from.path != "" {
+			oldFiles = map[string]string{name: from.path}
+		}
+		if to.path != "" {
+			newFiles = map[string]string{name: to.path}
+		}
+	}
+
+	paths := make([]string, 0, len(oldFiles)+len(newFiles))
+	for p := range oldFiles {
+		paths = append(paths, p)
+	}
+	for p := range newFiles {
+		if _, ok := oldFiles[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []FileChange
+	same := 0
+	for _, p := range paths {
+		o, inOld := oldFiles[p]
+		n, inNew := newFiles[p]
+		switch {
+		case !inOld:
+			changes = append(changes, FileChange{Path: p, Kind: "added", New: n})
+		case !inNew:
+			changes = append(changes, FileChange{Path: p, Kind: "removed", Old: o})
+		default:
+			equal, err := sameContent(o, n)
+			if err != nil {
+				return nil, 0, err
+			}
+			if equal {
+				same++
+			} else {
+				changes = append(changes, FileChange{Path: p, Kind: "changed", Old: o, New: n})
+			}
+		}
+	}
+	return changes, same, nil
+}
+
+func sameContent(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if ai.Size() != bi.Size() {
+		return false, nil
+	}
+	as, _, err := hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	bs, _, err := hashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return as == bs, nil
+}
+
+// compareWithSave prints what restoring a backup would change in the save.
+func compareWithSave(profile Profile, b Backup) error {
+	staging, err := scratchDir("compare")
+	if err != nil {
+		return fmt.Errorf("failed to prepare comparison: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	side, err := backupSide(profile, b, staging)
+	if err != nil {
+		return err
+	}
+	return printComparison(profile, saveSide(profile), side)
+}
+
+// compareBackups prints the differences between two backups, or between a
+// backup and the live save when other is nil.
+func compareBackups(profile Profile, b Backup, other *Backup) error {
+	staging, err := scratchDir("compare")
+	if err != nil {
+		return fmt.Errorf("failed to prepare comparison: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	from, err := backupSide(profile, b, staging)
+	if err != nil {
+		return err
+	}
+	to := saveSide(profile)
+	if other != nil {
+		if to, err = backupSide(profile, *other, staging); err != nil {
+			return err
+		}
+	}
+	return printComparison(profile, from, to)
+}
+
+// printComparison prints a summary of the changes from one side to the other,
+// followed by a detailed diff of the first changed files.
+func printComparison(profile Profile, from, to diffSide) error {
+	changes, same, err := compareSides(profile, from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s %s -> %s\n", iconInfo, cyan("COMPARE:"), from.label, to.label)
+
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Kind]++
+	}
+	fmt.Printf("%s %s %d added, %d removed, %d changed, %d unchanged\n", iconInfo, white("INFO:"), counts["added"], counts["removed"], counts["changed"], same)
+	if len(changes) == 0 {
+		fmt.Printf("%s %s No differences.\n", iconSuccess, green("INFO:"))
+		return nil
+	}
+	fmt.Println()
+
+	detailed := 0
+	for _, c := range changes {
+		switch c.Kind {
+		case "added":
+			fmt.Printf("%s %s (%s)\n", green("+"), c.Path, formatSize(fileSize(c.New)))
+		case "removed":
+			fmt.Printf("%s %s (%s)\n", red("-"), c.Path, formatSize(fileSize(c.Old)))
+		case "changed":
+			fmt.Printf("%s %s (%s -> %s)\n", yellow("~"), c.Path, formatSize(fileSize(c.Old)), formatSize(fileSize(c.New)))
+			if detailed < maxDiffFiles {
+				detailed++
+				if err := printFileDiff(c.Path, c.Old, c.New); err != nil {
+					fmt.Printf("    %s %v\n", red("ERROR:"), err)
+				}
+			}
+		}
+	}
+	if counts["changed"] > detailed {
+		fmt.Printf("%s %s Details shown for the first %d changed files only.\n", iconInfo, yellow("INFO:"), detailed)
+	}
+	return nil
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// readForDiff reads a file unless it is too large to diff in detail.
+func readForDiff(path string) ([]byte, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxDiffRead+1))
+	if err != nil {
+		return nil, false, err
+	}
+	return data, len(data) <= maxDiffRead, nil
+}
+
+// printFileDiff prints how one file changed: a structured diff for JSON and
+// XML, a line diff for other text and a hex summary for binary data. name is
+// used to recognise the file type by its extension.
+func printFileDiff(name, oldPath, newPath string) error {
+	a, okA, err := readForDiff(oldPath)
+	if err != nil {
+		return err
+	}
+	b, okB, err := readForDiff(newPath)
+	if err != nil {
+		return err
+	}
+	if !okA || !okB {
+		return printBinaryDiff(oldPath, newPath, nil, nil)
+	}
+
+	switch kind := contentKind(name, b); {
+	case kind == "json" && contentKind(name, a) == "json":
+		if changes, err := diffJSON(a, b); err == nil {
+			printStructured("JSON", changes)
+			return nil
+		}
+	case kind == "xml" && contentKind(name, a) == "xml":
+		if changes, err := diffXML(a, b); err == nil {
+			printStructured("XML", changes)
+			return nil
+		}
+	}
+	if isText(a) && isText(b) {
+		printTextDiff(a, b)
+		return nil
+	}
+	return printBinaryDiff(oldPath, newPath, a, b)
+}
+
+// contentKind guesses whether data is JSON, XML, text or binary, from the
+// file extension first and the content second.
+func contentKind(name string, data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return "json"
+	case ".xml":
+		return "xml"
+	}
+	switch {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed):
+		return "json"
+	case bytes.HasPrefix(trimmed, []byte("<?xml")):
+		return "xml"
+	case isText(data):
+		return "text"
+	}
+	return "binary"
+}
+
+func isText(data []byte) bool {
+	return utf8.Valid(data) && !bytes.Contains(data, []byte{0})
+}
+
+// Change is one difference found by a structured diff.
+type Change struct {
+	Path     string
+	Kind     string // added, removed or changed
+	Old, New string // rendered values
+}
+
+func printStructured(format string, changes []Change) {
+	fmt.Printf("    %s structured diff, %d change(s):\n", format, len(changes))
+	for i, c := range changes {
+		if i == maxDiffChanges {
+			fmt.Printf("    ... and %d more\n", len(changes)-i)
+			break
+		}
+		switch c.Kind {
+		case "added":
+			fmt.Printf("    %s %s: %s\n", green("+"), c.Path, c.New)
+		case "removed":
+			fmt.Printf("    %s %s: %s\n", red("-"), c.Path, c.Old)
+		default:
+			fmt.Printf("    %s %s: %s -> %s\n", yellow("~"), c.Path, c.Old, c.New)
+		}
+	}
+}
+
+// shorten truncates s for one-line display.
+func shorten(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-3]) + "..."
+}
+
+// diffJSON compares two JSON documents value by value.
+func diffJSON(a, b []byte) ([]Change, error) {
+	var av, bv any
+	for _, doc := range []struct {
+		data []byte
+		v    *any
+	}{{a, &av}, {b, &bv}} {
+		dec := json.NewDecoder(bytes.NewReader(doc.data))
+		dec.UseNumber()
+		if err := dec.Decode(doc.v); err != nil {
+			return nil, err
+		}
+	}
+	var changes []Change
+	walkJSON("$", av, bv, &changes)
+	return changes, nil
+}
+
+func renderJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return shorten(string(data), 60)
+}
+
+func walkJSON(path string, a, b any, changes *[]Change) {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + "." + k
+			x, inA := av[k]
+			y, inB := bv[k]
+			switch {
+			case !inA:
+				*changes = append(*changes, Change{Path: child, Kind: "added", New: renderJSON(y)})
+			case !inB:
+				*changes = append(*changes, Change{Path: child, Kind: "removed", Old: renderJSON(x)})
+			default:
+				walkJSON(child, x, y, changes)
+			}
+		}
+		return
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(av), len(bv)); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(av):
+				*changes = append(*changes, Change{Path: child, Kind: "added", New: renderJSON(bv[i])})
+			case i >= len(bv):
+				*changes = append(*changes, Change{Path: child, Kind: "removed", Old: renderJSON(av[i])})
+			default:
+				walkJSON(child, av[i], bv[i], changes)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, Kind: "changed", Old: renderJSON(a), New: renderJSON(b)})
+	}
+}
+
+// xmlValue is one attribute or text node of an XML document, addressed by an
+// XPath-like path such as /save/player[1]/@gold.
+type xmlValue struct {
+	path, value string
+}
+
+// flattenXML lists every attribute and text node of a document in document
+// order.
+func flattenXML(data []byte) ([]xmlValue, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	type frame struct {
+		path   string
+		counts map[string]int
+		text   strings.Builder
+	}
+	stack := []*frame{{counts: map[string]int{}}}
+	var values []xmlValue
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			top.counts[t.Name.Local]++
+			p := fmt.Sprintf("%s/%s[%d]", top.path, t.Name.Local, top.counts[t.Name.Local])
+			for _, attr := range t.Attr {
+				values = append(values, xmlValue{p + "/@" + attr.Name.Local, attr.Value})
+			}
+			stack = append(stack, &frame{path: p, counts: map[string]int{}})
+		case xml.CharData:
+			top.text.Write(t)
+		case xml.EndElement:
+			if len(stack) == 1 {
+				return nil, errors.New("unbalanced XML")
+			}
+			if text := strings.TrimSpace(top.text.String()); text != "" {
+				values = append(values, xmlValue{top.path + "/text()", text})
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) != 1 {
+		return nil, errors.New("unbalanced XML")
+	}
+	return values, nil
+}
+
+// diffXML compares the attributes and text of two XML documents.
+func diffXML(a, b []byte) ([]Change, error) {
+	av, err := flattenXML(a)
+	if err != nil {
+		return nil, err
+	}
+	bv, err := flattenXML(b)
+	if err != nil {
+		return nil, err
+	}
+	newValues := make(map[string]string, len(bv))
+	for _, v := range bv {
+		newValues[v.path] = v.value
+	}
+	oldValues := make(map[string]string, len(av))
+	var changes []Change
+	for _, v := range av {
+		oldValues[v.path] = v.value
+		n, ok := newValues[v.path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: v.path, Kind: "removed", Old: shorten(v.value, 60)})
+		case n != v.value:
+			changes = append(changes, Change{Path: v.path, Kind: "changed", Old: shorten(v.value, 60), New: shorten(n, 60)})
+		}
+	}
+	for _, v := range bv {
+		if _, ok := oldValues[v.path]; !ok {
+			changes = append(changes, Change{Path: v.path, Kind: "added", New: shorten(v.value, 60)})
+		}
+	}
+	return changes, nil
+}
+
+// printTextDiff prints the changed lines with a little context, unified-diff
+// style.
+func printTextDiff(a, b []byte) {
+	oldLines := splitLines(a)
+	newLines := splitLines(b)
+
+	// Only the part between the common prefix and suffix needs diffing.
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	oldMid := oldLines[prefix : len(oldLines)-suffix]
+	newMid := newLines[prefix : len(newLines)-suffix]
+	if len(oldMid)*len(newMid) > maxLCSCells {
+		fmt.Printf("    text: %d line(s) -> %d line(s), changes from line %d on are too large to show\n", len(oldLines), len(newLines), prefix+1)
+		return
+	}
+
+	ops := diffLines(oldMid, newMid)
+	fmt.Printf("    text diff, starting at line %d:\n", prefix+1)
+	for i := max(prefix-2, 0); i < prefix; i++ {
+		fmt.Printf("      %s\n", shorten(oldLines[i], 100))
+	}
+	shown := 0
+	for _, op := range ops {
+		if shown == maxDiffLines {
+			fmt.Printf("    ... more changes not shown\n")
+			return
+		}
+		switch op.kind {
+		case '-':
+			fmt.Printf("    %s %s\n", red("-"), red(shorten(op.text, 100)))
+		case '+':
+			fmt.Printf("    %s %s\n", green("+"), green(shorten(op.text, 100)))
+		default:
+			fmt.Printf("      %s\n", shorten(op.text, 100))
+		}
+		shown++
+	}
+	for i := len(oldLines) - suffix; i < len(oldLines) && i < len(oldLines)-suffix+2; i++ {
+		fmt.Printf("      %s\n", shorten(oldLines[i], 100))
+	}
+}
+
+// splitLines splits text into lines, ignoring a final newline.
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	return strings.Split(text, "\n")
+}
+
+type lineOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// diffLines computes a line edit script from a longest common subsequence.
+func diffLines(a, b []string) []lineOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []lineOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, lineOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, lineOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, lineOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, lineOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, lineOp{'+', b[j]})
+	}
+	return ops
+}
+
+// printBinaryDiff prints the sizes and hashes of both files and, when their
+// contents are given, where the bytes differ, old and new side by side.
+func printBinaryDiff(oldPath, newPath string, a, b []byte) error {
+	oldSum, oldSize, err := hashFile(oldPath)
+	if err != nil {
+		return err
+	}
+	newSum, newSize, err := hashFile(newPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("    binary: %d -> %d bytes\n", oldSize, newSize)
+	fmt.Printf("    sha256: %s -> %s\n", oldSum[:16], newSum[:16])
+	if a == nil || b == nil {
+		return nil
+	}
+
+	// Group differing bytes into regions, merging gaps of under 8 bytes.
+	type region struct{ start, end int }
+	var regions []region
+	differing := 0
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		differing++
+		if n := len(regions); n > 0 && i-regions[n-1].end < 8 {
+			regions[n-1].end = i + 1
+		} else {
+			regions = append(regions, region{i, i + 1})
+		}
+	}
+	if len(a) != len(b) {
+		differing += max(len(a), len(b)) - min(len(a), len(b))
+		regions = append(regions, region{min(len(a), len(b)), max(len(a), len(b))})
+	}
+	fmt.Printf("    %d byte(s) differ in %d region(s)\n", differing, len(regions))
+	for i, r := range regions {
+		if i == maxDiffRegions {
+			fmt.Printf("    ... and %d more region(s)\n", len(regions)-i)
+			break
+		}
+		end := min(r.end, r.start+16)
+		fmt.Printf("    0x%08x (%d byte(s)): %-47s | %s\n", r.start, r.end-r.start, hexBytes(a, r.start, end), hexBytes(b, r.start, end))
+	}
+	return nil
+}
+
+// hexBytes renders data[start:end] as hex, clipped to the data's length.
+func hexBytes(data []byte, start, end int) string {
+	if start >= len(data) {
+		return "(none)"
+	}
+	end = min(end, len(data))
+	parts := make([]string, 0, end-start)
+	for _, c := range data[start:end] {
+		parts = append(parts, fmt.Sprintf("%02x", c))
+	}
+	return strings.Join(parts, " ")
+}
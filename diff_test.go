@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares output with testdata/name.golden, or rewrites the file
+// when the tests run with -update.
+func checkGolden(t *testing.T, name, output string) {
+	t.Helper()
+	golden := filepath.Join("testdata", filepath.FromSlash(name)+".golden")
+	if *updateGolden {
+		writeTestFile(t, golden, output)
+		return
+	}
+	if want := readTestFile(t, golden); output != want {
+		t.Errorf("output differs from %s (rerun with -update to accept it)\ngot:\n%s\nwant:\n%s", golden, output, want)
+	}
+}
+
+// plainOutput returns what fn prints, without colors.
+func plainOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+	return captureStdout(t, fn)
+}
+
+func TestTextDiffGolden(t *testing.T) {
+	old := []byte("[player]\nname=Tarnished\nlevel=12\nrunes=3400\n\n[world]\nboss=Margit\nsite=Stormhill\nweather=clear\n")
+	updated := []byte("[player]\r\nname=Tarnished\r\nlevel=13\r\nrunes=0\r\n\r\n[world]\r\nsite=Stormhill\r\nsite=Stormveil\r\nweather=clear\r\n")
+	checkGolden(t, "diff/text", plainOutput(t, func() { printTextDiff(old, updated) }))
+}
+
+func TestDiffLines(t *testing.T) {
+	ops := diffLines([]string{"a", "b", "c", "d"}, []string{"a", "c", "e", "d"})
+	var got bytes.Buffer
+	for _, op := range ops {
+		got.WriteByte(op.kind)
+		got.WriteString(op.text)
+	}
+	if want := " a-b c+e d"; got.String() != want {
+		t.Errorf("got %q, want %q", got.String(), want)
+	}
+}
+
+func TestJSONDiffGolden(t *testing.T) {
+	old := []byte(`{"player": {"name": "Tarnished", "level": 12, "flasks": [3, 2]}, "flags": {"margit": false}, "playtime": 3600}`)
+	updated := []byte(`{"player": {"name": "Tarnished", "level": 13, "flasks": [4, 2, 1]}, "flags": {"margit": true, "godrick": false}, "seen": ["stormveil"]}`)
+	changes, err := diffJSON(old, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "diff/json", plainOutput(t, func() { printStructured("JSON", changes) }))
+}
+
+func TestXMLDiffGolden(t *testing.T) {
+	old := []byte(`<save version="1"><player gold="100">Tarnished</player><item id="1"/><item id="2"/></save>`)
+	updated := []byte(`<save version="2"><player gold="250">Tarnished</player><item id="1"/><item id="3"/><item id="4"/><note>boss next</note></save>`)
+	changes, err := diffXML(old, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "diff/xml", plainOutput(t, func() { printStructured("XML", changes) }))
+}
+
+func TestBinaryDiffGolden(t *testing.T) {
+	old := make([]byte, 64)
+	for i := range old {
+		old[i] = byte(i)
+	}
+	updated := bytes.Clone(old)
+	updated[4], updated[9] = 0xff, 0xee // one region: the gap is under 8 bytes
+	updated[40] = 0xaa                  // a second region
+	updated = append(updated, 0xde, 0xad)
+
+	dir := t.TempDir()
+	oldPath, newPath := filepath.Join(dir, "old.bin"), filepath.Join(dir, "new.bin")
+	if err := os.WriteFile(oldPath, old, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newPath, updated, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	output := plainOutput(t, func() { err = printBinaryDiff(oldPath, newPath, old, updated) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "diff/binary", output)
+}
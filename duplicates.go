@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DuplicatePolicy says what to do when the save hasn't changed since the
+// latest backup.
+type DuplicatePolicy string
+
+const (
+	DuplicatesAsk   DuplicatePolicy = "ask"   // ask before creating an identical backup
+	DuplicatesSkip  DuplicatePolicy = "skip"  // don't create it
+	DuplicatesAllow DuplicatePolicy = "allow" // create it anyway
+)
+
+var duplicatePolicies = []DuplicatePolicy{DuplicatesAsk, DuplicatesSkip, DuplicatesAllow}
+
+// parseDuplicatePolicy validates a policy name. An empty string means ask.
+func parseDuplicatePolicy(value string) (DuplicatePolicy, error) {
+	if value == "" {
+		return DuplicatesAsk, nil
+	}
+	for _, p := range duplicatePolicies {
+		if strings.EqualFold(value, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown duplicates policy %q (use ask, skip or allow)", value)
+}
+
+// duplicatePolicy returns the profile's policy, defaulting to ask.
+func duplicatePolicy(profile Profile) DuplicatePolicy {
+	if p, err := parseDuplicatePolicy(string(profile.Duplicates)); err == nil {
+		return p
+	}
+	return DuplicatesAsk
+}
+
+// contentSum returns the hash of the save a backup holds, or "" if its
+// manifest doesn't record one. For plain backups the stored hash is the same.
+func contentSum(b Backup) string {
+	if b.Meta == nil {
+		return ""
+	}
+	if b.Meta.ContentSHA256 != "" {
+		return b.Meta.ContentSHA256
+	}
+	if b.Format == FormatPlain {
+		return b.Meta.SHA256
+	}
+	return ""
+}
+
+// unchangedSince returns the latest backup if the live save is identical to
+// it, or nil if the save differs or there is nothing to compare with.
+func unchangedSince(profile Profile) (*Backup, error) {
+	backups, err := listBackupsInternal(profile)
+	if err != nil || len(backups) == 0 {
+		return nil, err
+	}
+	latest := backups[0]
+	want := contentSum(latest)
+	if want == "" {
+		return nil, nil
+	}
+	sum, _, err := hashSave(profile)
+	if err != nil {
+		return nil, err
+	}
+	if sum != want {
+		return nil, nil
+	}
+	return &latest, nil
+}
+
+// shouldSkipAutoBackup reports whether an automatic backup would only repeat
+// the latest backup. Automatic backups can't ask, so ask counts as skip.
+func shouldSkipAutoBackup(profile Profile) (*Backup, bool) {
+	if duplicatePolicy(profile) == DuplicatesAllow {
+		return nil, false
+	}
+	dup, err := unchangedSince(profile)
+	if err != nil || dup == nil {
+		return nil, false
+	}
+	return dup, true
+}
+
+// checkDuplicate applies the duplicates policy before a manual backup and
+// reports whether to go ahead. For the ask policy, confirm asks the user; a
+// nil confirm means there is no one to ask, and ask then counts as allow, so
+// a script's backup isn't dropped without an answer.
+func checkDuplicate(profile Profile, confirm func(prompt string) bool) bool {
+	policy := duplicatePolicy(profile)
+	if policy == DuplicatesAllow {
+		return true
+	}
+	dup, err := unchangedSince(profile)
+	if err != nil || dup == nil {
+		return true
+	}
+	fmt.Printf("%s %s The save hasn't changed since the latest backup, %s.\n", iconInfo, yellow("INFO:"), dup.Name)
+	if policy == DuplicatesAsk && confirm == nil {
+		fmt.Printf("%s %s Not running in a terminal, creating it anyway.\n", iconInfo, yellow("INFO:"))
+		return true
+	}
+	if policy == DuplicatesAsk && confirm("Create an identical backup anyway? (y/N)") {
+		return true
+	}
+	fmt.Printf("%s %s Backup skipped.\n", iconInfo, yellow("INFO:"))
+	return false
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestDuplicatePolicies(t *testing.T) {
+	yes := func(string) bool { return true }
+	no := func(string) bool { return false }
+
+	for _, tc := range []struct {
+		name     string
+		policy   DuplicatePolicy
+		changed  bool
+		confirm  func(string) bool
+		wantGo   bool // checkDuplicate goes ahead with a manual backup
+		wantSkip bool // shouldSkipAutoBackup skips an automatic one
+	}{
+		{"ask, unchanged, confirmed", DuplicatesAsk, false, yes, true, true},
+		{"ask, unchanged, declined", DuplicatesAsk, false, no, false, true},
+		{"ask, unchanged, no terminal", DuplicatesAsk, false, nil, true, true},
+		{"ask, changed", DuplicatesAsk, true, no, true, false},
+		{"default is ask", "", false, no, false, true},
+		{"skip, unchanged", DuplicatesSkip, false, yes, false, true},
+		{"skip, unchanged, no terminal", DuplicatesSkip, false, nil, false, true},
+		{"skip, changed", DuplicatesSkip, true, nil, true, false},
+		{"allow, unchanged", DuplicatesAllow, false, no, true, false},
+		{"allow, changed", DuplicatesAllow, true, no, true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			profile := newTestProfile(t)
+			profile.Duplicates = tc.policy
+			if _, err := writeBackup(profile, "latest", "", nil); err != nil {
+				t.Fatal(err)
+			}
+			if tc.changed {
+				writeTestFile(t, profile.SavePath, "slot 2")
+			}
+
+			var got bool
+			captureStdout(t, func() { got = checkDuplicate(profile, tc.confirm) })
+			if got != tc.wantGo {
+				t.Errorf("checkDuplicate: got %v, want %v", got, tc.wantGo)
+			}
+			dup, skip := shouldSkipAutoBackup(profile)
+			if skip != tc.wantSkip {
+				t.Errorf("shouldSkipAutoBackup: got %v, want %v", skip, tc.wantSkip)
+			}
+			if skip && (dup == nil || dup.Name != "latest") {
+				t.Errorf("shouldSkipAutoBackup: got %v, want the latest backup", dup)
+			}
+		})
+	}
+
+	t.Run("no backups yet", func(t *testing.T) {
+		profile := newTestProfile(t)
+		if !checkDuplicate(profile, nil) {
+			t.Error("checkDuplicate refused the first backup")
+		}
+		if _, skip := shouldSkipAutoBackup(profile); skip {
+			t.Error("shouldSkipAutoBackup skipped the first backup")
+		}
+	})
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// passphraseEnv names the environment variable that supplies the passphrase
+// without prompting, for scripts and scheduled runs.
+const passphraseEnv = "BACKUP_MANAGER_PASSPHRASE"
+
+// encryptedExtension is appended to the file name of an encrypted backup, after
+// the extension of the format it wraps.
+const encryptedExtension = ".enc"
+
+// Encrypted files start with encryptMagic, then a salt, the PBKDF2 iteration
+// count and a nonce prefix. The data follows as AES-256-GCM sealed chunks; each
+// chunk's nonce ends in its index and the final chunk is marked through the
+// additional data, so reordered, dropped or truncated chunks fail to open.
+const (
+	encryptMagic      = "BMENC1\n"
+	encryptSaltSize   = 16
+	encryptNonceSize  = 8 // random prefix; the remaining 4 bytes count chunks
+	encryptChunkSize  = 64 * 1024
+	encryptIterations = 600_000
+)
+
+var errWrongPassphrase = errors.New("wrong passphrase, or the backup is damaged")
+
+// EncryptionSettings turn on encryption for new backups of a profile.
+type EncryptionSettings struct {
+	Enabled bool   `json:"enabled"`
+	KeyFile string `json:"key_file,omitempty"` // file whose contents are the passphrase
+}
+
+// describeEncryption renders the settings for display.
+func describeEncryption(e EncryptionSettings) string {
+	switch {
+	case !e.Enabled:
+		return "off"
+	case e.KeyFile != "":
+		return "on (key file " + e.KeyFile + ")"
+	default:
+		return "on (passphrase)"
+	}
+}
+
+// splitEncrypted strips the encrypted-backup extension from a backup file name.
+func splitEncrypted(fileName string, isDir bool) (string, bool) {
+	if isDir || !strings.HasSuffix(fileName, encryptedExtension) {
+		return fileName, false
+	}
+	return strings.TrimSuffix(fileName, encryptedExtension), true
+}
+
+// formatLabel describes how a backup is stored, e.g. "zip" or "zip, encrypted".
+func (b Backup) formatLabel() string {
+	if b.Encrypted {
+		return string(b.Format) + ", encrypted"
+	}
+	return string(b.Format)
+}
+
+// sessionPassphrases remembers prompted passphrases by profile name so one run
+// asks only once, e.g. for the auto-backup and the restore that follows it.
+// Profiles can use different passphrases, so one is never tried for another.
+var sessionPassphrases = map[string]string{}
+
+// rememberPassphrase keeps a prompted passphrase for the rest of the run.
+func rememberPassphrase(profile Profile, passphrase string) {
+	sessionPassphrases[profile.Name] = passphrase
+}
+
+// forgetPassphrase drops a remembered passphrase, e.g. after it failed to
+// decrypt a backup, so the next attempt asks again.
+func forgetPassphrase(profile Profile) {
+	delete(sessionPassphrases, profile.Name)
+}
+
+// passphraseKnown reports whether backupPassphrase can answer without
+// prompting.
+func passphraseKnown(profile Profile) bool {
+	return os.Getenv(passphraseEnv) != "" || profile.Encryption.KeyFile != "" || sessionPassphrases[profile.Name] != ""
+}
+
+// backupPassphrase finds the passphrase from the environment, the profile's
+// key file or, failing both, a prompt. confirm asks twice, which is used before
+// encrypting so a typo can't lock a backup away.
+func backupPassphrase(profile Profile, confirm bool) (string, error) {
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return p, nil
+	}
+	if profile.Encryption.KeyFile != "" {
+		data, err := os.ReadFile(profile.Encryption.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read key file: %w", err)
+		}
+		if p := strings.TrimSpace(string(data)); p != "" {
+			return p, nil
+		}
+		return "", fmt.Errorf("key file %s is empty", profile.Encryption.KeyFile)
+	}
+	if p := sessionPassphrases[profile.Name]; p != "" {
+		return p, nil
+	}
+
+	p, err := promptForPassphrase("Enter the backup passphrase for " + profile.Name)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := promptForPassphrase("Enter it again to confirm")
+		if err != nil {
+			return "", err
+		}
+		if again != p {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	rememberPassphrase(profile, p)
+	return p, nil
+}
+
+// promptForEncryption turns encryption on or off and sets the key file.
+func promptForEncryption(settings *EncryptionSettings) error {
+	prompt := promptui.Select{
+		Label: white("Encrypt new backups?"),
+		Items: []string{"Yes, with a passphrase", "Yes, with a key file", "No"},
+	}
+	index, _, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	switch index {
+	case 0:
+		*settings = EncryptionSettings{Enabled: true}
+		fmt.Printf("%s %s You will be asked for the passphrase, unless $%s is set.\n", iconInfo, white("INFO:"), passphraseEnv)
+	case 1:
+		keyFile, err := promptForInput("Enter the full path of the key file")
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(keyFile); err != nil {
+			return fmt.Errorf("key file not found: %s", keyFile)
+		}
+		*settings = EncryptionSettings{Enabled: true, KeyFile: keyFile}
+	default:
+		settings.Enabled = false
+	}
+	return nil
+}
+
+func promptForPassphrase(label string) (string, error) {
+	prompt := promptui.Prompt{
+		Label:       white(label),
+		Mask:        '*',
+		HideEntered: true,
+		Validate: func(input string) error {
+			if input == "" {
+				return errors.New("the passphrase can't be empty")
+			}
+			return nil
+		},
+	}
+	return prompt.Run()
+}
+
+// deriveKey turns a passphrase into an AES-256 key.
+func deriveKey(passphrase string, salt []byte, iterations int) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+}
+
+// chunkNonce builds the nonce for chunk i.
+func chunkNonce(prefix []byte, i uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptNonceSize:], i)
+	return nonce
+}
+
+// chunkAAD marks whether a chunk is the last one.
+func chunkAAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// scratchDir creates a private temporary folder for downloaded, decrypted or
+// unpacked backups, which the caller removes when done. It is made in the
+// system's temporary directory rather than the backup directory: that one may
+// be synced to shared storage, and plaintext of encrypted backups must not
+// reach it, not even when the tool crashes mid-operation.
+func scratchDir(purpose string) (string, error) {
+	return os.MkdirTemp("", "backup_manager-"+purpose+"-")
+}
+
+// encryptFile encrypts src into a new file at dst.
+func encryptFile(src, dst, passphrase string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	salt := make([]byte, encryptSaltSize)
+	prefix := make([]byte, encryptNonceSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, salt, encryptIterations)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+	w := bufio.NewWriter(out)
+
+	var header bytes.Buffer
+	header.WriteString(encryptMagic)
+	header.Write(salt)
+	binary.Write(&header, binary.BigEndian, uint32(encryptIterations))
+	header.Write(prefix)
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	// Read one chunk ahead so the last chunk can be marked as such.
+	r := bufio.NewReaderSize(in, encryptChunkSize)
+	buf := make([]byte, encryptChunkSize)
+	for i := uint32(0); ; i++ {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		_, peekErr := r.Peek(1)
+		last := peekErr != nil
+		if _, err := w.Write(aead.Seal(nil, chunkNonce(prefix, i), buf[:n], chunkAAD(last))); err != nil {
+			return err
+		}
+		if last {
+			break
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+// decryptFile decrypts src into a new file at dst. A wrong passphrase or any
+// tampering is reported as errWrongPassphrase.
+func decryptFile(src, dst, passphrase string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	r := bufio.NewReader(in)
+
+	header := make([]byte, len(encryptMagic)+encryptSaltSize+4+encryptNonceSize)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(encryptMagic)]) != encryptMagic {
+		return fmt.Errorf("%s is not an encrypted backup", src)
+	}
+	rest := header[len(encryptMagic):]
+	salt := rest[:encryptSaltSize]
+	iterations := binary.BigEndian.Uint32(rest[encryptSaltSize:])
+	prefix := rest[encryptSaltSize+4:]
+	if iterations == 0 || iterations > 100*encryptIterations {
+		return fmt.Errorf("%s has an invalid encryption header", src)
+	}
+
+	key, err := deriveKey(passphrase, salt, int(iterations))
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+
+	buf := make([]byte, encryptChunkSize+aead.Overhead())
+	for i := uint32(0); ; i++ {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			if err == io.EOF {
+				// Ran out of data before the chunk marked as last.
+				return errWrongPassphrase
+			}
+			return err
+		}
+		_, peekErr := r.Peek(1)
+		last := peekErr != nil
+		plain, err := aead.Open(nil, chunkNonce(prefix, i), buf[:n], chunkAAD(last))
+		if err != nil {
+			return errWrongPassphrase
+		}
+		if _, err := out.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// checkNoPlaintext fails if any file under dir contains secret.
+func checkNoPlaintext(t *testing.T, dir, secret string) {
+	t.Helper()
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s holds plaintext of the save", p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptedBackupsStagePlaintextOutsideBackupDir(t *testing.T) {
+	profile := newTestProfile(t)
+	const secret = "gold=999999"
+	writeTestFile(t, profile.SavePath, secret)
+	profile.Encryption = EncryptionSettings{Enabled: true}
+	profile.Format = string(FormatZip)
+	t.Setenv(passphraseEnv, "correct horse")
+	scratch := t.TempDir()
+	t.Setenv("TMPDIR", scratch)
+
+	backup, err := writeBackup(profile, "enc", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNoPlaintext(t, profile.BackupDir, secret)
+
+	writeTestFile(t, profile.SavePath, "gold=0")
+	if _, err := applyBackup(profile, backup, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, profile.SavePath); got != secret {
+		t.Errorf("restored %q, want %q", got, secret)
+	}
+	if err := compareWithSave(profile, backup); err != nil {
+		t.Fatal(err)
+	}
+	checkNoPlaintext(t, profile.BackupDir, secret)
+
+	// Scratch folders are removed again once the work is done.
+	if entries, _ := os.ReadDir(scratch); len(entries) != 0 {
+		t.Errorf("scratch folders left behind: %v", entries)
+	}
+
+	// Decrypting goes through the system's temporary folder: with that
+	// unusable, the restore fails instead of falling back to the backup
+	// directory.
+	t.Setenv("TMPDIR", filepath.Join(scratch, "missing"))
+	writeTestFile(t, profile.SavePath, "gold=0")
+	if _, err := applyBackup(profile, backup, false); err == nil {
+		t.Error("restore succeeded without a usable temporary folder")
+	}
+	if got := readTestFile(t, profile.SavePath); got != "gold=0" {
+		t.Errorf("save changed to %q by a failed restore", got)
+	}
+}
+
+func TestScratchDirIsPrivateAndOutsideBackupDir(t *testing.T) {
+	profile := newTestProfile(t)
+	dir, err := scratchDir("restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if rel, err := filepath.Rel(profile.BackupDir, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		t.Errorf("scratch folder %s is inside the backup directory", dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		t.Errorf("scratch folder has mode %v, want it private to the user", perm)
+	}
+}
+
+func TestPassphrasesAreRememberedPerProfile(t *testing.T) {
+	t.Cleanup(func() { sessionPassphrases = map[string]string{} })
+	t.Setenv(passphraseEnv, "")
+	first, second := newTestProfile(t), newTestProfile(t)
+	first.Name, second.Name = "first", "second"
+	first.Encryption = EncryptionSettings{Enabled: true}
+	second.Encryption = EncryptionSettings{Enabled: true}
+	rememberPassphrase(first, "one")
+	rememberPassphrase(second, "two")
+
+	backup, err := writeBackup(second, "enc", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decryptFile(backup.Path, filepath.Join(t.TempDir(), "out"), "two"); err != nil {
+		t.Errorf("second profile's backup doesn't open with its own passphrase: %v", err)
+	}
+	if other := (Profile{Name: "third", Encryption: EncryptionSettings{Enabled: true}}); passphraseKnown(other) {
+		t.Error("a profile without a passphrase of its own is treated as known")
+	}
+}
+
+func TestWrongPassphraseIsForgotten(t *testing.T) {
+	t.Cleanup(func() { sessionPassphrases = map[string]string{} })
+	t.Setenv(passphraseEnv, "")
+	profile := newTestProfile(t)
+	profile.Encryption = EncryptionSettings{Enabled: true}
+	rememberPassphrase(profile, "right")
+	backup, err := writeBackup(profile, "enc", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rememberPassphrase(profile, "wrong")
+	if _, err := applyBackup(profile, backup, false); !errors.Is(err, errWrongPassphrase) {
+		t.Fatalf("applyBackup: got %v, want errWrongPassphrase", err)
+	}
+	if passphraseKnown(profile) {
+		t.Error("the wrong passphrase is still remembered after it failed to decrypt")
+	}
+}
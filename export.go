@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+)
+
+// exportRootName is the single top-level folder of an export archive. Inside
+// it the backups are laid out exactly as in a backup directory.
+const exportRootName = "backup_manager_export"
+
+// exportManifestName is the hidden file in an export that describes it.
+const exportManifestName = ".export.json"
+
+// ExportManifest describes an export archive.
+type ExportManifest struct {
+	Profile     string    `json:"profile"`
+	ExportedAt  time.Time `json:"exported_at"`
+	ToolVersion string    `json:"tool_version"`
+	Backups     []string  `json:"backups"`
+}
+
+// restoreTarget picks where restoreTo writes a backup: inside dest when it is
+// an existing directory, named after the backup, and otherwise dest itself.
+func restoreTarget(profile Profile, backup Backup, dest string, isDir bool) string {
+	info, err := os.Stat(dest)
+	if err != nil || !info.IsDir() {
+		return dest
+	}
+	name := backup.Name
+	if !isDir {
+		source := profile.SavePath
+		if backup.Meta != nil && backup.Meta.SourcePath != "" {
+			source = backup.Meta.SourcePath
+		}
+		name += filepath.Ext(source)
+	}
+	return filepath.Join(dest, name)
+}
+
+// restoreTo writes a backup to dest without touching the save. If dest is an
+// existing directory the backup is written inside it; nothing that already
+// exists is overwritten. It returns the path that was written.
+func restoreTo(profile Profile, backup Backup, dest string, force bool) (string, error) {
+	if !filepath.IsAbs(dest) {
+		return "", errors.New("the destination must be an absolute path")
+	}
+	if result := verifyBackup(backup); !result.healthy() && !force {
+		return "", fmt.Errorf("%w: %s is %s (%s)", errBackupDamaged, backup.Name, result.Status, result.Detail)
+	}
+
+	staging, err := scratchDir("restore")
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare restore: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	source, err := unpackBackup(profile, backup, staging)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	target := restoreTarget(profile, backup, dest, info.IsDir())
+	if rel, err := filepath.Rel(profile.SavePath, target); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("the destination is the save or inside it; restore over the save instead")
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return "", fmt.Errorf("%s already exists", target)
+	}
+	if info.IsDir() {
+		err = copySaveTree(Profile{}, source, target)
+	} else {
+		err = copyFile(source, target)
+	}
+	if err != nil {
+		os.RemoveAll(target)
+		return "", fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return target, nil
+}
+
+// exportBackups bundles backups, with their manifests and any store blobs they
+// use, into a single zip archive at dst. Backups are exported as stored, so
+// encrypted ones still need their passphrase after importing.
+func exportBackups(profile Profile, backups []Backup, dst string) error {
+	if len(backups) == 0 {
+		return errors.New("no backups to export")
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+
+	staging, err := scratchDir("export")
+	if err != nil {
+		return fmt.Errorf("failed to prepare export: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	root := filepath.Join(staging, exportRootName)
+	if err := os.Mkdir(root, 0755); err != nil {
+		return fmt.Errorf("failed to prepare export: %w", err)
+	}
+
+	bundle := localStorage{root: root}
+	manifest := ExportManifest{Profile: profile.Name, ExportedAt: time.Now(), ToolVersion: version}
+	for _, b := range backups {
+		if err := copyBackup(b, bundle); err != nil {
+			return err
+		}
+		manifest.Backups = append(manifest.Backups, b.Name)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(root, exportManifestName), data, 0644); err != nil {
+		return err
+	}
+	return writeArchive(Profile{SavePath: root}, FormatZip, dst)
+}
+
+// ImportResult lists what importBackups did with each backup in an export.
+type ImportResult struct {
+	Imported []Backup
+	Skipped  []string // "name: reason"
+}
+
+// importBackups adds the backups of an export archive to the profile's
+// storage. Backups whose name is already taken, or that fail verification,
+// are skipped.
+func importBackups(profile Profile, src string) (ImportResult, error) {
+	var result ImportResult
+	st, err := openStorage(profile)
+	if err != nil {
+		return result, err
+	}
+
+	staging, err := scratchDir("import")
+	if err != nil {
+		return result, fmt.Errorf("failed to prepare import: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	root, err := extractArchive(src, staging)
+	if err != nil {
+		return result, err
+	}
+	if _, err := os.Stat(filepath.Join(root, exportManifestName)); err != nil {
+		return result, fmt.Errorf("%s is not a backup export", filepath.Base(src))
+	}
+
+	bundled, err := listBackupsIn(localStorage{root: root})
+	if err != nil {
+		return result, err
+	}
+	for _, b := range bundled {
+		taken, err := storageExists(st, b.Key)
+		if err != nil {
+			return result, err
+		}
+		if !taken {
+			if taken, err = storageExists(st, manifestPath(b.Key)); err != nil {
+				return result, err
+			}
+		}
+		if taken {
+			result.Skipped = append(result.Skipped, b.Name+": a backup with this name already exists")
+			continue
+		}
+		if check := verifyBackup(b); !check.healthy() {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %s (%s)", b.Name, check.Status, check.Detail))
+			continue
+		}
+		if err := copyBackup(b, st); err != nil {
+			return result, err
+		}
+		result.Imported = append(result.Imported, newBackup(st, b.Key, b.Name, b.Format, b.Encrypted, b.CreatedAt, b.Meta))
+	}
+	return result, nil
+}
+
+// restoreToPrompt asks for a destination and writes a backup there.
+func restoreToPrompt(profile Profile, backup Backup) {
+	fmt.Println()
+	fmt.Printf("%s %s Enter a folder to write the backup into, or a new file or folder path.\n", iconInfo, white("INFO:"))
+	dest, err := promptForInput("Destination")
+	if err != nil || dest == "" {
+		fmt.Printf("%s %s Restore cancelled.\n", iconError, yellow("INFO:"))
+		waitForEnter()
+		return
+	}
+
+	force := false
+	if result := verifyBackup(backup); !result.healthy() {
+		fmt.Printf("%s %s This backup is %s (%s) and may not restore correctly!\n", iconError, red("WARNING:"), result.Status, result.Detail)
+		answer, err := promptForInput("Type 'force' to restore it anyway")
+		if err != nil || strings.ToLower(answer) != "force" {
+			fmt.Printf("%s %s Restore cancelled.\n", iconError, yellow("INFO:"))
+			waitForEnter()
+			return
+		}
+		force = true
+	}
+
+	target, err := restoreTo(profile, backup, dest, force)
+	if err != nil {
+		fmt.Printf("%s %s Failed to restore backup: %v\n", iconError, red("ERROR:"), err)
+	} else {
+		fmt.Printf("%s %s Backup written to: %s\n", iconSuccess, green("SUCCESS:"), target)
+	}
+	waitForEnter()
+}
+
+// exportImportMenu lets the user export backups to an archive or import one.
+func exportImportMenu(profile Profile) {
+	clearScreen()
+	fmt.Println(cyan("====================================="))
+	fmt.Printf("%s %s EXPORT / IMPORT BACKUPS\n", iconDir, cyan("EXPORT / IMPORT BACKUPS"))
+	fmt.Println(cyan("====================================="))
+	fmt.Println()
+
+	prompt := promptui.Select{
+		Label: white("Select an action"),
+		Items: []string{"Export Backups", "Import Backups", "Back"},
+	}
+	index, _, err := prompt.Run()
+	if err != nil || index == 2 {
+		return
+	}
+	if index == 1 {
+		importMenu(profile)
+		return
+	}
+
+	backups, err := listBackupsInternal(profile)
+	if err != nil {
+		fmt.Printf("%s %s Failed to list backups: %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return
+	}
+	if len(backups) == 0 {
+		fmt.Printf("%s %s No backups found.\n", iconError, red("INFO:"))
+		waitForEnter()
+		return
+	}
+	selected, err := pickBackups("Select backups to export", backups)
+	if err != nil {
+		if errors.Is(err, errCancelled) {
+			fmt.Printf("%s %s Export cancelled.\n", iconError, yellow("INFO:"))
+		} else {
+			fmt.Printf("%s %s %v\n", iconError, yellow("INFO:"), err)
+		}
+		waitForEnter()
+		return
+	}
+
+	dst, err := promptForInput("Enter the full path of the archive to create (e.g. /tmp/saves.zip)")
+	if err != nil || dst == "" {
+		fmt.Printf("%s %s Export cancelled.\n", iconError, yellow("INFO:"))
+		waitForEnter()
+		return
+	}
+	if err := exportBackups(profile, selected, dst); err != nil {
+		fmt.Printf("%s %s Export failed: %v\n", iconError, red("ERROR:"), err)
+	} else {
+		fmt.Printf("%s %s Exported %d backup(s) to: %s\n", iconSuccess, green("SUCCESS:"), len(selected), dst)
+	}
+	waitForEnter()
+}
+
+func importMenu(profile Profile) {
+	src, err := promptForInput("Enter the full path of the archive to import")
+	if err != nil || src == "" {
+		fmt.Printf("%s %s Import cancelled.\n", iconError, yellow("INFO:"))
+		waitForEnter()
+		return
+	}
+	result, err := importBackups(profile, src)
+	printImportResult(result)
+	if err != nil {
+		fmt.Printf("%s %s Import failed: %v\n", iconError, red("ERROR:"), err)
+	} else if len(result.Imported) > 0 {
+		reportMirrors(profile)
+	}
+	waitForEnter()
+}
+
+// printImportResult prints one line per imported or skipped backup.
+func printImportResult(result ImportResult) {
+	for _, b := range result.Imported {
+		fmt.Printf("%s %s Imported: %s\n", iconSuccess, green("SUCCESS:"), b.Name)
+	}
+	for _, reason := range result.Skipped {
+		fmt.Printf("%s %s Skipped %s\n", iconError, yellow("INFO:"), reason)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRestoreTo(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		profile := newTestProfile(t)
+		backup, err := writeBackup(profile, "old", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, profile.SavePath, "slot 2")
+
+		dest := t.TempDir()
+		target, err := restoreTo(profile, backup, dest, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(dest, "old.dat"); target != want {
+			t.Errorf("wrote %s, want %s", target, want)
+		}
+		if got := readTestFile(t, target); got != "slot 1" {
+			t.Errorf("wrote %q, want the backup's content", got)
+		}
+
+		writeTestFile(t, target, "kept")
+		if _, err := restoreTo(profile, backup, dest, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("second restore: got %v, want a refusal to overwrite", err)
+		}
+		if got := readTestFile(t, target); got != "kept" {
+			t.Errorf("second restore overwrote the file with %q", got)
+		}
+		if _, err := restoreTo(profile, backup, profile.SavePath, false); err == nil {
+			t.Error("restore over the save itself succeeded")
+		}
+		if _, err := restoreTo(profile, backup, "relative", false); err == nil {
+			t.Error("restore to a relative path succeeded")
+		}
+		if got := readTestFile(t, profile.SavePath); got != "slot 2" {
+			t.Errorf("restoreTo changed the save to %q", got)
+		}
+	})
+
+	t.Run("folder", func(t *testing.T) {
+		profile := newTestProfile(t)
+		profile.SavePath = filepath.Join(t.TempDir(), "SaveData")
+		writeTestFile(t, filepath.Join(profile.SavePath, "slots", "1.sav"), "level 3")
+		backup, err := writeBackup(profile, "old", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := readTestTree(t, profile.SavePath)
+
+		for _, dest := range []string{profile.SavePath, filepath.Join(profile.SavePath, "slots")} {
+			if _, err := restoreTo(profile, backup, dest, false); err == nil || !strings.Contains(err.Error(), "inside it") {
+				t.Errorf("restore into %s: got %v, want a refusal", dest, err)
+			}
+		}
+		if got := readTestTree(t, profile.SavePath); !maps.Equal(got, want) {
+			t.Errorf("save after the refused restores: got %v, want %v", got, want)
+		}
+
+		// A sibling whose name starts with the save's isn't inside it.
+		dest := profile.SavePath + "Copy"
+		target, err := restoreTo(profile, backup, dest, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readTestTree(t, target); !maps.Equal(got, want) {
+			t.Errorf("restored %v, want %v", got, want)
+		}
+	})
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := newTestProfile(t)
+	source.Name = "source"
+	var exported []Backup
+	for i, format := range []ArchiveFormat{FormatPlain, FormatStore, FormatStore} {
+		source.Format = string(format)
+		writeTestFile(t, source.SavePath, "level "+strings.Repeat("I", i+1))
+		name := string(format) + strings.Repeat("+", i)
+		b, err := writeBackup(source, name, "before the "+name+" boss", []string{"boss", name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		exported = append(exported, b)
+	}
+	archive := filepath.Join(t.TempDir(), "export.zip")
+	if err := exportBackups(source, exported, archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportBackups(source, exported, archive); err == nil {
+		t.Error("export over an existing file succeeded")
+	}
+
+	target := newTestProfile(t)
+	result, err := importBackups(target, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != len(exported) || len(result.Skipped) != 0 {
+		t.Fatalf("imported %d and skipped %v, want all %d imported", len(result.Imported), result.Skipped, len(exported))
+	}
+	for _, want := range exported {
+		got, err := findBackup(target, want.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Meta == nil || got.Meta.SHA256 != want.Meta.SHA256 || got.Meta.ContentSHA256 != want.Meta.ContentSHA256 ||
+			got.Meta.Note != want.Meta.Note || !slices.Equal(got.Meta.Tags, want.Meta.Tags) ||
+			got.Meta.Profile != "source" || !got.CreatedAt.Equal(want.CreatedAt) {
+			t.Errorf("%s: imported manifest %+v, want %+v", want.Name, got.Meta, want.Meta)
+		}
+		if result := verifyBackup(got); result.Status != VerifyOK {
+			t.Errorf("%s: verify after import got %s (%s)", want.Name, result.Status, result.Detail)
+		}
+		if _, err := applyBackup(target, got, false); err != nil {
+			t.Fatal(err)
+		}
+		if sum, _, err := hashFile(target.SavePath); err != nil || sum != contentSum(want) {
+			t.Errorf("%s: restored %q from the import, want the exported save", want.Name, readTestFile(t, target.SavePath))
+		}
+		if want.Format == FormatStore {
+			blob := filepath.Join(target.BackupDir, filepath.FromSlash(blobPath(want.Meta.ContentSHA256)))
+			if _, err := os.Stat(blob); err != nil {
+				t.Errorf("%s: blob missing after import: %v", want.Name, err)
+			}
+		}
+	}
+
+	again, err := importBackups(target, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Imported) != 0 || len(again.Skipped) != len(exported) {
+		t.Errorf("second import: imported %d, skipped %v; want every name skipped", len(again.Imported), again.Skipped)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestProfile returns a profile whose save file and backup directory live
+// in a temporary directory, with the wait for a settled save turned off.
+func newTestProfile(t *testing.T) Profile {
+	t.Helper()
+	dir := t.TempDir()
+	profile := Profile{
+		Name:      "test",
+		SavePath:  filepath.Join(dir, "save.dat"),
+		BackupDir: filepath.Join(dir, "backups"),
+		StableFor: "0s",
+	}
+	if err := os.MkdirAll(profile.BackupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, profile.SavePath, "slot 1")
+	return profile
+}
+
+// writeTestFile writes content to path, creating its parent directories.
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// backupNames lists the profile's backups by name, newest first.
+func backupNames(t *testing.T, profile Profile) []string {
+	t.Helper()
+	backups, err := listBackupsInternal(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(backups))
+	for i, b := range backups {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// readTestFile returns the content of the file at path.
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// readTestTree returns the files under dir by slash-separated relative path.
+func readTestTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = readTestFile(t, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// captureStdout returns what fn prints.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	defer func() { os.Stdout = stdout }()
+	fn()
+	w.Close()
+	return <-done
+}
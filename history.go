@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// historyFileName is the hidden file in the backup directory that records
+// the profile's restores, newest last.
+const historyFileName = ".history.json"
+
+// maxHistory is how many restores the history keeps.
+const maxHistory = 20
+
+// errNothingToUndo is returned when the history holds no restore to undo.
+var errNothingToUndo = errors.New("there is no restore to undo")
+
+// RestoreRecord is one restore in a profile's history.
+type RestoreRecord struct {
+	Time    time.Time `json:"time"`
+	Backup  string    `json:"backup"`             // the backup that was restored
+	SavedAs string    `json:"saved_as,omitempty"` // the backup holding the save it overwrote
+	Undo    bool      `json:"undo,omitempty"`     // this restore undid the one before it
+	Undone  bool      `json:"undone,omitempty"`
+}
+
+func historyPath(profile Profile) string {
+	return filepath.Join(profile.BackupDir, historyFileName)
+}
+
+// readHistory loads the profile's restore history, oldest first. A missing
+// history file is an empty history.
+func readHistory(profile Profile) ([]RestoreRecord, error) {
+	data, err := os.ReadFile(historyPath(profile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []RestoreRecord
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("restore history is corrupted: %w", err)
+	}
+	return history, nil
+}
+
+// writeHistory saves the history, dropping all but the newest maxHistory
+// restores.
+func writeHistory(profile Profile, history []RestoreRecord) error {
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(historyPath(profile), data, 0644)
+}
+
+// recordRestore adds a restore to the history. With undo set it also marks
+// the restore it undid.
+func recordRestore(profile Profile, record RestoreRecord) error {
+	history, err := readHistory(profile)
+	if err != nil {
+		return err
+	}
+	if record.Undo && len(history) > 0 {
+		history[len(history)-1].Undone = true
+	}
+	return writeHistory(profile, append(history, record))
+}
+
+// undoTarget returns the name of the backup undo would restore, so pruning
+// can leave it alone. It returns "" when there is nothing to undo.
+func undoTarget(profile Profile) string {
+	history, err := readHistory(profile)
+	if err != nil || len(history) == 0 {
+		return ""
+	}
+	return history[len(history)-1].SavedAs
+}
+
+// planUndo returns the latest restore and the backup holding the save it
+// overwrote.
+func planUndo(profile Profile) (RestoreRecord, Backup, error) {
+	history, err := readHistory(profile)
+	if err != nil {
+		return RestoreRecord{}, Backup{}, err
+	}
+	if len(history) == 0 {
+		return RestoreRecord{}, Backup{}, errNothingToUndo
+	}
+	last := history[len(history)-1]
+	if last.SavedAs == "" && !profile.AutoBackup {
+		return last, Backup{}, fmt.Errorf("the save overwritten by restoring %s was not backed up, so the restore can't be undone: undo needs auto-backup on restore, which is off. Turn it on in Settings so later restores can be undone", last.Backup)
+	}
+	if last.SavedAs == "" {
+		return last, Backup{}, fmt.Errorf("the save overwritten by restoring %s was not backed up (auto-backup was off or there was no save), so the restore can't be undone", last.Backup)
+	}
+	backup, err := findBackup(profile, last.SavedAs)
+	if err != nil {
+		return last, Backup{}, fmt.Errorf("the backup of the overwritten save is gone: %w", err)
+	}
+	return last, backup, nil
+}
+
+// undoLastRestore puts back the save that the latest restore overwrote. The
+// undo is itself recorded as a restore, so undoing it again redoes the
+// original restore. It returns the name of the auto-backup taken first, if
+// any.
+func undoLastRestore(profile Profile) (string, error) {
+	_, backup, err := planUndo(profile)
+	if err != nil {
+		return "", err
+	}
+	autoBackupName, savedAs, err := restoreSave(profile, backup, false)
+	if err != nil {
+		return autoBackupName, err
+	}
+	record := RestoreRecord{Time: time.Now(), Backup: backup.Name, SavedAs: savedAs, Undo: true}
+	if err := recordRestore(profile, record); err != nil {
+		fmt.Printf("%s %s Could not update the restore history: %v\n", iconError, yellow("WARNING:"), err)
+	}
+	return autoBackupName, nil
+}
+
+// describeRecord renders a history entry for display.
+func describeRecord(r RestoreRecord) string {
+	line := fmt.Sprintf("%s  %s", r.Time.Format("01/02/2006 03:04:05 PM"), r.Backup)
+	if r.SavedAs != "" {
+		line += " (previous save kept as " + r.SavedAs + ")"
+	} else {
+		line += " (previous save not kept)"
+	}
+	var flags []string
+	if r.Undo {
+		flags = append(flags, "undo")
+	}
+	if r.Undone {
+		flags = append(flags, "undone")
+	}
+	if len(flags) > 0 {
+		line += " [" + strings.Join(flags, ", ") + "]"
+	}
+	return line
+}
+
+// undoMenu shows the restore history and offers to undo the latest restore.
+func undoMenu(profile Profile) {
+	clearScreen()
+	fmt.Println(cyan("====================================="))
+	fmt.Printf("%s %s UNDO LAST RESTORE\n", iconRestore, cyan("UNDO LAST RESTORE"))
+	fmt.Println(cyan("====================================="))
+	fmt.Println()
+
+	history, err := readHistory(profile)
+	if err != nil {
+		fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return
+	}
+	if len(history) == 0 {
+		fmt.Printf("%s %s No restores recorded yet.\n", iconInfo, yellow("INFO:"))
+		waitForEnter()
+		return
+	}
+	fmt.Printf("%s %s Recent restores (newest first):\n", iconInfo, white("INFO:"))
+	for i := len(history) - 1; i >= 0 && i >= len(history)-10; i-- {
+		fmt.Printf(" - %s\n", describeRecord(history[i]))
+	}
+	fmt.Println()
+
+	last, backup, err := planUndo(profile)
+	if err != nil {
+		fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return
+	}
+	fmt.Printf("%s %s This will put back the save as it was before %s was restored, from backup %s.\n", iconRestore, yellow("INFO:"), last.Backup, backup.Name)
+
+	if err := checkGameRunning(profile, profile.GameCheck.restorePolicy(), "restore"); err != nil {
+		fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return
+	}
+
+	confirm, err := promptForInput("Undo the last restore? (y/N)")
+	if err != nil || strings.ToLower(confirm) != "y" {
+		fmt.Printf("%s %s Undo cancelled.\n", iconError, yellow("INFO:"))
+		waitForEnter()
+		return
+	}
+
+	finishUndo(profile, last)
+	waitForEnter()
+}
+
+// finishUndo undoes a confirmed restore and prints the result.
+func finishUndo(profile Profile, last RestoreRecord) {
+	autoBackupName, err := undoLastRestore(profile)
+	if autoBackupName != "" {
+		fmt.Printf("%s %s Auto-backup of current save created: %s\n", iconSuccess, green("SUCCESS:"), autoBackupName)
+		reportAutoPrune(profile)
+		reportMirrors(profile)
+	}
+	if err != nil {
+		fmt.Printf("%s %s Failed to undo the restore: %v\n", iconError, red("ERROR:"), err)
+	} else {
+		fmt.Printf("%s %s Restore of %s undone.\n", iconSuccess, green("SUCCESS:"), last.Backup)
+	}
+}
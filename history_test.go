@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUndoLastRestore(t *testing.T) {
+	profile := newTestProfile(t)
+	profile.AutoBackup = true
+	if _, err := undoLastRestore(profile); !errors.Is(err, errNothingToUndo) {
+		t.Fatalf("undo with no history: got %v, want errNothingToUndo", err)
+	}
+	backup, err := writeBackup(profile, "old", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, profile.SavePath, "slot 2")
+	if _, err := applyBackup(profile, backup, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := undoLastRestore(profile); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, profile.SavePath); got != "slot 2" {
+		t.Errorf("undo left %q, want the overwritten save back", got)
+	}
+	if _, err := undoLastRestore(profile); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, profile.SavePath); got != "slot 1" {
+		t.Errorf("second undo left %q, want the restore redone", got)
+	}
+}
+
+func TestUndoNeedsAutoBackup(t *testing.T) {
+	profile := newTestProfile(t)
+	backup, err := writeBackup(profile, "old", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, profile.SavePath, "slot 2")
+	if _, err := applyBackup(profile, backup, false); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = planUndo(profile)
+	if err == nil || !strings.Contains(err.Error(), "auto-backup on restore") {
+		t.Errorf("got %v, want an error saying undo needs auto-backup", err)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,24 +13,34 @@ import (
 	"strings"
 	"time"
 
-	"github.com/AlecAivazis/survey/v2"
 	"github.com/fatih/color"
 	"github.com/inancgumus/screen"
 	"github.com/manifoldco/promptui"
+	"golang.org/x/term"
 )
 
 // Config holds the CLI settings
 type Config struct {
-	SavePath   string `json:"save_path"`
-	BackupDir  string `json:"backup_dir"`
-	AutoBackup bool   `json:"auto_backup"`
+	ActiveProfile string    `json:"active_profile"`
+	Profiles      []Profile `json:"profiles"`
+
+	// Legacy single-game fields, migrated into a "default" profile on load.
+	SavePath   string `json:"save_path,omitempty"`
+	BackupDir  string `json:"backup_dir,omitempty"`
+	AutoBackup bool   `json:"auto_backup,omitempty"`
 }
 
 // Backup represents a backup file
 type Backup struct {
 	Name      string
-	Path      string
+	Path      string // location for display; on disk for local storage
+	Key       string // name in the storage
+	storage   Storage
+	Format    ArchiveFormat
+	Encrypted bool // stored as <name><ext>.enc
 	CreatedAt time.Time
+	Meta      *Manifest // nil for backups made before manifests existed
+	MetaErr   error     // why an existing manifest couldn't be read; Meta is nil then
 }
 
 // Colors for CLI output
@@ -54,6 +65,10 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] != "menu" {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	config, configPath, err := loadConfig()
 	if err != nil {
 		fmt.Printf("%s %s Configuration error: %v\n", iconError, red("ERROR:"), err)
@@ -64,9 +79,22 @@ func main() {
 		return
 	}
 
+	// The full-screen interface needs a terminal; without one, or with the
+	// menu command, the classic menus are used.
+	if len(os.Args) == 1 && term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		if err := runTUI(config, configPath); err != nil {
+			fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+		}
+		return
+	}
+	runMenu(config, configPath)
+}
+
+// runMenu runs the classic numbered menu until the user exits.
+func runMenu(config Config, configPath string) {
 	for {
 		displayMenu(config)
-		choice, err := promptForChoice("Select an option (1-6)", []string{"1", "2", "3", "4", "5", "6"})
+		choice, err := promptForChoice("Select an option (1-9)", []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"})
 		clearScreen()
 		if err != nil {
 			if err == promptui.ErrInterrupt {
@@ -78,18 +106,25 @@ func main() {
 			continue
 		}
 
+		profile := config.activeProfile()
 		switch choice {
 		case "1":
-			createBackup(config)
+			createBackup(profile)
 		case "2":
-			restoreBackup(config)
+			restoreBackup(profile)
 		case "3":
-			listBackups(config)
+			undoMenu(profile)
 		case "4":
-			deleteBackups(config)
+			listBackups(profile)
 		case "5":
-			config, configPath = settingsMenu(config, configPath)
+			deleteBackups(profile)
 		case "6":
+			exportImportMenu(profile)
+		case "7":
+			config = switchProfile(config, configPath)
+		case "8":
+			config, configPath = settingsMenu(config, configPath)
+		case "9":
 			fmt.Printf("%s %s Thank you for using Game Save Backup Manager!\n", iconSuccess, green("INFO:"))
 			fmt.Println("Press Enter to exit...")
 			fmt.Scanln()
@@ -99,22 +134,16 @@ func main() {
 }
 
 func loadConfig() (Config, string, error) {
-	exePath, err := os.Executable()
+	configPath, err := defaultConfigPath()
 	if err != nil {
-		return Config{}, "", fmt.Errorf("failed to get executable path: %w", err)
+		return Config{}, "", err
 	}
-	exeDir := filepath.Dir(exePath)
-	configPath := filepath.Join(exeDir, "config.json")
 
 	// Try to load existing config
-	if data, err := os.ReadFile(configPath); err == nil {
-		var config Config
-		if err := json.Unmarshal(data, &config); err != nil {
-			return Config{}, "", fmt.Errorf("configuration file is corrupted - please delete config.json and restart")
-		}
-		// Ensure backup directory exists
-		if err := os.MkdirAll(config.BackupDir, 0755); err != nil {
-			return Config{}, "", fmt.Errorf("cannot access backup directory: %s", config.BackupDir)
+	if _, err := os.Stat(configPath); err == nil {
+		config, err := readConfig(configPath)
+		if err != nil {
+			return Config{}, "", err
 		}
 		return config, configPath, nil
 	}
@@ -133,6 +162,45 @@ func loadConfig() (Config, string, error) {
 	return config, configPath, nil
 }
 
+// defaultConfigPath returns the location of config.json next to the executable.
+func defaultConfigPath() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	return filepath.Join(filepath.Dir(exePath), "config.json"), nil
+}
+
+// readConfig loads an existing config file and makes sure its backup directory exists.
+func readConfig(configPath string) (Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("configuration file is corrupted - please delete config.json and restart")
+	}
+	if migrateLegacyConfig(&config) {
+		if err := saveConfig(config, configPath); err != nil {
+			return Config{}, fmt.Errorf("failed to save migrated configuration: %w", err)
+		}
+	}
+	if len(config.Profiles) == 0 {
+		return Config{}, fmt.Errorf("configuration has no game profiles - please delete config.json and restart")
+	}
+	if _, err := config.profile(config.ActiveProfile); err != nil {
+		config.ActiveProfile = config.Profiles[0].Name
+	}
+	// Ensure backup directories exist
+	for _, profile := range config.Profiles {
+		if err := os.MkdirAll(profile.BackupDir, 0755); err != nil {
+			return Config{}, fmt.Errorf("cannot access backup directory for %s: %s", profile.Name, profile.BackupDir)
+		}
+	}
+	return config, nil
+}
+
 func runFirstTimeSetup() (Config, error) {
 	clearScreen()
 	fmt.Println(cyan("====================================="))
@@ -159,28 +227,17 @@ func runFirstTimeSetup() (Config, error) {
 	}
 	fmt.Println()
 
-	var config Config
-	var err error
-
-	// Get save file path with improved validation
-	config.SavePath, err = getSaveFilePath()
-	if err != nil {
-		return Config{}, err
-	}
-
-	// Get backup directory with validation
-	config.BackupDir, err = getBackupDirectory()
+	profile, err := setupProfile(Config{}, defaultProfileName)
 	if err != nil {
 		return Config{}, err
 	}
-
-	// Set default auto-backup to true
-	config.AutoBackup = true
+	config := Config{ActiveProfile: profile.Name, Profiles: []Profile{profile}}
 
 	fmt.Printf("\n%s %s Configuration completed successfully!\n", iconSuccess, green("SUCCESS:"))
-	fmt.Printf("%s %s Save file: %s\n", iconInfo, white("INFO:"), config.SavePath)
-	fmt.Printf("%s %s Backup directory: %s\n", iconInfo, white("INFO:"), config.BackupDir)
-	fmt.Printf("%s %s Auto-backup on restore: %v\n", iconInfo, white("INFO:"), config.AutoBackup)
+	fmt.Printf("%s %s Game profile: %s\n", iconInfo, white("INFO:"), profile.Name)
+	fmt.Printf("%s %s Save file: %s\n", iconInfo, white("INFO:"), profile.SavePath)
+	fmt.Printf("%s %s Backup directory: %s\n", iconInfo, white("INFO:"), profile.BackupDir)
+	fmt.Printf("%s %s Auto-backup on restore: %v\n", iconInfo, white("INFO:"), profile.AutoBackup)
 	fmt.Println()
 	fmt.Printf("%s %s You can now create your first backup from the main menu!\n", iconSuccess, green("NEXT:"))
 	waitForEnter()
@@ -191,11 +248,11 @@ func runFirstTimeSetup() (Config, error) {
 func getSaveFilePath() (string, error) {
 	for {
 		fmt.Printf("%s %s SAVE FILE SETUP\n", iconSettings, cyan("STEP 1:"))
-		fmt.Println("Enter the full path to your game save file.")
+		fmt.Println("Enter the full path to your game save file, or to the folder that holds your saves.")
 		fmt.Printf("Type '%s' to exit setup.\n", yellow("exit"))
 		fmt.Println()
 
-		savePath, err := promptForInput("Save file path")
+		savePath, err := promptForInput("Save file or folder path")
 		if err != nil {
 			if err == promptui.ErrInterrupt {
 				return "", fmt.Errorf("setup cancelled by user")
@@ -221,23 +278,23 @@ func getSaveFilePath() (string, error) {
 			continue
 		}
 
-		// Check if file exists
-		if _, err := os.Stat(savePath); os.IsNotExist(err) {
-			fmt.Printf("%s %s File not found: %s\n", iconError, red("ERROR:"), savePath)
-			fmt.Printf("%s %s Please check the path and make sure the file exists.\n", iconError, red("TIP:"))
-			fmt.Println()
-			continue
-		}
-
-		// Check if it's actually a file (not a directory)
-		if info, err := os.Stat(savePath); err == nil && info.IsDir() {
-			fmt.Printf("%s %s Path points to a directory, not a file: %s\n", iconError, red("ERROR:"), savePath)
+		// Check if file or folder exists
+		info, err := os.Stat(savePath)
+		if os.IsNotExist(err) {
+			fmt.Printf("%s %s Path not found: %s\n", iconError, red("ERROR:"), savePath)
+			fmt.Printf("%s %s Please check the path and make sure the file or folder exists.\n", iconError, red("TIP:"))
 			fmt.Println()
 			continue
 		}
 
 		// Check read permissions
-		if file, err := os.Open(savePath); err != nil {
+		if info != nil && info.IsDir() {
+			if _, err := os.ReadDir(savePath); err != nil {
+				fmt.Printf("%s %s Cannot read folder (permission denied): %s\n", iconError, red("ERROR:"), savePath)
+				fmt.Println()
+				continue
+			}
+		} else if file, err := os.Open(savePath); err != nil {
 			fmt.Printf("%s %s Cannot read file (permission denied): %s\n", iconError, red("ERROR:"), savePath)
 			fmt.Println()
 			continue
@@ -245,7 +302,7 @@ func getSaveFilePath() (string, error) {
 			file.Close()
 		}
 
-		fmt.Printf("%s %s Save file validated successfully!\n", iconSuccess, green("SUCCESS:"))
+		fmt.Printf("%s %s Save path validated successfully!\n", iconSuccess, green("SUCCESS:"))
 		fmt.Println()
 		return savePath, nil
 	}
@@ -336,21 +393,30 @@ func saveConfig(config Config, configPath string) error {
 }
 
 func displayMenu(config Config) {
+	profile := config.activeProfile()
 	clearScreen()
 	fmt.Println(cyan("====================================="))
 	fmt.Printf("%s %s\n", iconSettings, cyan("GAME SAVE BACKUP MANAGER"))
 	fmt.Println(cyan("====================================="))
 	fmt.Println()
-	fmt.Printf("%s %s Current Save File: %s\n", iconDir, white("INFO:"), config.SavePath)
-	fmt.Printf("%s %s Current Backup Directory: %s\n", iconDir, white("INFO:"), config.BackupDir)
-	fmt.Printf("%s %s Auto-Backup on Restore: %v\n", iconSettings, white("INFO:"), config.AutoBackup)
+	fmt.Printf("%s %s Current Game: %s\n", iconInfo, white("INFO:"), profile.Name)
+	fmt.Printf("%s %s Current Save File: %s\n", iconDir, white("INFO:"), profile.SavePath)
+	fmt.Printf("%s %s Current Backup Directory: %s\n", iconDir, white("INFO:"), profile.BackupDir)
+	fmt.Printf("%s %s Auto-Backup on Restore: %v\n", iconSettings, white("INFO:"), profile.AutoBackup)
 	fmt.Println()
 	fmt.Printf("1. %s Create Backup\n", iconSuccess)
 	fmt.Printf("2. %s Restore Backup\n", iconRestore)
-	fmt.Printf("3. %s List Backups\n", iconDir)
-	fmt.Printf("4. %s Delete Backup\n", iconDelete)
-	fmt.Printf("5. %s Settings\n", iconSettings)
-	fmt.Printf("6. %s Exit\n", iconExit)
+	if profile.AutoBackup {
+		fmt.Printf("3. %s Undo Last Restore\n", iconRestore)
+	} else {
+		fmt.Printf("3. %s Undo Last Restore (needs Auto-Backup on Restore, which is off)\n", iconRestore)
+	}
+	fmt.Printf("4. %s List Backups\n", iconDir)
+	fmt.Printf("5. %s Delete Backup\n", iconDelete)
+	fmt.Printf("6. %s Export / Import Backups\n", iconDir)
+	fmt.Printf("7. %s Switch Game\n", iconRestore)
+	fmt.Printf("8. %s Settings\n", iconSettings)
+	fmt.Printf("9. %s Exit\n", iconExit)
 	fmt.Println()
 }
 
@@ -384,20 +450,28 @@ func promptForInput(prompt string) (string, error) {
 	return strings.TrimSpace(result), nil
 }
 
-func createBackup(config Config) {
+func createBackup(profile Profile) {
 	clearScreen()
 	fmt.Println(cyan("====================================="))
 	fmt.Printf("%s %s CREATE BACKUP\n", iconSuccess, cyan("CREATE BACKUP"))
 	fmt.Println(cyan("====================================="))
 	fmt.Println()
 
-	if _, err := os.Stat(config.SavePath); os.IsNotExist(err) {
-		fmt.Printf("%s %s Save file not found at: %s\n", iconError, red("ERROR:"), config.SavePath)
+	if _, err := os.Stat(profile.SavePath); os.IsNotExist(err) {
+		fmt.Printf("%s %s Save file not found at: %s\n", iconError, red("ERROR:"), profile.SavePath)
 		fmt.Printf("%s %s Please check the path in Settings.\n", iconError, red("ERROR:"))
 		waitForEnter()
 		return
 	}
 
+	if !checkDuplicate(profile, func(prompt string) bool {
+		answer, err := promptForInput(prompt)
+		return err == nil && strings.ToLower(answer) == "y"
+	}) {
+		waitForEnter()
+		return
+	}
+
 	backupName, err := promptForInput("Enter backup name (press Enter for default)")
 	if err != nil {
 		if err != promptui.ErrInterrupt {
@@ -407,50 +481,276 @@ func createBackup(config Config) {
 		return
 	}
 
+	note, err := promptForInput("Enter a note, e.g. \"before boss fight\" (optional)")
+	if err != nil {
+		if err != promptui.ErrInterrupt {
+			fmt.Printf("%s %s Failed to read input: %v\n", iconError, red("ERROR:"), err)
+		}
+		waitForEnter()
+		return
+	}
+
+	finishCreateBackup(profile, backupName, note)
+	waitForEnter()
+}
+
+// finishCreateBackup checks for the running game, creates the backup and
+// prints the result, once the name and note are known.
+func finishCreateBackup(profile Profile, backupName, note string) {
+	if err := checkGameRunning(profile, profile.GameCheck.backupPolicy(), "back up"); err != nil {
+		fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+		return
+	}
+
+	backup, err := writeBackup(profile, backupName, note, nil)
+	if err != nil {
+		fmt.Printf("%s %s Failed to create backup: %v\n", iconError, red("ERROR:"), err)
+		return
+	}
+	fmt.Printf("%s %s Backup created successfully!\n", iconSuccess, green("SUCCESS:"))
+	fmt.Printf("%s %s Backup name: %s\n", iconSuccess, green("INFO:"), backup.Name)
+	fmt.Printf("%s %s Created at: %s\n", iconSuccess, green("INFO:"), backup.CreatedAt.Format("01/02/2006 03:04:05 PM"))
+	fmt.Printf("%s %s Size: %s\n", iconSuccess, green("INFO:"), formatSize(backup.Meta.Size))
+	reportAutoPrune(profile)
+	reportMirrors(profile)
+}
+
+// writeBackup copies the save file or save directory into the backup directory
+// under the given name and records its manifest. An empty name gets a
+// timestamped default, and a numeric suffix is added when a backup with that
+// name already exists.
+func writeBackup(profile Profile, backupName, note string, tags []string) (Backup, error) {
+	createdAt := time.Now()
+	info, err := os.Stat(profile.SavePath)
+	if err != nil {
+		return Backup{}, fmt.Errorf("failed to read save: %w", err)
+	}
+	if info.IsDir() {
+		files, err := collectSaveFiles(profile, profile.SavePath)
+		if err != nil {
+			return Backup{}, err
+		}
+		if len(files) == 0 {
+			return Backup{}, fmt.Errorf("%w: %s is empty or every file in it is filtered out", errEmptySave, profile.SavePath)
+		}
+	}
+
 	if backupName == "" {
-		backupName = fmt.Sprintf("Backup_%s", time.Now().Format("2006-01-02_15-04-05"))
+		backupName = fmt.Sprintf("Backup_%s", createdAt.Format("2006-01-02_15-04-05"))
 	}
 
-	backupPath := filepath.Join(config.BackupDir, backupName+".sav")
-	counter := 1
-	baseName := backupName
-	for {
-		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
-			break
+	backupName, err = uniqueBackupName(profile, backupName)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	format, err := parseArchiveFormat(profile.Format)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	encrypted := profile.Encryption.Enabled
+	if encrypted && format == FormatStore {
+		return Backup{}, fmt.Errorf("the store format can't be encrypted; choose another backup format")
+	}
+	if encrypted && format == FormatPlain && info.IsDir() {
+		// Encryption works on single files, so folders are packed first.
+		format = FormatTarGz
+	}
+
+	st, err := openStorage(profile)
+	if err != nil {
+		return Backup{}, err
+	}
+	// Backups are written straight into local storage. For remote storage
+	// they are built in a scratch folder and uploaded from there.
+	workDir, local := localPath(st, "")
+	if !local {
+		if workDir, err = scratchDir("upload"); err != nil {
+			return Backup{}, fmt.Errorf("failed to prepare upload: %w", err)
 		}
-		backupName = fmt.Sprintf("%s_%d", baseName, counter)
-		backupPath = filepath.Join(config.BackupDir, backupName+".sav")
-		counter++
+		defer os.RemoveAll(workDir)
 	}
 
-	data, err := os.ReadFile(config.SavePath)
+	var key string
+	var capture func(dst string) error
+	switch {
+	case format == FormatStore:
+		key = backupName + archiveExtension(format)
+		capture = func(dst string) error { return writeSnapshot(profile, st, dst) }
+	case format != FormatPlain:
+		key = backupName + archiveExtension(format)
+		capture = func(dst string) error { return writeArchive(profile, format, dst) }
+	case info.IsDir():
+		// Directory saves are stored as a folder named after the backup.
+		key = backupName
+		capture = func(dst string) error { return copySaveTree(profile, profile.SavePath, dst) }
+	default:
+		key = backupName + ".sav"
+		capture = func(dst string) error {
+			data, err := os.ReadFile(profile.SavePath)
+			if err != nil {
+				return fmt.Errorf("failed to read save file: %w", err)
+			}
+			return os.WriteFile(dst, data, 0644)
+		}
+	}
+	if encrypted {
+		passphrase, err := backupPassphrase(profile, true)
+		if err != nil {
+			return Backup{}, err
+		}
+		// The plaintext is written to a scratch folder outside the backup
+		// directory and encrypted from there.
+		plain := capture
+		key += encryptedExtension
+		capture = func(dst string) error {
+			dir, err := scratchDir("encrypt")
+			if err != nil {
+				return fmt.Errorf("failed to prepare encryption: %w", err)
+			}
+			defer os.RemoveAll(dir)
+			tmp := filepath.Join(dir, filepath.Base(dst))
+			if err := plain(tmp); err != nil {
+				return err
+			}
+			return encryptFile(tmp, dst, passphrase)
+		}
+	}
+	backupPath := filepath.Join(workDir, key)
+
+	// Copy only once the game has stopped writing, and retry if it starts again
+	// mid-copy. The content hash is taken inside the same window so it
+	// describes exactly what was copied.
+	var contentSum string
+	copyAndHash := func() error {
+		if err := capture(backupPath); err != nil {
+			return err
+		}
+		var err error
+		contentSum, _, err = hashSave(profile)
+		return err
+	}
+	waited, err := captureStableSave(profile, copyAndHash, func() { os.RemoveAll(backupPath) })
 	if err != nil {
-		fmt.Printf("%s %s Failed to read save file: %v\n", iconError, red("ERROR:"), err)
-		waitForEnter()
-		return
+		return Backup{}, err
+	}
+	if waited >= time.Second {
+		fmt.Printf("%s %s Waited %s for the save to stop changing.\n", iconInfo, white("INFO:"), waited.Round(100*time.Millisecond))
 	}
 
-	err = os.WriteFile(backupPath, data, 0644)
+	sum, size, err := hashBackup(backupPath)
 	if err != nil {
-		fmt.Printf("%s %s Failed to create backup: %v\n", iconError, red("ERROR:"), err)
-	} else {
-		createdAt, _ := getFileCreationTime(backupPath)
-		fmt.Printf("%s %s Backup created successfully!\n", iconSuccess, green("SUCCESS:"))
-		fmt.Printf("%s %s Backup name: %s\n", iconSuccess, green("INFO:"), backupName)
-		fmt.Printf("%s %s Created at: %s\n", iconSuccess, green("INFO:"), createdAt.Format("01/02/2006 03:04:05 PM"))
+		os.RemoveAll(backupPath)
+		return Backup{}, fmt.Errorf("failed to record backup metadata: %w", err)
+	}
+	if !local {
+		if info, _ := os.Stat(backupPath); info != nil && info.IsDir() {
+			err = putTree(st, key, backupPath)
+		} else {
+			err = putFile(st, key, backupPath)
+		}
+		if err != nil {
+			st.Delete(key)
+			return Backup{}, fmt.Errorf("failed to upload backup to %s: %w", st, err)
+		}
 	}
 
-	waitForEnter()
+	manifest := &Manifest{
+		Name:          backupName,
+		Profile:       profile.Name,
+		SourcePath:    profile.SavePath,
+		Format:        format,
+		Encrypted:     encrypted,
+		SHA256:        sum,
+		ContentSHA256: contentSum,
+		Size:          size,
+		CreatedAt:     createdAt,
+		ToolVersion:   version,
+		Note:          note,
+		Tags:          tags,
+	}
+	if err = writeManifest(st, key, manifest); err != nil {
+		// A backup without its manifest can't be verified later, so don't keep it.
+		st.Delete(key)
+		return Backup{}, fmt.Errorf("failed to record backup metadata: %w", err)
+	}
+	return newBackup(st, key, backupName, format, encrypted, createdAt, manifest), nil
+}
+
+// newBackup describes a backup stored under key.
+func newBackup(st Storage, key, name string, format ArchiveFormat, encrypted bool, createdAt time.Time, meta *Manifest) Backup {
+	p, ok := localPath(st, key)
+	if !ok {
+		p = strings.TrimSuffix(st.String(), "/") + "/" + key
+	}
+	return Backup{Name: name, Path: p, Key: key, storage: st, Format: format, Encrypted: encrypted, CreatedAt: createdAt, Meta: meta}
+}
+
+// uniqueBackupName adds a numeric suffix to name if a backup already uses it.
+func uniqueBackupName(profile Profile, name string) (string, error) {
+	counter := 1
+	baseName := name
+	for {
+		exists, err := backupExists(profile, name)
+		if err != nil || !exists {
+			return name, err
+		}
+		name = fmt.Sprintf("%s_%d", baseName, counter)
+		counter++
+	}
 }
 
-func restoreBackup(config Config) {
+// backupExists reports whether a backup with this name exists in any format.
+func backupExists(profile Profile, name string) (bool, error) {
+	candidates := []string{name}
+	for _, format := range archiveFormats {
+		candidates = append(candidates, name+archiveExtension(format), name+archiveExtension(format)+encryptedExtension)
+	}
+	st, err := openStorage(profile)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range candidates {
+		if exists, err := storageExists(st, p); err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+func restoreBackup(profile Profile) {
 	clearScreen()
 	fmt.Println(cyan("====================================="))
 	fmt.Printf("%s %s RESTORE BACKUP\n", iconRestore, cyan("RESTORE BACKUP"))
 	fmt.Println(cyan("====================================="))
 	fmt.Println()
 
-	backups, err := listBackupsInternal(config)
+	// With mirrors set up, offer to restore from one of them, e.g. when the
+	// backup directory is gone.
+	source := ""
+	if len(profile.Mirrors) > 0 {
+		items := []string{"Backup directory - " + describeStorage(profile)}
+		for _, m := range profile.Mirrors {
+			items = append(items, "Mirror "+m.Name+" - "+describeMirror(m))
+		}
+		prompt := promptui.Select{Label: white("Restore from"), Items: items}
+		index, _, err := prompt.Run()
+		if err != nil {
+			return
+		}
+		if index > 0 {
+			source = profile.Mirrors[index-1].Name
+		}
+	}
+
+	st, err := backupSource(profile, source)
+	if err != nil {
+		fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return
+	}
+	backups, err := listBackupsIn(st)
 	if err != nil {
 		fmt.Printf("%s %s Failed to list backups: %v\n", iconError, red("ERROR:"), err)
 		waitForEnter()
@@ -463,37 +763,66 @@ func restoreBackup(config Config) {
 		return
 	}
 
-	items := make([]string, len(backups))
-	for i, backup := range backups {
-		items[i] = fmt.Sprintf("%s (Created: %s)", backup.Name, backup.CreatedAt.Format("01/02/2006 03:04:05 PM"))
-	}
-
-	prompt := promptui.Select{
-		Label: white("Select a backup to restore (or cancel)"),
-		Items: append(items, "Cancel"),
-		// Keep the list height reasonable – no scroll‑back!
-		Size: 7, // fits nicely on 24‑line consoles; tweak if you like
-	}
-	index, _, err := prompt.Run()
+	selectedBackup, err := pickBackup("Select a backup to restore", backups)
 	if err != nil {
-		if err != promptui.ErrInterrupt {
+		if errors.Is(err, errCancelled) {
+			fmt.Printf("%s %s Restore cancelled.\n", iconError, yellow("INFO:"))
+		} else if err != promptui.ErrInterrupt {
 			fmt.Printf("%s %s Failed to select backup: %v\n", iconError, red("ERROR:"), err)
 		}
 		waitForEnter()
 		return
 	}
-	if index == len(items) {
-		fmt.Printf("%s %s Restore cancelled.\n", iconError, yellow("INFO:"))
-		waitForEnter()
+	fmt.Println()
+	fmt.Printf("%s %s Selected backup: %s\n", iconRestore, yellow("INFO:"), selectedBackup.Name)
+	printBackupDetails(selectedBackup)
+	fmt.Println()
+
+	target := promptui.Select{
+		Label: white("Where should the backup be restored?"),
+		Items: []string{"Over the current save", "To another location...", "Compare with the current save first", "Cancel"},
+	}
+	targetIndex := 2
+	for targetIndex == 2 {
+		targetIndex, _, err = target.Run()
+		if err != nil || targetIndex == 3 {
+			fmt.Printf("%s %s Restore cancelled.\n", iconError, yellow("INFO:"))
+			waitForEnter()
+			return
+		}
+		if targetIndex == 2 {
+			fmt.Println()
+			if err := compareWithSave(profile, selectedBackup); err != nil {
+				fmt.Printf("%s %s Failed to compare: %v\n", iconError, red("ERROR:"), err)
+			}
+			fmt.Println()
+		}
+	}
+	if targetIndex == 1 {
+		restoreToPrompt(profile, selectedBackup)
 		return
 	}
-
-	selectedBackup := backups[index]
-	fmt.Println()
 	fmt.Printf("%s %s WARNING: This will overwrite your current save file!\n", iconError, yellow("WARNING:"))
-	fmt.Printf("%s %s Selected backup: %s\n", iconRestore, yellow("INFO:"), selectedBackup.Name)
 	fmt.Println()
 
+	force := false
+	if result := verifyBackup(selectedBackup); !result.healthy() {
+		fmt.Printf("%s %s This backup is %s (%s) and may not restore correctly!\n", iconError, red("WARNING:"), result.Status, result.Detail)
+		answer, err := promptForInput("Type 'force' to restore it anyway")
+		if err != nil || strings.ToLower(answer) != "force" {
+			fmt.Printf("%s %s Restore cancelled.\n", iconError, yellow("INFO:"))
+			waitForEnter()
+			return
+		}
+		force = true
+	}
+
+	if err := checkGameRunning(profile, profile.GameCheck.restorePolicy(), "restore"); err != nil {
+		fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return
+	}
+
 	confirm, err := promptForInput("Are you sure you want to restore this backup? (y/N)")
 	if err != nil || strings.ToLower(confirm) != "y" {
 		fmt.Printf("%s %s Restore cancelled.\n", iconError, yellow("INFO:"))
@@ -501,43 +830,153 @@ func restoreBackup(config Config) {
 		return
 	}
 
-	if config.AutoBackup {
-		if _, err := os.Stat(config.SavePath); !os.IsNotExist(err) {
-			autoBackupName := fmt.Sprintf("AutoBackup_%s", time.Now().Format("2006-01-02_15-04-05"))
-			autoBackupPath := filepath.Join(config.BackupDir, autoBackupName+".sav")
-			data, err := os.ReadFile(config.SavePath)
-			if err == nil {
-				err = os.WriteFile(autoBackupPath, data, 0644)
-				if err == nil {
-					fmt.Printf("%s %s Auto-backup of current save created: %s\n", iconSuccess, green("SUCCESS:"), autoBackupName)
+	finishRestore(profile, selectedBackup, force)
+	waitForEnter()
+}
+
+// finishRestore restores a confirmed backup over the save and prints the
+// result.
+func finishRestore(profile Profile, backup Backup, force bool) {
+	autoBackupName, err := applyBackup(profile, backup, force)
+	if autoBackupName != "" {
+		fmt.Printf("%s %s Auto-backup of current save created: %s\n", iconSuccess, green("SUCCESS:"), autoBackupName)
+		reportAutoPrune(profile)
+		reportMirrors(profile)
+	}
+	if err != nil {
+		fmt.Printf("%s %s Failed to restore backup: %v\n", iconError, red("ERROR:"), err)
+	} else {
+		fmt.Printf("%s %s Backup restored successfully!\n", iconSuccess, green("SUCCESS:"))
+	}
+}
+
+// applyBackup overwrites the save with the given backup and records the
+// restore in the history, so it can be undone. Backups that fail verification
+// are refused unless force is set. When auto-backup is enabled the current
+// save is copied first, and if that fails the save is left alone unless force
+// is set; the name of that auto-backup is returned, or "" if none was made.
+func applyBackup(profile Profile, backup Backup, force bool) (string, error) {
+	autoBackupName, savedAs, err := restoreSave(profile, backup, force)
+	if err != nil {
+		return autoBackupName, err
+	}
+	record := RestoreRecord{Time: time.Now(), Backup: backup.Name, SavedAs: savedAs}
+	if err := recordRestore(profile, record); err != nil {
+		fmt.Printf("%s %s Could not update the restore history: %v\n", iconError, yellow("WARNING:"), err)
+	}
+	return autoBackupName, nil
+}
+
+// restoreSave does the work of applyBackup without touching the history. It
+// also returns the name of the backup that holds the overwritten save: the
+// new auto-backup, or the latest backup if the save was identical to it.
+func restoreSave(profile Profile, backup Backup, force bool) (autoBackupName, savedAs string, err error) {
+	if result := verifyBackup(backup); !result.healthy() && !force {
+		return "", "", fmt.Errorf("%w: %s is %s (%s)", errBackupDamaged, backup.Name, result.Status, result.Detail)
+	}
+
+	// Unpack the backup first, so a wrong passphrase or broken archive fails
+	// before anything else happens.
+	// Backups are downloaded, decrypted and unpacked in a scratch folder
+	// outside the backup directory.
+	staging, err := scratchDir("restore")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to prepare restore: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	source, err := unpackBackup(profile, backup, staging)
+	if err != nil {
+		return "", "", err
+	}
+
+	if profile.AutoBackup {
+		_, err := os.Stat(profile.SavePath)
+		// No need for an auto-backup when the latest backup already holds this save.
+		dup, duplicate := shouldSkipAutoBackup(profile)
+		switch {
+		case os.IsNotExist(err):
+		case duplicate:
+			savedAs = dup.Name
+		default:
+			name := fmt.Sprintf("AutoBackup_%s", time.Now().Format("2006-01-02_15-04-05"))
+			note := fmt.Sprintf("Automatic backup before restoring %s", backup.Name)
+			auto, err := writeBackup(profile, name, note, []string{"auto"})
+			if err != nil {
+				if !force {
+					return "", "", fmt.Errorf("%w: %v (force the restore to go ahead without a way to undo it)", errAutoBackupFailed, err)
 				}
+				fmt.Printf("%s %s Could not back up the current save: %v. Restoring anyway; this restore can't be undone.\n", iconError, yellow("WARNING:"), err)
+				break
 			}
+			autoBackupName = auto.Name
+			savedAs = auto.Name
 		}
 	}
 
-	data, err := os.ReadFile(selectedBackup.Path)
+	sourceInfo, err := os.Stat(source)
 	if err != nil {
-		fmt.Printf("%s %s Failed to read backup: %v\n", iconError, red("ERROR:"), err)
-	} else {
-		err = os.WriteFile(config.SavePath, data, 0644)
-		if err != nil {
-			fmt.Printf("%s %s Failed to restore backup: %v\n", iconError, red("ERROR:"), err)
-		} else {
-			fmt.Printf("%s %s Backup restored successfully!\n", iconSuccess, green("SUCCESS:"))
-		}
+		return autoBackupName, savedAs, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if saveInfo, err := os.Stat(profile.SavePath); err == nil && saveInfo.IsDir() != sourceInfo.IsDir() {
+		return autoBackupName, savedAs, fmt.Errorf("backup %s does not match the save type (file vs. directory)", backup.Name)
 	}
 
-	waitForEnter()
+	if sourceInfo.IsDir() {
+		return autoBackupName, savedAs, replaceSaveTree(profile, source)
+	}
+	return autoBackupName, savedAs, replaceSaveFile(profile.SavePath, source)
 }
 
-func listBackups(config Config) {
+// unpackBackup downloads, decrypts and extracts a backup as needed, using
+// staging for scratch space, and returns the path of the plain save file or
+// folder.
+func unpackBackup(profile Profile, backup Backup, staging string) (string, error) {
+	source, err := fetchBackup(backup, staging)
+	if err != nil {
+		return "", err
+	}
+	if backup.Format != FormatPlain || backup.Encrypted {
+		if backup.Encrypted {
+			passphrase, err := backupPassphrase(profile, false)
+			if err != nil {
+				return "", err
+			}
+			decrypted := filepath.Join(staging, ".decrypted")
+			if err := decryptFile(source, decrypted, passphrase); err != nil {
+				if errors.Is(err, errWrongPassphrase) {
+					// Ask again next time rather than retrying a mistyped passphrase.
+					forgetPassphrase(profile)
+				}
+				return "", fmt.Errorf("failed to decrypt %s: %w", backup.Name, err)
+			}
+			source = decrypted
+		}
+		if backup.Format != FormatPlain {
+			unpacked, err := os.MkdirTemp(staging, "unpacked-")
+			if err != nil {
+				return "", fmt.Errorf("failed to prepare restore: %w", err)
+			}
+			if backup.Format == FormatStore {
+				source, err = extractSnapshot(backup.storage, source, unpacked)
+			} else {
+				source, err = extractArchive(source, unpacked)
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return source, nil
+}
+
+func listBackups(profile Profile) {
 	clearScreen()
 	fmt.Println(cyan("====================================="))
 	fmt.Printf("%s %s BACKUP LIST\n", iconDir, cyan("BACKUP LIST"))
 	fmt.Println(cyan("====================================="))
 	fmt.Println()
 
-	backups, err := listBackupsInternal(config)
+	backups, err := listBackupsInternal(profile)
 	if err != nil {
 		fmt.Printf("%s %s Failed to list backups: %v\n", iconError, red("ERROR:"), err)
 		waitForEnter()
@@ -549,46 +988,70 @@ func listBackups(config Config) {
 		return
 	}
 
-	// Build display strings once.
-	items := make([]string, len(backups))
-	for i, b := range backups {
-		items[i] = fmt.Sprintf("%s (Created: %s)",
-			b.Name, b.CreatedAt.Format("01/02/2006 03:04:05 PM"))
+	backup, err := pickBackup("Select a backup to rename, annotate or pin", backups)
+	if err != nil {
+		return
 	}
+	manageBackupMenu(profile, backup)
+}
 
-	sel := promptui.Select{
-		Label: white("Backups(↲ to leave)"),
-		Items: items,
-		// Keep the list height reasonable – no scroll‑back!
-		Size:         7, // fits nicely on 24‑line consoles; tweak if you like
-		HideSelected: true,
+// backupLabel formats a backup for the selection lists.
+func backupLabel(b Backup) string {
+	label := fmt.Sprintf("%s (Created: %s, %s)", b.Name, b.CreatedAt.Format("01/02/2006 03:04:05 PM"), b.formatLabel())
+	if isPinned(b) {
+		label += " [pinned]"
 	}
+	if b.Meta != nil {
+		if b.Meta.Note != "" {
+			label += " - " + b.Meta.Note
+		}
+		if len(b.Meta.Tags) > 0 {
+			label += " [" + strings.Join(b.Meta.Tags, ", ") + "]"
+		}
+	}
+	return label
+}
 
-	// We don’t actually *use* the selection; we only want the paginated view.
-	_, _, _ = sel.Run()
+func listBackupsInternal(profile Profile) ([]Backup, error) {
+	st, err := openStorage(profile)
+	if err != nil {
+		return nil, err
+	}
+	return listBackupsIn(st)
 }
 
-func listBackupsInternal(config Config) ([]Backup, error) {
-	files, err := os.ReadDir(config.BackupDir)
+// listBackupsIn lists the backups kept in a storage, newest first.
+func listBackupsIn(st Storage) ([]Backup, error) {
+	files, err := st.List("")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read backup directory: %w", err)
 	}
 
 	var backups []Backup
 	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".sav") {
-			path := filepath.Join(config.BackupDir, file.Name())
-			createdAt, err := getFileCreationTime(path)
-			if err != nil {
-				// Log error or handle it, for now, skip the file
-				continue
+		fileName, encrypted := splitEncrypted(file.Name, file.IsDir)
+		if name, format, ok := parseBackupFileName(fileName, file.IsDir); ok {
+			createdAt := file.ModTime
+			if path, ok := localPath(st, file.Name); ok {
+				var err error
+				if createdAt, err = getFileCreationTime(path); err != nil {
+					// Log error or handle it, for now, skip the file
+					continue
+				}
+			}
+			// Prefer the recorded creation time over the storage's. A
+			// manifest that exists but can't be read is kept as an error,
+			// so the backup shows up as damaged rather than as one made
+			// before manifests existed.
+			meta, err := readManifest(st, file.Name)
+			if meta != nil {
+				createdAt = meta.CreatedAt
 			}
-			name := strings.TrimSuffix(file.Name(), ".sav")
-			backups = append(backups, Backup{
-				Name:      name,
-				Path:      path,
-				CreatedAt: createdAt,
-			})
+			b := newBackup(st, file.Name, name, format, encrypted, createdAt, meta)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				b.MetaErr = err
+			}
+			backups = append(backups, b)
 		}
 	}
 
@@ -599,14 +1062,40 @@ func listBackupsInternal(config Config) ([]Backup, error) {
 	return backups, nil
 }
 
-func deleteBackups(config Config) {
+// errBackupNotFound is returned when no backup has the requested name.
+var errBackupNotFound = errors.New("backup not found")
+
+// findBackup looks up a backup by name in the backup directory.
+func findBackup(profile Profile, name string) (Backup, error) {
+	st, err := openStorage(profile)
+	if err != nil {
+		return Backup{}, err
+	}
+	return findBackupIn(st, name)
+}
+
+// findBackupIn looks up a backup by name in a storage.
+func findBackupIn(st Storage, name string) (Backup, error) {
+	backups, err := listBackupsIn(st)
+	if err != nil {
+		return Backup{}, err
+	}
+	for _, backup := range backups {
+		if backup.Name == name {
+			return backup, nil
+		}
+	}
+	return Backup{}, fmt.Errorf("%w: %s", errBackupNotFound, name)
+}
+
+func deleteBackups(profile Profile) {
 	clearScreen()
 	fmt.Println(cyan("====================================="))
 	fmt.Printf("%s %s DELETE BACKUP\n", iconDelete, cyan("DELETE BACKUP"))
 	fmt.Println(cyan("====================================="))
 	fmt.Println()
 
-	backups, err := listBackupsInternal(config)
+	backups, err := listBackupsInternal(profile)
 	if err != nil {
 		fmt.Printf("%s %s Failed to list backups: %v\n", iconError, red("ERROR:"), err)
 		waitForEnter()
@@ -619,34 +1108,26 @@ func deleteBackups(config Config) {
 		return
 	}
 
-	items := make([]string, len(backups))
-	for i, backup := range backups {
-		items[i] = fmt.Sprintf("%s (Created: %s)", backup.Name, backup.CreatedAt.Format("01/02/2006 03:04:05 PM"))
-	}
-
-	var selectedIndices []int
-	prompt := &survey.MultiSelect{
-		Message: "Select backups to delete (use space to select, enter to confirm):",
-		Options: items,
-	}
-	err = survey.AskOne(prompt, &selectedIndices)
+	selected, err := pickBackups("Select backups to delete", backups)
 	if err != nil {
-		fmt.Printf("%s %s Deletion cancelled.\n", iconError, yellow("INFO:"))
+		if errors.Is(err, errCancelled) {
+			fmt.Printf("%s %s Deletion cancelled.\n", iconError, yellow("INFO:"))
+		} else {
+			fmt.Printf("%s %s %v\n", iconError, yellow("INFO:"), err)
+		}
 		waitForEnter()
 		return
 	}
-
-	if len(selectedIndices) == 0 {
-		fmt.Printf("%s %s No backups selected.\n", iconError, yellow("INFO:"))
+	fmt.Println()
+	selected = pinnedSkipped(selected)
+	if len(selected) == 0 {
 		waitForEnter()
 		return
 	}
 
-	fmt.Println()
 	fmt.Printf("%s %s WARNING: This will permanently delete the selected backups!\n", iconError, yellow("WARNING:"))
-	for _, index := range selectedIndices {
-		backupName := strings.Split(items[index], " (Created:")[0]
-		fmt.Printf(" - %s %s\n", iconDelete, yellow(backupName))
+	for _, backup := range selected {
+		fmt.Printf(" - %s %s\n", iconDelete, yellow(backup.Name))
 	}
 	fmt.Println()
 
@@ -657,43 +1138,87 @@ func deleteBackups(config Config) {
 		return
 	}
 
-	deletedCount := 0
-	for _, index := range selectedIndices {
-		backup := backups[index]
-		err := os.Remove(backup.Path)
+	removeBackups(profile, selected)
+	waitForEnter()
+}
+
+// removeBackups deletes confirmed backups, here and on the mirrors, and
+// prints the result.
+func removeBackups(profile Profile, backups []Backup) {
+	var deleted []Backup
+	for _, backup := range backups {
+		err := removeBackup(backup)
 		if err != nil {
 			fmt.Printf("%s %s Failed to delete %s: %v\n", iconError, red("ERROR:"), backup.Name, err)
 		} else {
-			deletedCount++
+			deleted = append(deleted, backup)
 		}
 	}
 
-	if deletedCount > 0 {
-		fmt.Printf("%s %s %d backup(s) deleted successfully!\n", iconSuccess, green("SUCCESS:"), deletedCount)
+	if len(deleted) > 0 {
+		fmt.Printf("%s %s %d backup(s) deleted successfully!\n", iconSuccess, green("SUCCESS:"), len(deleted))
+		reportGarbage(profile)
+		reportMirrorRemoval(profile, deleted)
 	}
-	waitForEnter()
+}
+
+// removeBackup permanently deletes a backup and its manifest from its storage.
+func removeBackup(backup Backup) error {
+	if err := backup.storage.Delete(backup.Key); err != nil {
+		return err
+	}
+	return backup.storage.Delete(manifestPath(backup.Key))
 }
 
 func settingsMenu(config Config, currentConfigPath string) (Config, string) {
 	for {
+		profile, _ := config.profile(config.ActiveProfile)
+		if profile == nil {
+			profile = &config.Profiles[0]
+		}
+
 		clearScreen()
 		fmt.Println(cyan("====================================="))
 		fmt.Printf("%s %s SETTINGS\n", iconSettings, cyan("SETTINGS"))
 		fmt.Println(cyan("====================================="))
 		fmt.Println()
-		fmt.Printf("%s %s Current Save File Path: %s\n", iconDir, white("INFO:"), config.SavePath)
-		fmt.Printf("%s %s Current Backup Directory: %s\n", iconDir, white("INFO:"), config.BackupDir)
-		fmt.Printf("%s %s Auto-Backup on Restore: %v\n", iconSettings, white("INFO:"), config.AutoBackup)
+		fmt.Printf("%s %s Current Game: %s\n", iconInfo, white("INFO:"), profile.Name)
+		fmt.Printf("%s %s Current Save File Path: %s\n", iconDir, white("INFO:"), profile.SavePath)
+		fmt.Printf("%s %s Current Backup Directory: %s\n", iconDir, white("INFO:"), profile.BackupDir)
+		fmt.Printf("%s %s Backup Storage: %s\n", iconDir, white("INFO:"), describeStorage(*profile))
+		fmt.Printf("%s %s Mirrors: %s\n", iconDir, white("INFO:"), describeMirrors(profile.Mirrors))
+		fmt.Printf("%s %s Auto-Backup on Restore: %v\n", iconSettings, white("INFO:"), profile.AutoBackup)
+		fmt.Printf("%s %s Retention: %s\n", iconSettings, white("INFO:"), describeRetention(profile.Retention))
+		fmt.Printf("%s %s Folder Filters: %s\n", iconSettings, white("INFO:"), describeFilters(*profile))
+		fmt.Printf("%s %s Backup Format: %s\n", iconSettings, white("INFO:"), profileFormat(*profile))
+		fmt.Printf("%s %s Encryption: %s\n", iconSettings, white("INFO:"), describeEncryption(profile.Encryption))
+		fmt.Printf("%s %s Duplicate Backups: %s\n", iconSettings, white("INFO:"), duplicatePolicy(*profile))
+		fmt.Printf("%s %s Running Game Check: %s\n", iconSettings, white("INFO:"), describeGameCheck(profile.GameCheck))
+		fmt.Printf("%s %s Backup Schedule: %s\n", iconSettings, white("INFO:"), describeSchedule(profile.Schedule))
 		fmt.Println()
 		fmt.Printf("1. %s Change Save File Path\n", iconSettings)
 		fmt.Printf("2. %s Change Backup Directory\n", iconSettings)
 		fmt.Printf("3. %s Toggle Auto-Backup on Restore\n", iconSettings)
-		fmt.Printf("4. %s Test Save File Path\n", iconSettings)
-		fmt.Printf("5. %s Open Backup Directory\n", iconDir)
-		fmt.Printf("6. %s Back to Main Menu\n", iconSuccess)
+		fmt.Printf("4. %s Change Retention Settings\n", iconSettings)
+		fmt.Printf("5. %s Change Folder Filters\n", iconSettings)
+		fmt.Printf("6. %s Change Backup Format\n", iconSettings)
+		fmt.Printf("7. %s Change Encryption\n", iconSettings)
+		fmt.Printf("8. %s Change Running Game Check\n", iconSettings)
+		fmt.Printf("9. %s Change Duplicate Handling\n", iconSettings)
+		fmt.Printf("10. %s Change Backup Schedule\n", iconSettings)
+		fmt.Printf("11. %s Change Backup Storage\n", iconSettings)
+		fmt.Printf("12. %s Manage Mirrors\n", iconDir)
+		fmt.Printf("13. %s Test Save File Path\n", iconSettings)
+		fmt.Printf("14. %s Verify Backups\n", iconSettings)
+		fmt.Printf("15. %s Prune Old Backups\n", iconDelete)
+		fmt.Printf("16. %s Open Backup Directory\n", iconDir)
+		fmt.Printf("17. %s Switch Game Profile\n", iconRestore)
+		fmt.Printf("18. %s Add Game Profile\n", iconSettings)
+		fmt.Printf("19. %s Remove Game Profile\n", iconDelete)
+		fmt.Printf("20. %s Back to Main Menu\n", iconSuccess)
 		fmt.Println()
 
-		choice, err := promptForChoice("Select an option (1-6)", []string{"1", "2", "3", "4", "5", "6"})
+		choice, err := promptForChoice("Select an option (1-20)", []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19", "20"})
 		clearScreen() // Clear the promptui output
 		if err != nil {
 			if err == promptui.ErrInterrupt {
@@ -707,21 +1232,21 @@ func settingsMenu(config Config, currentConfigPath string) (Config, string) {
 		switch choice {
 		case "1": // Change Save File Path
 			fmt.Println()
-			fmt.Printf("%s %s Current path: %s\n", iconDir, white("INFO:"), config.SavePath)
+			fmt.Printf("%s %s Current path: %s\n", iconDir, white("INFO:"), profile.SavePath)
 			newPath, err := promptForInput("Enter new save file path")
 			if err == nil && newPath != "" {
-				config.SavePath = newPath
+				profile.SavePath = newPath
 				if err := saveConfig(config, currentConfigPath); err != nil {
 					fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
 				}
 			}
 		case "2": // Change Backup Directory
 			fmt.Println()
-			fmt.Printf("%s %s Current directory: %s\n", iconDir, white("INFO:"), config.BackupDir)
+			fmt.Printf("%s %s Current directory: %s\n", iconDir, white("INFO:"), profile.BackupDir)
 			newDir, err := promptForInput("Enter new backup directory")
 			if err == nil && newDir != "" {
-				config.BackupDir = newDir
-				if err := os.MkdirAll(config.BackupDir, 0755); err != nil {
+				profile.BackupDir = newDir
+				if err := os.MkdirAll(profile.BackupDir, 0755); err != nil {
 					fmt.Printf("%s %s Failed to create backup directory: %v\n", iconError, red("ERROR:"), err)
 				}
 				if err := saveConfig(config, currentConfigPath); err != nil {
@@ -730,28 +1255,207 @@ func settingsMenu(config Config, currentConfigPath string) (Config, string) {
 			}
 		case "3": // Toggle Auto-Backup on Restore
 			fmt.Println()
-			config.AutoBackup = !config.AutoBackup
+			profile.AutoBackup = !profile.AutoBackup
 			status := "DISABLED"
-			if config.AutoBackup {
+			if profile.AutoBackup {
 				status = "ENABLED"
 			}
 			fmt.Printf("%s %s Auto-backup has been %s\n", iconSuccess, green("SUCCESS:"), status)
+			if !profile.AutoBackup {
+				fmt.Printf("%s %s Restores made while it is off can't be undone.\n", iconInfo, yellow("INFO:"))
+			}
 			if err := saveConfig(config, currentConfigPath); err != nil {
 				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
 			}
 			waitForEnter()
-		case "4": // Test Save File Path
+		case "4": // Change Retention Settings
+			prompt := promptui.Select{
+				Label: white("Which backups should these rules apply to?"),
+				Items: []string{"Manual backups", "Auto-backups (before a restore, watch mode, schedules)"},
+			}
+			index, _, err := prompt.Run()
+			if err != nil {
+				continue
+			}
+			rules := &profile.Retention.RetentionRules
+			if index == 1 {
+				rules = &profile.Retention.Auto
+			}
 			fmt.Println()
-			if _, err := os.Stat(config.SavePath); os.IsNotExist(err) {
-				fmt.Printf("%s %s Save file not found at: %s\n", iconError, red("ERROR:"), config.SavePath)
+			fmt.Printf("%s %s A backup is kept if any rule keeps it. Pinned backups are never pruned.\n", iconInfo, white("INFO:"))
+			fmt.Printf("%s %s Enter 0 to turn a rule off, or press Enter to keep the current value.\n", iconInfo, white("INFO:"))
+			updated, err := promptForRules(*rules)
+			if err != nil {
+				continue
+			}
+			*rules = updated
+			if err := saveConfig(config, currentConfigPath); err != nil {
+				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
 			} else {
-				fmt.Printf("%s %s Save file found at: %s\n", iconSuccess, green("SUCCESS:"), config.SavePath)
+				fmt.Printf("%s %s Retention set to: %s\n", iconSuccess, green("SUCCESS:"), describeRetention(profile.Retention))
 			}
 			waitForEnter()
-		case "5": // Open Backup Directory
-			openExplorer(config.BackupDir)
+		case "5": // Change Folder Filters
+			fmt.Println()
+			if info, err := os.Stat(profile.SavePath); err == nil && !info.IsDir() {
+				fmt.Printf("%s %s Folder filters only apply when the save path is a folder.\n", iconInfo, yellow("INFO:"))
+				waitForEnter()
+				continue
+			}
+			include, exclude, err := promptForPatterns(profile.Include, profile.Exclude)
+			if err != nil {
+				continue
+			}
+			profile.Include = include
+			profile.Exclude = exclude
+			if err := saveConfig(config, currentConfigPath); err != nil {
+				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+			} else {
+				fmt.Printf("%s %s Folder filters set to: %s\n", iconSuccess, green("SUCCESS:"), describeFilters(*profile))
+			}
+			waitForEnter()
+		case "6": // Change Backup Format
+			items := make([]string, len(archiveFormats))
+			for i, f := range archiveFormats {
+				items[i] = string(f)
+			}
+			prompt := promptui.Select{
+				Label: white("Select the format for new backups"),
+				Items: items,
+			}
+			index, _, err := prompt.Run()
+			if err != nil {
+				continue
+			}
+			profile.Format = items[index]
+			if err := saveConfig(config, currentConfigPath); err != nil {
+				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+			} else {
+				fmt.Printf("%s %s New backups will use the %s format.\n", iconSuccess, green("SUCCESS:"), profile.Format)
+				fmt.Printf("%s %s Existing backups keep their format and can still be restored.\n", iconInfo, white("INFO:"))
+			}
+			waitForEnter()
+		case "7": // Change Encryption
+			fmt.Println()
+			if err := promptForEncryption(&profile.Encryption); err != nil {
+				if err != promptui.ErrInterrupt {
+					fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+					waitForEnter()
+				}
+				continue
+			}
+			if err := saveConfig(config, currentConfigPath); err != nil {
+				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+			} else {
+				fmt.Printf("%s %s Encryption set to: %s\n", iconSuccess, green("SUCCESS:"), describeEncryption(profile.Encryption))
+				fmt.Printf("%s %s Existing backups keep their encryption and can still be restored.\n", iconInfo, white("INFO:"))
+			}
+			waitForEnter()
+		case "8": // Change Running Game Check
+			fmt.Println()
+			if err := promptForGameCheck(&profile.GameCheck); err != nil {
+				continue
+			}
+			if err := saveConfig(config, currentConfigPath); err != nil {
+				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+			} else {
+				fmt.Printf("%s %s Running game check set to: %s\n", iconSuccess, green("SUCCESS:"), describeGameCheck(profile.GameCheck))
+			}
+			waitForEnter()
+		case "9": // Change Duplicate Handling
+			items := []string{
+				"ask - ask before creating an identical backup",
+				"skip - don't create identical backups",
+				"allow - always create the backup",
+			}
+			prompt := promptui.Select{
+				Label: white("When the save hasn't changed since the latest backup"),
+				Items: items,
+			}
+			index, _, err := prompt.Run()
+			if err != nil {
+				continue
+			}
+			profile.Duplicates = duplicatePolicies[index]
+			if err := saveConfig(config, currentConfigPath); err != nil {
+				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+			} else {
+				fmt.Printf("%s %s Duplicate handling set to: %s\n", iconSuccess, green("SUCCESS:"), profile.Duplicates)
+			}
+			waitForEnter()
+		case "10": // Change Backup Schedule
+			fmt.Println()
+			schedule, err := promptForSchedule(profile.Schedule)
+			if err != nil {
+				continue
+			}
+			profile.Schedule = schedule
+			if err := saveConfig(config, currentConfigPath); err != nil {
+				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+			} else {
+				fmt.Printf("%s %s Backup schedule set to: %s\n", iconSuccess, green("SUCCESS:"), describeSchedule(profile.Schedule))
+				if profile.Schedule != "" {
+					fmt.Printf("%s %s Scheduled backups run while \"backup_manager daemon\" is running.\n", iconInfo, white("INFO:"))
+				}
+			}
+			waitForEnter()
+		case "11": // Change Backup Storage
+			fmt.Println()
+			if err := promptForStorage(&profile.Storage); err != nil {
+				if err != promptui.ErrInterrupt {
+					fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+					waitForEnter()
+				}
+				continue
+			}
+			if err := saveConfig(config, currentConfigPath); err != nil {
+				fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+			} else {
+				fmt.Printf("%s %s Backup storage set to: %s\n", iconSuccess, green("SUCCESS:"), describeStorage(*profile))
+				if _, err := listBackupsInternal(*profile); err != nil {
+					fmt.Printf("%s %s Could not reach the storage: %v\n", iconError, yellow("WARNING:"), err)
+				}
+			}
+			waitForEnter()
+		case "12": // Manage Mirrors
+			if mirrorsMenu(profile) {
+				if err := saveConfig(config, currentConfigPath); err != nil {
+					fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+					waitForEnter()
+				}
+			}
+		case "13": // Test Save File Path
+			fmt.Println()
+			if info, err := os.Stat(profile.SavePath); os.IsNotExist(err) {
+				fmt.Printf("%s %s Save not found at: %s\n", iconError, red("ERROR:"), profile.SavePath)
+			} else if err == nil && info.IsDir() {
+				files, err := collectSaveFiles(*profile, profile.SavePath)
+				if err != nil {
+					fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+				} else {
+					fmt.Printf("%s %s Save folder found at: %s (%d file(s) match the filters)\n", iconSuccess, green("SUCCESS:"), profile.SavePath, len(files))
+				}
+			} else {
+				fmt.Printf("%s %s Save file found at: %s\n", iconSuccess, green("SUCCESS:"), profile.SavePath)
+			}
+			waitForEnter()
+		case "14": // Verify Backups
+			verifyBackups(*profile)
+		case "15": // Prune Old Backups
+			pruneMenu(*profile)
+		case "16": // Open Backup Directory
+			if profile.Storage.Type != "" && profile.Storage.Type != StorageLocal {
+				fmt.Printf("%s %s Backups are kept in %s, not on this machine.\n", iconInfo, yellow("INFO:"), describeStorage(*profile))
+			}
+			openExplorer(profile.BackupDir)
 			waitForEnter()
-		case "6": // Back to Main Menu
+		case "17": // Switch Game Profile
+			config = switchProfile(config, currentConfigPath)
+		case "18": // Add Game Profile
+			config = addProfile(config, currentConfigPath)
+		case "19": // Remove Game Profile
+			config = removeProfile(config, currentConfigPath)
+		case "20": // Back to Main Menu
 			return config, currentConfigPath
 		}
 	}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// errPinned explains why bulk deletes skip a backup.
+var errPinned = errors.New("the backup is pinned; unpin it first")
+
+// errCancelled is returned when the user backs out of a prompt.
+var errCancelled = errors.New("cancelled")
+
+// validateBackupName rejects names that can't be used as a backup name.
+func validateBackupName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("the backup name can't be empty")
+	case strings.ContainsAny(name, `/\`):
+		return errors.New("the backup name can't contain slashes")
+	case strings.HasPrefix(name, "."):
+		return errors.New("the backup name can't start with a dot")
+	}
+	return nil
+}
+
+// backupManifest returns a copy of a backup's manifest to edit. Backups made
+// before manifests existed get one built from what is known about them, so
+// their hash is recorded from then on.
+func backupManifest(profile Profile, b Backup) (*Manifest, error) {
+	if b.MetaErr != nil {
+		// Writing a fresh manifest would hide the damage.
+		return nil, fmt.Errorf("%w: %v", errBackupDamaged, b.MetaErr)
+	}
+	if b.Meta != nil {
+		meta := *b.Meta
+		meta.Tags = append([]string(nil), b.Meta.Tags...)
+		return &meta, nil
+	}
+	sum, size, err := hashStored(b.storage, b.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", b.Name, err)
+	}
+	return &Manifest{
+		Name:        b.Name,
+		Profile:     profile.Name,
+		SourcePath:  profile.SavePath,
+		Format:      b.Format,
+		Encrypted:   b.Encrypted,
+		SHA256:      sum,
+		Size:        size,
+		CreatedAt:   b.CreatedAt,
+		ToolVersion: version,
+	}, nil
+}
+
+// editBackup applies edit to a backup's manifest and saves it. The stored
+// backup itself is not touched, so its hashes stay valid.
+func editBackup(profile Profile, b Backup, edit func(*Manifest)) (Backup, error) {
+	meta, err := backupManifest(profile, b)
+	if err != nil {
+		return b, err
+	}
+	edit(meta)
+	if err := writeManifest(b.storage, b.Key, meta); err != nil {
+		return b, fmt.Errorf("failed to save metadata: %w", err)
+	}
+	b.Meta = meta
+	return b, nil
+}
+
+// renameBackup gives a backup a new name, adding a numeric suffix if the name
+// is taken. The manifest moves with it and keeps its hashes, and restore
+// history entries that refer to the old name are updated.
+func renameBackup(profile Profile, b Backup, newName string) (Backup, error) {
+	newName = strings.TrimSpace(newName)
+	if err := validateBackupName(newName); err != nil {
+		return b, err
+	}
+	if newName == b.Name {
+		return b, fmt.Errorf("the backup is already called %s", newName)
+	}
+	meta, err := backupManifest(profile, b)
+	if err != nil {
+		return b, err
+	}
+
+	if newName, err = uniqueBackupName(profile, newName); err != nil {
+		return b, err
+	}
+	newKey := newName + strings.TrimPrefix(b.Key, b.Name)
+	meta.Name = newName
+	if err := renameStored(b.storage, b.Key, newKey); err != nil {
+		return b, fmt.Errorf("failed to rename %s: %w", b.Name, err)
+	}
+	renamed := newBackup(b.storage, newKey, newName, b.Format, b.Encrypted, b.CreatedAt, meta)
+	if err := writeManifest(b.storage, newKey, meta); err != nil {
+		return renamed, fmt.Errorf("failed to save metadata: %w", err)
+	}
+	if err := b.storage.Delete(manifestPath(b.Key)); err != nil {
+		return renamed, fmt.Errorf("failed to remove the old metadata: %w", err)
+	}
+	if err := renameInHistory(profile, b.Name, newName); err != nil {
+		return renamed, fmt.Errorf("failed to update the restore history: %w", err)
+	}
+	return renamed, nil
+}
+
+// renameInHistory points restore history entries at a renamed backup.
+func renameInHistory(profile Profile, from, to string) error {
+	history, err := readHistory(profile)
+	if err != nil || len(history) == 0 {
+		return err
+	}
+	changed := false
+	for i := range history {
+		if history[i].Backup == from {
+			history[i].Backup = to
+			changed = true
+		}
+		if history[i].SavedAs == from {
+			history[i].SavedAs = to
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeHistory(profile, history)
+}
+
+// manageBackupMenu offers the edits that can be made to a backup after it
+// was created.
+func manageBackupMenu(profile Profile, b Backup) {
+	for {
+		clearScreen()
+		fmt.Println(cyan("====================================="))
+		fmt.Printf("%s %s MANAGE BACKUP\n", iconSettings, cyan("MANAGE BACKUP"))
+		fmt.Println(cyan("====================================="))
+		fmt.Println()
+		printBackupDetails(b)
+		fmt.Println()
+
+		pin := "Pin"
+		if isPinned(b) {
+			pin = "Unpin"
+		}
+		prompt := promptui.Select{
+			Label: white("Select an action"),
+			Items: []string{"Rename", "Edit Note", "Edit Tags", pin, "Back"},
+		}
+		index, _, err := prompt.Run()
+		if err != nil || index == 4 {
+			return
+		}
+
+		oldKey := b.Key
+		updated, err := applyBackupAction(profile, b, index)
+		if errors.Is(err, errCancelled) {
+			continue
+		}
+		if err != nil {
+			fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+		} else {
+			reportMirrorUpdate(profile, oldKey, updated)
+		}
+		b = updated
+		waitForEnter()
+	}
+}
+
+// applyBackupAction asks for the details of one manageBackupMenu action and
+// carries it out.
+func applyBackupAction(profile Profile, b Backup, action int) (Backup, error) {
+	switch action {
+	case 0:
+		name, err := promptForInput("Enter the new name")
+		if err != nil || strings.TrimSpace(name) == "" {
+			return b, errCancelled
+		}
+		updated, err := renameBackup(profile, b, name)
+		if err == nil {
+			fmt.Printf("%s %s Backup renamed to: %s\n", iconSuccess, green("SUCCESS:"), updated.Name)
+		}
+		return updated, err
+	case 1:
+		note, err := promptForInput("Enter the note (empty to clear)")
+		if err != nil {
+			return b, errCancelled
+		}
+		updated, err := editBackup(profile, b, func(m *Manifest) { m.Note = strings.TrimSpace(note) })
+		if err == nil {
+			fmt.Printf("%s %s Note updated.\n", iconSuccess, green("SUCCESS:"))
+		}
+		return updated, err
+	case 2:
+		tags, err := promptForInput("Enter comma-separated tags (empty to clear)")
+		if err != nil {
+			return b, errCancelled
+		}
+		updated, err := editBackup(profile, b, func(m *Manifest) { m.Tags = parseTags(tags) })
+		if err == nil {
+			fmt.Printf("%s %s Tags updated.\n", iconSuccess, green("SUCCESS:"))
+		}
+		return updated, err
+	}
+	pinned := !isPinned(b)
+	updated, err := editBackup(profile, b, func(m *Manifest) { m.Pinned = pinned })
+	if err == nil && pinned {
+		fmt.Printf("%s %s Backup pinned; pruning and bulk delete will skip it.\n", iconSuccess, green("SUCCESS:"))
+	} else if err == nil {
+		fmt.Printf("%s %s Backup unpinned.\n", iconSuccess, green("SUCCESS:"))
+	}
+	return updated, err
+}
+
+// pinnedSkipped prints a notice for each pinned backup and returns the rest.
+func pinnedSkipped(backups []Backup) []Backup {
+	var rest []Backup
+	for _, b := range backups {
+		if isPinned(b) {
+			fmt.Printf("%s %s Skipped %s: %v\n", iconError, yellow("INFO:"), b.Name, errPinned)
+			continue
+		}
+		rest = append(rest, b)
+	}
+	return rest
+}
+
+// formatPinned renders the pinned flag for tables.
+func formatPinned(b Backup) string {
+	if isPinned(b) {
+		return "yes"
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRenameBackup(t *testing.T) {
+	for _, format := range []ArchiveFormat{FormatPlain, FormatZip} {
+		t.Run(string(format), func(t *testing.T) {
+			profile := newTestProfile(t)
+			profile.Format = string(format)
+			for _, name := range []string{"before boss", "taken"} {
+				if _, err := writeBackup(profile, name, "note for "+name, []string{"boss"}); err != nil {
+					t.Fatal(err)
+				}
+				writeTestFile(t, profile.SavePath, "after "+name)
+			}
+			record := RestoreRecord{Time: time.Now(), Backup: "before boss", SavedAs: "before boss"}
+			if err := recordRestore(profile, record); err != nil {
+				t.Fatal(err)
+			}
+
+			original, err := findBackup(profile, "before boss")
+			if err != nil {
+				t.Fatal(err)
+			}
+			renamed, err := renameBackup(profile, original, " taken ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if renamed.Name != "taken_1" {
+				t.Errorf("renamed to %q, want taken_1 since taken exists", renamed.Name)
+			}
+			got := backupNames(t, profile)
+			slices.Sort(got)
+			if want := []string{"taken", "taken_1"}; !slices.Equal(got, want) {
+				t.Errorf("backups after rename: got %v, want %v", got, want)
+			}
+
+			found, err := findBackup(profile, "taken_1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found.Meta == nil || found.Meta.Name != "taken_1" || found.Meta.Note != "note for before boss" || found.Meta.SHA256 != original.Meta.SHA256 {
+				t.Errorf("manifest after rename: got %+v, want the old one under the new name", found.Meta)
+			}
+			if result := verifyBackup(found); result.Status != VerifyOK {
+				t.Errorf("verify after rename: got %s (%s)", result.Status, result.Detail)
+			}
+
+			history, err := readHistory(profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 1 || history[0].Backup != "taken_1" || history[0].SavedAs != "taken_1" {
+				t.Errorf("history after rename: got %+v, want both names updated", history)
+			}
+
+			if _, err := renameBackup(profile, found, "taken_1"); err == nil {
+				t.Error("renaming a backup to its own name succeeded")
+			}
+			if _, err := renameBackup(profile, found, "bad/name"); err == nil {
+				t.Error("renaming a backup to a path succeeded")
+			}
+		})
+	}
+}
+
+func TestEditDamagedBackupIsRefused(t *testing.T) {
+	profile := newTestProfile(t)
+	backup, err := writeBackup(profile, "boss", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backup.Meta, backup.MetaErr = nil, errors.New("unexpected end of JSON input")
+	if _, err := editBackup(profile, backup, func(m *Manifest) { m.Pinned = true }); !errors.Is(err, errBackupDamaged) {
+		t.Errorf("edit: got %v, want errBackupDamaged", err)
+	}
+	if _, err := renameBackup(profile, backup, "renamed"); !errors.Is(err, errBackupDamaged) {
+		t.Errorf("rename: got %v, want errBackupDamaged", err)
+	}
+}
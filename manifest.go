@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// version is the tool version recorded in backup manifests. Release builds
+// set it with -ldflags "-X main.version=...".
+var version = "dev"
+
+// manifestSuffix is appended to a backup's path to name its sidecar manifest.
+const manifestSuffix = ".meta.json"
+
+// Manifest is the metadata stored next to each backup.
+type Manifest struct {
+	Name          string        `json:"name"`
+	Profile       string        `json:"profile"`
+	SourcePath    string        `json:"source_path"`
+	Format        ArchiveFormat `json:"format"`
+	Encrypted     bool          `json:"encrypted,omitempty"`
+	SHA256        string        `json:"sha256"`                   // of the stored backup
+	ContentSHA256 string        `json:"content_sha256,omitempty"` // of the save, whatever the format
+	Size          int64         `json:"size"`
+	CreatedAt     time.Time     `json:"created_at"`
+	ToolVersion   string        `json:"tool_version"`
+	Note          string        `json:"note,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
+	Pinned        bool          `json:"pinned,omitempty"` // never removed by pruning
+}
+
+// manifestPath returns the sidecar manifest location for a backup.
+func manifestPath(backupPath string) string {
+	return backupPath + manifestSuffix
+}
+
+// readManifest loads a backup's sidecar manifest from its storage. Backups made
+// before manifests existed have none, which is reported as os.ErrNotExist.
+func readManifest(st Storage, key string) (*Manifest, error) {
+	r, err := st.Get(manifestPath(key))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("manifest for %s is corrupted: %w", path.Base(key), err)
+	}
+	return &m, nil
+}
+
+// writeManifest saves a backup's sidecar manifest to its storage.
+func writeManifest(st Storage, key string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	return st.Put(manifestPath(key), bytes.NewReader(data))
+}
+
+// hashBackup returns the SHA-256 and total size of a stored backup. Files are
+// hashed directly; folders hash every file's relative path and content hash in
+// sorted order, so the result doesn't depend on directory iteration order.
+func hashBackup(path string) (string, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, err
+	}
+	if !info.IsDir() {
+		return hashFile(path)
+	}
+	return hashTree(Profile{}, path)
+}
+
+// hashStored hashes a backup the way hashBackup does, reading it through its
+// storage so remote backups are checked without downloading them to disk.
+func hashStored(st Storage, key string) (string, int64, error) {
+	if p, ok := localPath(st, key); ok {
+		return hashBackup(p)
+	}
+	entry, err := st.Stat(key)
+	if err != nil {
+		return "", 0, err
+	}
+	if !entry.IsDir {
+		return hashStoredFile(st, key)
+	}
+
+	// Visit files in the same order filepath.WalkDir does, so the result
+	// matches hashTree.
+	h := sha256.New()
+	var total int64
+	var walk func(dir, rel string) error
+	walk = func(dir, rel string) error {
+		entries, err := st.List(dir)
+		if err != nil {
+			return err
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+		for _, e := range entries {
+			childRel := path.Join(rel, e.Name)
+			if e.IsDir {
+				if err := walk(path.Join(dir, e.Name), childRel); err != nil {
+					return err
+				}
+				continue
+			}
+			sum, size, err := hashStoredFile(st, path.Join(dir, e.Name))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%s\n", childRel, sum)
+			total += size
+		}
+		return nil
+	}
+	if err := walk(key, ""); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), total, nil
+}
+
+func hashStoredFile(st Storage, name string) (string, int64, error) {
+	r, err := st.Get(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// hashSave hashes the live save the same way hashBackup hashes a plain backup
+// of it, applying the profile's folder filters.
+func hashSave(profile Profile) (string, int64, error) {
+	info, err := os.Stat(profile.SavePath)
+	if err != nil {
+		return "", 0, err
+	}
+	if !info.IsDir() {
+		return hashFile(profile.SavePath)
+	}
+	return hashTree(profile, profile.SavePath)
+}
+
+// hashTree hashes every file's relative path and content hash in sorted order.
+func hashTree(profile Profile, root string) (string, int64, error) {
+	files, err := collectSaveFiles(profile, root)
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	var total int64
+	for _, rel := range files {
+		sum, size, err := hashFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", 0, err
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", rel, sum)
+		total += size
+	}
+	return hex.EncodeToString(h.Sum(nil)), total, nil
+}
+
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// formatSize renders a byte count for display.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// parseTags splits a comma-separated tag list, dropping blanks and duplicates.
+func parseTags(value string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(value, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// printBackupDetails prints the metadata of a backup, as shown before a restore
+// and by the show command.
+func printBackupDetails(b Backup) {
+	fmt.Printf("%s %s Name: %s\n", iconInfo, white("INFO:"), b.Name)
+	fmt.Printf("%s %s Created at: %s\n", iconInfo, white("INFO:"), b.CreatedAt.Format("01/02/2006 03:04:05 PM"))
+	fmt.Printf("%s %s Format: %s\n", iconInfo, white("INFO:"), b.formatLabel())
+	if b.Meta == nil {
+		fmt.Printf("%s %s No metadata recorded for this backup.\n", iconInfo, yellow("INFO:"))
+		return
+	}
+	if b.Meta.Note != "" {
+		fmt.Printf("%s %s Note: %s\n", iconInfo, white("INFO:"), b.Meta.Note)
+	}
+	if len(b.Meta.Tags) > 0 {
+		fmt.Printf("%s %s Tags: %s\n", iconInfo, white("INFO:"), strings.Join(b.Meta.Tags, ", "))
+	}
+	if b.Meta.Pinned {
+		fmt.Printf("%s %s Pinned: yes (never pruned or bulk deleted)\n", iconInfo, white("INFO:"))
+	}
+	fmt.Printf("%s %s Profile: %s\n", iconInfo, white("INFO:"), b.Meta.Profile)
+	fmt.Printf("%s %s Source: %s\n", iconInfo, white("INFO:"), b.Meta.SourcePath)
+	fmt.Printf("%s %s Size: %s\n", iconInfo, white("INFO:"), formatSize(b.Meta.Size))
+	fmt.Printf("%s %s SHA-256: %s\n", iconInfo, white("INFO:"), b.Meta.SHA256)
+	fmt.Printf("%s %s Tool version: %s\n", iconInfo, white("INFO:"), b.Meta.ToolVersion)
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Mirror is a secondary location that holds a copy of every backup of a
+// profile, such as an external drive or a remote backend. For local mirrors
+// Path is the directory.
+type Mirror struct {
+	Name string `json:"name"`
+	StorageSettings
+}
+
+// describeMirror renders a mirror's location for display.
+func describeMirror(m Mirror) string {
+	return describeStorageAt(m.StorageSettings, m.Path)
+}
+
+// describeMirrors renders a profile's mirrors for display.
+func describeMirrors(mirrors []Mirror) string {
+	if len(mirrors) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(mirrors))
+	for i, m := range mirrors {
+		parts[i] = m.Name + " (" + describeMirror(m) + ")"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// openMirror connects to a mirror, creating a local mirror's directory.
+func openMirror(m Mirror) (Storage, error) {
+	if m.Type != "" && m.Type != StorageLocal {
+		return openRemoteStorage(m.StorageSettings)
+	}
+	if m.Path == "" {
+		return nil, fmt.Errorf("mirror %s has no directory", m.Name)
+	}
+	if err := os.MkdirAll(m.Path, 0755); err != nil {
+		return nil, fmt.Errorf("cannot access mirror %s: %w", m.Name, err)
+	}
+	return localStorage{root: m.Path}, nil
+}
+
+// findMirror looks up a mirror of the profile by name.
+func findMirror(profile Profile, name string) (*Mirror, error) {
+	for i := range profile.Mirrors {
+		if profile.Mirrors[i].Name == name {
+			return &profile.Mirrors[i], nil
+		}
+	}
+	return nil, fmt.Errorf("mirror not found: %s", name)
+}
+
+// backupSource opens the storage backups are listed and restored from: the
+// profile's own storage, or the named mirror.
+func backupSource(profile Profile, mirror string) (Storage, error) {
+	if mirror == "" {
+		return openStorage(profile)
+	}
+	m, err := findMirror(profile, mirror)
+	if err != nil {
+		return nil, err
+	}
+	return openMirror(*m)
+}
+
+// parseMirrorTarget turns a directory, s3://bucket/prefix or
+// sftp://user@host[:port]/path into mirror settings. S3 mirrors default to the
+// AWS endpoint; other settings are changed with mirror set.
+func parseMirrorTarget(target string) (StorageSettings, error) {
+	switch {
+	case strings.HasPrefix(target, "s3://"), strings.HasPrefix(target, "sftp://"):
+		u, err := url.Parse(target)
+		if err != nil {
+			return StorageSettings{}, err
+		}
+		if u.Scheme == StorageS3 {
+			if u.Host == "" {
+				return StorageSettings{}, errors.New("s3 mirrors need a bucket, e.g. s3://my-bucket/saves")
+			}
+			return StorageSettings{Type: StorageS3, Endpoint: "s3.amazonaws.com", Bucket: u.Host, Path: strings.Trim(u.Path, "/")}, nil
+		}
+		if u.Host == "" || u.User.Username() == "" {
+			return StorageSettings{}, errors.New("sftp mirrors need a user and a host, e.g. sftp://me@nas/backups")
+		}
+		return StorageSettings{Type: StorageSFTP, User: u.User.Username(), Host: u.Host, Path: u.Path}, nil
+	case filepath.IsAbs(target):
+		return StorageSettings{Path: target}, nil
+	}
+	return StorageSettings{}, fmt.Errorf("mirror target must be an absolute directory, s3://bucket/prefix or sftp://user@host/path")
+}
+
+// copyBackup copies a backup and its manifest into another storage. The
+// content of store-format backups is copied first, skipping blobs the other
+// side already has, and the manifest last. A failed copy is removed again.
+func copyBackup(b Backup, dst Storage) error {
+	err := func() error {
+		if b.Format == FormatStore {
+			snapshot, err := readSnapshot(b.storage, b.Key)
+			if err != nil {
+				return err
+			}
+			for _, entry := range snapshot.Entries {
+				blob := blobPath(entry.SHA256)
+				exists, err := storageExists(dst, blob)
+				if err != nil {
+					return err
+				}
+				if exists {
+					continue
+				}
+				if err := copyStored(b.storage, dst, blob); err != nil {
+					return err
+				}
+			}
+		}
+		if err := copyStored(b.storage, dst, b.Key); err != nil {
+			return err
+		}
+		hasManifest, err := storageExists(b.storage, manifestPath(b.Key))
+		if err != nil || !hasManifest {
+			return err
+		}
+		return copyStored(b.storage, dst, manifestPath(b.Key))
+	}()
+	if err != nil {
+		dst.Delete(b.Key)
+		return fmt.Errorf("failed to copy %s: %w", b.Name, err)
+	}
+	return nil
+}
+
+// missingFrom returns the backups in from whose storage name isn't in to.
+func missingFrom(from, to []Backup) []Backup {
+	have := make(map[string]bool, len(to))
+	for _, b := range to {
+		have[b.Key] = true
+	}
+	var missing []Backup
+	for _, b := range from {
+		if !have[b.Key] {
+			missing = append(missing, b)
+		}
+	}
+	return missing
+}
+
+// MirrorStatus is the outcome of bringing one mirror up to date.
+type MirrorStatus struct {
+	Mirror  Mirror
+	Pushed  []Backup // copied to the mirror
+	Pulled  []Backup // copied from the mirror
+	Removed []Backup // deleted here earlier, removed from the mirror
+	Err     error
+}
+
+// syncMirror copies backups the mirror is missing to it and, when pull is
+// set, backups only the mirror has back to the profile's storage. Backups
+// deleted here that are still on the mirror are removed from it rather than
+// pulled back. With dryRun set nothing is copied or removed and the status
+// lists what would be.
+func syncMirror(profile Profile, m Mirror, pull, dryRun bool) MirrorStatus {
+	status := MirrorStatus{Mirror: m}
+	primary, err := openStorage(profile)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	mirror, err := openMirror(m)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	local, err := listBackupsIn(primary)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	remote, err := listBackupsIn(mirror)
+	if err != nil {
+		status.Err = fmt.Errorf("failed to list mirror: %w", err)
+		return status
+	}
+	tombstones, err := readTombstones(profile)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	if deleted := deletedOn(tombstones, m.Name); len(deleted) > 0 {
+		remote = slices.DeleteFunc(remote, func(b Backup) bool {
+			if deleted[b.Key] {
+				status.Removed = append(status.Removed, b)
+			}
+			return deleted[b.Key]
+		})
+		if !dryRun {
+			if err := removeFromStorage(mirror, status.Removed); err != nil {
+				status.Err = err
+				return status
+			}
+			if err := clearTombstones(profile, m.Name); err != nil {
+				status.Err = err
+				return status
+			}
+		}
+	}
+
+	for _, b := range missingFrom(local, remote) {
+		if !dryRun {
+			if err := copyBackup(b, mirror); err != nil {
+				status.Err = err
+				return status
+			}
+		}
+		status.Pushed = append(status.Pushed, b)
+	}
+	if !pull {
+		return status
+	}
+	for _, b := range missingFrom(remote, local) {
+		if !dryRun {
+			if err := copyBackup(b, primary); err != nil {
+				status.Err = err
+				return status
+			}
+		}
+		status.Pulled = append(status.Pulled, b)
+	}
+	return status
+}
+
+// reportMirrors copies new backups to every mirror after a backup and prints
+// one status line per mirror.
+func reportMirrors(profile Profile) {
+	for _, m := range profile.Mirrors {
+		status := syncMirror(profile, m, false, false)
+		switch {
+		case status.Err != nil:
+			fmt.Printf("%s %s Mirror %s failed: %v\n", iconError, red("ERROR:"), m.Name, status.Err)
+		case len(status.Pushed) > 0:
+			fmt.Printf("%s %s Mirrored %d backup(s) to %s.\n", iconSuccess, green("INFO:"), len(status.Pushed), m.Name)
+		}
+	}
+}
+
+// removeFromMirrors deletes backups from every mirror, so pruned or deleted
+// backups aren't copied back by the next sync. Mirrors that can't be reached
+// get tombstones instead, and the next sync removes the backups there. It
+// returns the first failure.
+func removeFromMirrors(profile Profile, backups []Backup) error {
+	if len(backups) == 0 {
+		return nil
+	}
+	var firstErr error
+	for _, m := range profile.Mirrors {
+		st, err := openMirror(m)
+		if err == nil {
+			err = removeFromStorage(st, backups)
+		}
+		if err != nil {
+			if terr := addTombstones(profile, m.Name, backups); terr != nil {
+				err = fmt.Errorf("%w (and failed to record the deletion: %v)", err, terr)
+			}
+			if firstErr == nil {
+				firstErr = fmt.Errorf("mirror %s: %w", m.Name, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// removeFromStorage deletes backups and their manifests from a storage, then
+// the blobs only they used.
+func removeFromStorage(st Storage, backups []Backup) error {
+	if len(backups) == 0 {
+		return nil
+	}
+	for _, b := range backups {
+		if err := st.Delete(b.Key); err != nil {
+			return err
+		}
+		if err := st.Delete(manifestPath(b.Key)); err != nil {
+			return err
+		}
+	}
+	_, _, err := collectGarbage(st)
+	return err
+}
+
+// reportMirrorRemoval deletes backups from the mirrors and prints any failure.
+func reportMirrorRemoval(profile Profile, backups []Backup) {
+	if err := removeFromMirrors(profile, backups); err != nil {
+		fmt.Printf("%s %s Failed to delete from %v\n", iconError, red("ERROR:"), err)
+	}
+}
+
+// updateMirrors carries a rename or metadata edit over to the mirrors that
+// hold the backup, so the next sync doesn't bring back the old version.
+// oldKey is the backup's key before the change. Mirrors without the backup
+// are left for sync to fill in.
+func updateMirrors(profile Profile, oldKey string, b Backup) error {
+	var firstErr error
+	for _, m := range profile.Mirrors {
+		st, err := openMirror(m)
+		held := false
+		if err == nil {
+			held, err = storageExists(st, oldKey)
+		}
+		if held {
+			if oldKey != b.Key {
+				if err = renameStored(st, oldKey, b.Key); err == nil {
+					err = st.Delete(manifestPath(oldKey))
+				}
+			}
+			if err == nil {
+				err = copyStored(b.storage, st, manifestPath(b.Key))
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("mirror %s: %w", m.Name, err)
+		}
+	}
+	return firstErr
+}
+
+// reportMirrorUpdate updates the mirrors after a backup changed and prints
+// any failure.
+func reportMirrorUpdate(profile Profile, oldKey string, b Backup) {
+	if err := updateMirrors(profile, oldKey, b); err != nil {
+		fmt.Printf("%s %s Failed to update %v\n", iconError, red("ERROR:"), err)
+	}
+}
+
+// printSyncStatus prints what a sync did, or would do, for one mirror.
+func printSyncStatus(status MirrorStatus, dryRun bool) {
+	verb := "Copied"
+	if dryRun {
+		verb = "Would copy"
+	}
+	name := status.Mirror.Name
+	for _, b := range status.Pushed {
+		fmt.Printf("%s %s %s %s to %s\n", iconSuccess, green("INFO:"), verb, b.Name, name)
+	}
+	for _, b := range status.Pulled {
+		fmt.Printf("%s %s %s %s from %s\n", iconRestore, green("INFO:"), verb, b.Name, name)
+	}
+	removed := "Removed"
+	if dryRun {
+		removed = "Would remove"
+	}
+	for _, b := range status.Removed {
+		fmt.Printf("%s %s %s deleted backup %s from %s\n", iconDelete, green("INFO:"), removed, b.Name, name)
+	}
+	switch {
+	case status.Err != nil:
+		fmt.Printf("%s %s Mirror %s: %v\n", iconError, red("ERROR:"), name, status.Err)
+	case len(status.Pushed) == 0 && len(status.Pulled) == 0 && len(status.Removed) == 0:
+		fmt.Printf("%s %s Mirror %s is in sync.\n", iconSuccess, green("INFO:"), name)
+	}
+}
+
+// mirrorsMenu shows every mirror's status and lets the user add, remove and
+// sync mirrors. It returns whether the profile changed.
+func mirrorsMenu(profile *Profile) bool {
+	changed := false
+	for {
+		clearScreen()
+		fmt.Println(cyan("====================================="))
+		fmt.Printf("%s %s MIRRORS\n", iconDir, cyan("MIRRORS"))
+		fmt.Println(cyan("====================================="))
+		fmt.Println()
+		if len(profile.Mirrors) == 0 {
+			fmt.Printf("%s %s No mirrors set up. Backups are only kept in %s.\n", iconInfo, yellow("INFO:"), describeStorage(*profile))
+		}
+		for _, m := range profile.Mirrors {
+			status := syncMirror(*profile, m, true, true)
+			line := fmt.Sprintf(" - %s: %s", m.Name, describeMirror(m))
+			switch {
+			case status.Err != nil:
+				line += " " + red("("+status.Err.Error()+")")
+			case len(status.Pushed) == 0 && len(status.Pulled) == 0 && len(status.Removed) == 0:
+				line += " " + green("(in sync)")
+			default:
+				line += " " + yellow(fmt.Sprintf("(%d missing on the mirror, %d only on the mirror)", len(status.Pushed), len(status.Pulled)+len(status.Removed)))
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+
+		prompt := promptui.Select{
+			Label: white("Select an action"),
+			Items: []string{"Add Mirror", "Remove Mirror", "Sync Now", "Back"},
+		}
+		index, _, err := prompt.Run()
+		if err != nil || index == 3 {
+			return changed
+		}
+		switch index {
+		case 0:
+			m, err := promptForMirror(*profile)
+			if err != nil {
+				if err != promptui.ErrInterrupt {
+					fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+					waitForEnter()
+				}
+				continue
+			}
+			profile.Mirrors = append(profile.Mirrors, m)
+			changed = true
+			fmt.Printf("%s %s Mirror %s added. Run Sync Now to copy the existing backups.\n", iconSuccess, green("SUCCESS:"), m.Name)
+			waitForEnter()
+		case 1:
+			if len(profile.Mirrors) == 0 {
+				continue
+			}
+			items := make([]string, len(profile.Mirrors))
+			for i, m := range profile.Mirrors {
+				items[i] = m.Name + " - " + describeMirror(m)
+			}
+			sel := promptui.Select{Label: white("Select a mirror to remove"), Items: items}
+			i, _, err := sel.Run()
+			if err != nil {
+				continue
+			}
+			name := profile.Mirrors[i].Name
+			profile.Mirrors = append(profile.Mirrors[:i], profile.Mirrors[i+1:]...)
+			changed = true
+			fmt.Printf("%s %s Mirror %s removed. The backups on it are kept.\n", iconSuccess, green("SUCCESS:"), name)
+			waitForEnter()
+		case 2:
+			fmt.Println()
+			for _, m := range profile.Mirrors {
+				printSyncStatus(syncMirror(*profile, m, true, false), false)
+			}
+			waitForEnter()
+		}
+	}
+}
+
+// promptForMirror asks for a new mirror's name and location.
+func promptForMirror(profile Profile) (Mirror, error) {
+	name, err := promptForInput("Enter a name for the mirror (e.g. usb or nas)")
+	if err != nil {
+		return Mirror{}, err
+	}
+	if name == "" {
+		return Mirror{}, errors.New("the mirror needs a name")
+	}
+	if _, err := findMirror(profile, name); err == nil {
+		return Mirror{}, fmt.Errorf("a mirror named %s already exists", name)
+	}
+	m := Mirror{Name: name}
+	if err := promptForStorage(&m.StorageSettings); err != nil {
+		return Mirror{}, err
+	}
+	if m.Type == "" {
+		dir, err := promptForInput("Enter the full path of the mirror directory")
+		if err != nil {
+			return Mirror{}, err
+		}
+		if !filepath.IsAbs(dir) {
+			return Mirror{}, errors.New("the mirror directory must be an absolute path")
+		}
+		if filepath.Clean(dir) == filepath.Clean(profile.BackupDir) {
+			return Mirror{}, errors.New("a mirror can't be the backup directory itself")
+		}
+		m.Path = dir
+	}
+	if _, err := openMirror(m); err != nil {
+		return Mirror{}, err
+	}
+	return m, nil
+}
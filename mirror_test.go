@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncDoesNotPullBackDeletedBackups(t *testing.T) {
+	profile := newTestProfile(t)
+	mirrorDir := filepath.Join(t.TempDir(), "usb")
+	profile.Mirrors = []Mirror{{Name: "usb", StorageSettings: StorageSettings{Path: mirrorDir}}}
+
+	doomed, err := writeBackup(profile, "doomed", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, profile.SavePath, "slot 2")
+	if _, err := writeBackup(profile, "kept", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if status := syncMirror(profile, profile.Mirrors[0], false, false); status.Err != nil || len(status.Pushed) != 2 {
+		t.Fatalf("first sync: got %+v, want both backups pushed", status)
+	}
+
+	// The drive is unplugged while the backup is deleted.
+	unplugged := profile
+	blocker := filepath.Join(t.TempDir(), "not-a-folder")
+	writeTestFile(t, blocker, "")
+	unplugged.Mirrors = []Mirror{{Name: "usb", StorageSettings: StorageSettings{Path: filepath.Join(blocker, "usb")}}}
+	if err := removeBackup(doomed); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeFromMirrors(unplugged, []Backup{doomed}); err == nil {
+		t.Fatal("removeFromMirrors reported success for an unreachable mirror")
+	}
+
+	preview := syncMirror(profile, profile.Mirrors[0], true, true)
+	if preview.Err != nil || len(preview.Pulled) != 0 || len(preview.Removed) != 1 {
+		t.Errorf("dry run: got %+v, want doomed listed for removal and nothing pulled", preview)
+	}
+	status := syncMirror(profile, profile.Mirrors[0], true, false)
+	if status.Err != nil || len(status.Pulled) != 0 || len(status.Removed) != 1 || status.Removed[0].Name != "doomed" {
+		t.Fatalf("sync: got %+v, want doomed removed from the mirror and nothing pulled", status)
+	}
+	if got := backupNames(t, profile); len(got) != 1 || got[0] != "kept" {
+		t.Errorf("backups after sync: got %v, want only kept", got)
+	}
+	mirrored, err := listBackupsIn(localStorage{root: mirrorDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrored) != 1 || mirrored[0].Name != "kept" {
+		t.Errorf("mirror after sync: got %d backups, want only kept", len(mirrored))
+	}
+	if _, err := os.Stat(tombstonesPath(profile)); !os.IsNotExist(err) {
+		t.Errorf("tombstones were kept after the mirror caught up: %v", err)
+	}
+
+	// A new backup under the deleted name is mirrored as usual.
+	writeTestFile(t, profile.SavePath, "slot 3")
+	if _, err := writeBackup(profile, "doomed", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if status := syncMirror(profile, profile.Mirrors[0], true, false); status.Err != nil || len(status.Pushed) != 1 {
+		t.Errorf("sync of a new backup reusing the name: got %+v, want it pushed", status)
+	}
+}
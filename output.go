@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// OutputFormat is how the list, show, verify and status commands print
+// their results.
+type OutputFormat string
+
+const (
+	OutputTable OutputFormat = "table" // aligned columns for people
+	OutputJSON  OutputFormat = "json"  // full records for scripts and dashboards
+	OutputCSV   OutputFormat = "csv"   // one row per record, raw values
+)
+
+var outputFormats = []OutputFormat{OutputTable, OutputJSON, OutputCSV}
+
+// parseOutputFormat validates an output format name. An empty string means
+// table.
+func parseOutputFormat(value string) (OutputFormat, error) {
+	if value == "" {
+		return OutputTable, nil
+	}
+	for _, f := range outputFormats {
+		if strings.EqualFold(value, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown output format %q (use table, json or csv)", errUsage, value)
+}
+
+// outputFlag adds the --output flag shared by the commands that print records.
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", "table", "output format: table, json or csv")
+}
+
+// BackupRecord is the machine-readable form of a backup, as printed by
+// list, show and status with --output json or csv.
+type BackupRecord struct {
+	Name          string        `json:"name"`
+	Profile       string        `json:"profile"`
+	Path          string        `json:"path"`
+	Format        ArchiveFormat `json:"format"`
+	Encrypted     bool          `json:"encrypted"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Size          int64         `json:"size"`
+	SHA256        string        `json:"sha256"`
+	ContentSHA256 string        `json:"content_sha256"`
+	SourcePath    string        `json:"source_path"`
+	ToolVersion   string        `json:"tool_version"`
+	Note          string        `json:"note"`
+	Tags          []string      `json:"tags"`
+	Pinned        bool          `json:"pinned"`
+	Auto          bool          `json:"auto"`
+	HasManifest   bool          `json:"has_manifest"`
+}
+
+// newBackupRecord describes a backup of the profile. Fields only the manifest
+// knows stay empty for backups made before manifests existed.
+func newBackupRecord(profile Profile, b Backup) BackupRecord {
+	r := BackupRecord{
+		Name:      b.Name,
+		Profile:   profile.Name,
+		Path:      b.Path,
+		Format:    b.Format,
+		Encrypted: b.Encrypted,
+		CreatedAt: b.CreatedAt,
+		Tags:      []string{},
+		Pinned:    isPinned(b),
+		Auto:      isAutoBackup(b),
+	}
+	if b.Meta != nil {
+		r.HasManifest = true
+		r.Size = b.Meta.Size
+		r.SHA256 = b.Meta.SHA256
+		r.ContentSHA256 = contentSum(b)
+		r.SourcePath = b.Meta.SourcePath
+		r.ToolVersion = b.Meta.ToolVersion
+		r.Note = b.Meta.Note
+		if len(b.Meta.Tags) > 0 {
+			r.Tags = b.Meta.Tags
+		}
+		if b.Meta.Profile != "" {
+			r.Profile = b.Meta.Profile
+		}
+	}
+	return r
+}
+
+var backupCSVHeader = []string{"name", "profile", "path", "format", "encrypted", "created_at", "size", "sha256", "content_sha256", "source_path", "tool_version", "note", "tags", "pinned", "auto", "has_manifest"}
+
+// csvRow renders the record in the column order of backupCSVHeader. Tags are
+// joined with commas; times are RFC 3339.
+func (r BackupRecord) csvRow() []string {
+	return []string{
+		r.Name, r.Profile, r.Path, string(r.Format), strconv.FormatBool(r.Encrypted),
+		r.CreatedAt.Format(time.RFC3339), strconv.FormatInt(r.Size, 10), r.SHA256, r.ContentSHA256,
+		r.SourcePath, r.ToolVersion, r.Note, strings.Join(r.Tags, ","), strconv.FormatBool(r.Pinned),
+		strconv.FormatBool(r.Auto), strconv.FormatBool(r.HasManifest),
+	}
+}
+
+// writeJSON prints v as indented JSON.
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeRows prints a header and rows as CSV, or as aligned columns for the
+// table format.
+func writeRows(format OutputFormat, header []string, rows [][]string) error {
+	if format == OutputCSV {
+		w := csv.NewWriter(os.Stdout)
+		w.Write(header)
+		w.WriteAll(rows)
+		return w.Error()
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// MirrorState is the machine-readable sync state of one mirror.
+type MirrorState struct {
+	Name            string `json:"name"`
+	Location        string `json:"location"`
+	InSync          bool   `json:"in_sync"`
+	MissingOnMirror int    `json:"missing_on_mirror"`
+	OnlyOnMirror    int    `json:"only_on_mirror"`
+	Error           string `json:"error,omitempty"`
+}
+
+// StatusReport summarizes a profile: its save, its backups, the last restore
+// and the state of its mirrors.
+type StatusReport struct {
+	Profile      string         `json:"profile"`
+	SavePath     string         `json:"save_path"`
+	SaveExists   bool           `json:"save_exists"`
+	SaveModified *time.Time     `json:"save_modified,omitempty"`
+	BackedUp     *bool          `json:"backed_up,omitempty"` // the save matches the latest backup; unset when unknown
+	Storage      string         `json:"storage"`
+	Backups      int            `json:"backups"`
+	Pinned       int            `json:"pinned"`
+	TotalSize    int64          `json:"total_size"`
+	Latest       *BackupRecord  `json:"latest_backup,omitempty"`
+	LastRestore  *RestoreRecord `json:"last_restore,omitempty"`
+	Retention    string         `json:"retention"`
+	Mirrors      []MirrorState  `json:"mirrors"`
+}
+
+// profileStatus gathers the status report of a profile. Mirrors are checked
+// with a dry-run sync, so a slow or offline mirror slows the report down.
+func profileStatus(profile Profile) (StatusReport, error) {
+	report := StatusReport{
+		Profile:   profile.Name,
+		SavePath:  profile.SavePath,
+		Retention: describeRetention(profile.Retention),
+		Mirrors:   []MirrorState{},
+	}
+	st, err := openStorage(profile)
+	if err != nil {
+		return report, err
+	}
+	report.Storage = st.String()
+	backups, err := listBackupsIn(st)
+	if err != nil {
+		return report, err
+	}
+	report.Backups = len(backups)
+	for _, b := range backups {
+		if isPinned(b) {
+			report.Pinned++
+		}
+		if b.Meta != nil {
+			report.TotalSize += b.Meta.Size
+		}
+	}
+
+	if info, err := os.Stat(profile.SavePath); err == nil {
+		report.SaveExists = true
+		modified := info.ModTime()
+		report.SaveModified = &modified
+	}
+	if len(backups) > 0 {
+		latest := newBackupRecord(profile, backups[0])
+		report.Latest = &latest
+		if want := contentSum(backups[0]); want != "" && report.SaveExists {
+			if sum, _, err := hashSave(profile); err == nil {
+				backedUp := sum == want
+				report.BackedUp = &backedUp
+			}
+		}
+	}
+	if history, err := readHistory(profile); err == nil && len(history) > 0 {
+		report.LastRestore = &history[len(history)-1]
+	}
+
+	for _, m := range profile.Mirrors {
+		status := syncMirror(profile, m, true, true)
+		state := MirrorState{
+			Name:            m.Name,
+			Location:        describeMirror(m),
+			MissingOnMirror: len(status.Pushed),
+			OnlyOnMirror:    len(status.Pulled) + len(status.Removed),
+		}
+		if status.Err != nil {
+			state.Error = status.Err.Error()
+		}
+		state.InSync = status.Err == nil && len(status.Pushed) == 0 && len(status.Pulled) == 0 && len(status.Removed) == 0
+		report.Mirrors = append(report.Mirrors, state)
+	}
+	return report, nil
+}
+
+// fields renders the report as key/value pairs for the table and CSV
+// formats, with mirrors folded into one value. For people (human set) sizes
+// and times are formatted as elsewhere in the tool; otherwise they are raw
+// bytes and RFC 3339.
+func (s StatusReport) fields(human bool) [][2]string {
+	formatTime := func(t time.Time) string {
+		if human {
+			return t.Format("01/02/2006 03:04:05 PM")
+		}
+		return t.Format(time.RFC3339)
+	}
+	totalSize := strconv.FormatInt(s.TotalSize, 10)
+	if human {
+		totalSize = formatSize(s.TotalSize)
+	}
+	var saveModified, backedUp, latest, latestAt, lastRestore, lastRestoreAt string
+	if s.SaveModified != nil {
+		saveModified = formatTime(*s.SaveModified)
+	}
+	if s.BackedUp != nil {
+		backedUp = strconv.FormatBool(*s.BackedUp)
+	}
+	if s.Latest != nil {
+		latest, latestAt = s.Latest.Name, formatTime(s.Latest.CreatedAt)
+	}
+	if s.LastRestore != nil {
+		lastRestore, lastRestoreAt = s.LastRestore.Backup, formatTime(s.LastRestore.Time)
+	}
+	var mirrors []string
+	for _, m := range s.Mirrors {
+		state := "in sync"
+		switch {
+		case m.Error != "":
+			state = "error: " + m.Error
+		case !m.InSync:
+			state = fmt.Sprintf("%d missing on the mirror, %d only on the mirror", m.MissingOnMirror, m.OnlyOnMirror)
+		}
+		mirrors = append(mirrors, fmt.Sprintf("%s (%s)", m.Name, state))
+	}
+	return [][2]string{
+		{"profile", s.Profile},
+		{"save_path", s.SavePath},
+		{"save_exists", strconv.FormatBool(s.SaveExists)},
+		{"save_modified", saveModified},
+		{"backed_up", backedUp},
+		{"storage", s.Storage},
+		{"backups", strconv.Itoa(s.Backups)},
+		{"pinned", strconv.Itoa(s.Pinned)},
+		{"total_size", totalSize},
+		{"latest_backup", latest},
+		{"latest_backup_at", latestAt},
+		{"last_restore", lastRestore},
+		{"last_restore_at", lastRestoreAt},
+		{"retention", s.Retention},
+		{"mirrors", strings.Join(mirrors, "; ")},
+	}
+}
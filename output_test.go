@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestConfig writes a config holding only profile where the program
+// looks for it, for the tests of commands and the API.
+func writeTestConfig(t *testing.T, profile Profile) {
+	t.Helper()
+	configPath, err := defaultConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(configPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("refusing to overwrite %s", configPath)
+	}
+	if err := saveConfig(Config{ActiveProfile: profile.Name, Profiles: []Profile{profile}}, configPath); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(configPath) })
+}
+
+func TestListOutputGolden(t *testing.T) {
+	profile := newTestProfile(t)
+	created := time.Date(2026, time.March, 14, 18, 30, 0, 0, time.UTC)
+	for i, b := range []struct {
+		name, note string
+		tags       []string
+		pinned     bool
+	}{
+		{"plain", "", nil, false},
+		{"quoted", `before "Margit", the Fell Omen`, []string{"boss", "stormveil"}, true},
+		{"AutoBackup_20260314_190000", "two\nlines", []string{"auto"}, false},
+	} {
+		writeTestFile(t, profile.SavePath, "level "+strings.Repeat("I", i+1))
+		backup, err := writeBackup(profile, b.name, b.note, b.tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = editBackup(profile, backup, func(m *Manifest) {
+			m.CreatedAt = created.Add(time.Duration(i) * time.Hour)
+			m.ToolVersion = "test"
+			m.Pinned = b.pinned
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestConfig(t, profile)
+	// The temporary folder changes from run to run.
+	stable := func(output string) string {
+		return strings.ReplaceAll(output, filepath.Dir(profile.SavePath), "$TMP")
+	}
+
+	for _, format := range []string{"json", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			var err error
+			output := captureStdout(t, func() { err = cmdList([]string{"--output", format}) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "list/"+format, stable(output))
+		})
+	}
+}
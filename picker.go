@@ -0,0 +1,356 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/manifoldco/promptui"
+)
+
+// BackupSort is the order backups are shown in by the pickers.
+type BackupSort string
+
+const (
+	SortNewest BackupSort = "newest"
+	SortOldest BackupSort = "oldest"
+	SortName   BackupSort = "name"
+	SortSize   BackupSort = "size" // largest first
+)
+
+var backupSorts = []BackupSort{SortNewest, SortOldest, SortName, SortSize}
+
+// backupQueryHelp explains the query syntax next to the filter prompts.
+const backupQueryHelp = "Words are fuzzy-matched against the name, note and tags, closest matches first. Filters: after:DATE, before:DATE (YYYY-MM-DD, or 7d / 12h ago), size>10MB, size<1GB, is:auto, is:manual, is:pinned; order with sort:newest, sort:oldest, sort:name or sort:size."
+
+// BackupQuery narrows and orders a list of backups, e.g. for
+// "boss after:2026-01-01 size>1MB is:manual sort:size".
+type BackupQuery struct {
+	Words   []string  // each must fuzzy-match the name, the note or a tag
+	After   time.Time // zero for no lower bound
+	Before  time.Time // zero for no upper bound
+	MinSize int64     // -1 for no bound; backups without a manifest never match a size bound
+	MaxSize int64
+	Kind    string // "auto", "manual", "pinned" or "" for all
+	Sort    BackupSort
+	sorted  bool // Sort was given; otherwise matches for words come best first
+}
+
+// parseBackupQuery parses the query syntax described by backupQueryHelp.
+func parseBackupQuery(text string) (BackupQuery, error) {
+	q := BackupQuery{MinSize: -1, MaxSize: -1, Sort: SortNewest}
+	for _, word := range strings.Fields(text) {
+		key, value, _ := strings.Cut(strings.ToLower(word), ":")
+		var err error
+		switch {
+		case key == "after":
+			q.After, err = parseQueryTime(value, false)
+		case key == "before":
+			q.Before, err = parseQueryTime(value, true)
+		case strings.HasPrefix(key, "size>"):
+			q.MinSize, err = parseQuerySize(strings.TrimPrefix(key, "size>"))
+		case strings.HasPrefix(key, "size<"):
+			q.MaxSize, err = parseQuerySize(strings.TrimPrefix(key, "size<"))
+		case key == "is":
+			if !slices.Contains([]string{"auto", "manual", "pinned"}, value) {
+				err = fmt.Errorf("unknown filter is:%s (use is:auto, is:manual or is:pinned)", value)
+			}
+			q.Kind = value
+		case key == "sort":
+			q.Sort, q.sorted = BackupSort(value), true
+			if !slices.Contains(backupSorts, q.Sort) {
+				err = fmt.Errorf("unknown order sort:%s (use newest, oldest, name or size)", value)
+			}
+		default:
+			q.Words = append(q.Words, strings.ToLower(word))
+		}
+		if err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// queryAgeUnits are the suffixes of ages in after: and before: filters.
+var queryAgeUnits = map[string]time.Duration{"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+
+// parseQueryTime reads a YYYY-MM-DD date, a YYYY-MM-DDTHH:MM time or an age
+// such as 7d or 12h. A bare date used as an upper bound includes that day.
+func parseQueryTime(value string, endOfDay bool) (time.Time, error) {
+	for suffix, unit := range queryAgeUnits {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return time.Time{}, fmt.Errorf("invalid age %q (use a whole number of hours, days or weeks, like 12h, 7d or 2w)", value)
+			}
+			return time.Now().Add(-time.Duration(n) * unit), nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", strings.ToUpper(value), time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD, YYYY-MM-DDTHH:MM or an age like 7d)", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parseQuerySize reads a size such as 500, 300KB, 1.5MB or 2GiB. Units are
+// powers of 1024, as printed by formatSize.
+func parseQuerySize(value string) (int64, error) {
+	number := strings.TrimRight(value, "kmgtib")
+	unit := strings.TrimSuffix(strings.TrimSuffix(value[len(number):], "b"), "i")
+	n, err := strconv.ParseFloat(number, 64)
+	exp := 0
+	if unit != "" {
+		exp = strings.Index("kmgt", unit) + 1
+	}
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) || len(unit) > 1 || (unit != "" && exp == 0) || (unit == "" && strings.HasSuffix(value, "ib")) {
+		return 0, fmt.Errorf("invalid size %q (use values like 500KB or 1.5GB)", value)
+	}
+	for range exp {
+		n *= 1024
+	}
+	return int64(n), nil
+}
+
+// fuzzyMatch reports whether the runes of pattern appear in s in order, and
+// how many bytes lie between them: 0 for a plain substring.
+func fuzzyMatch(pattern, s string) (int, bool) {
+	s = strings.ToLower(s)
+	gaps := 0
+	for i, r := range pattern {
+		j := strings.IndexRune(s, r)
+		if j < 0 {
+			return 0, false
+		}
+		if i > 0 {
+			gaps += j
+		}
+		s = s[j+utf8.RuneLen(r):]
+	}
+	return gaps, true
+}
+
+// matches reports whether a backup passes every part of the query.
+func (q BackupQuery) matches(b Backup) bool {
+	_, ok := q.score(b)
+	return ok
+}
+
+// score checks a backup against the query and rates how closely its words
+// matched; lower is closer.
+func (q BackupQuery) score(b Backup) (int, bool) {
+	if !q.After.IsZero() && b.CreatedAt.Before(q.After) {
+		return 0, false
+	}
+	if !q.Before.IsZero() && !b.CreatedAt.Before(q.Before) {
+		return 0, false
+	}
+	if q.MinSize >= 0 || q.MaxSize >= 0 {
+		if b.Meta == nil || (q.MinSize >= 0 && b.Meta.Size < q.MinSize) || (q.MaxSize >= 0 && b.Meta.Size > q.MaxSize) {
+			return 0, false
+		}
+	}
+	switch q.Kind {
+	case "auto":
+		if !isAutoBackup(b) {
+			return 0, false
+		}
+	case "manual":
+		if isAutoBackup(b) {
+			return 0, false
+		}
+	case "pinned":
+		if !isPinned(b) {
+			return 0, false
+		}
+	}
+
+	fields := []string{b.Name}
+	if b.Meta != nil {
+		fields = append(fields, b.Meta.Note)
+		fields = append(fields, b.Meta.Tags...)
+	}
+	score := 0
+	for _, word := range q.Words {
+		best, found := 0, false
+		for _, f := range fields {
+			if gaps, ok := fuzzyMatch(word, f); ok && (!found || gaps < best) {
+				best, found = gaps, true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		score += best
+	}
+	return score, true
+}
+
+// order returns the indexes of the backups that match, in the query's order.
+// Without an explicit order, the closest matches for words come first.
+func (q BackupQuery) order(backups []Backup) []int {
+	var indexes []int
+	scores := make([]int, len(backups))
+	for i, b := range backups {
+		if score, ok := q.score(b); ok {
+			indexes = append(indexes, i)
+			scores[i] = score
+		}
+	}
+	size := func(b Backup) int64 {
+		if b.Meta == nil {
+			return -1
+		}
+		return b.Meta.Size
+	}
+	slices.SortStableFunc(indexes, func(i, j int) int {
+		a, b := backups[i], backups[j]
+		if len(q.Words) > 0 && !q.sorted && scores[i] != scores[j] {
+			return cmp.Compare(scores[i], scores[j])
+		}
+		switch q.Sort {
+		case SortOldest:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case SortName:
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case SortSize:
+			return cmp.Compare(size(b), size(a))
+		}
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return indexes
+}
+
+// apply returns the backups that match, in the query's order.
+func (q BackupQuery) apply(backups []Backup) []Backup {
+	var shown []Backup
+	for _, i := range q.order(backups) {
+		shown = append(shown, backups[i])
+	}
+	return shown
+}
+
+// searchBackups is the live search of the pickers: the typed text is a query.
+func searchBackups(input string, b Backup) bool {
+	q, err := parseBackupQuery(input)
+	return err == nil && q.matches(b)
+}
+
+// promptForQuery asks for a query, starting from the current one.
+func promptForQuery(current string) (string, BackupQuery, error) {
+	fmt.Printf("%s %s %s\n", iconInfo, white("INFO:"), backupQueryHelp)
+	prompt := promptui.Prompt{
+		Label:     white("Filter and sort (Enter for all, newest first)"),
+		Default:   current,
+		AllowEdit: true,
+		Validate: func(input string) error {
+			_, err := parseBackupQuery(input)
+			return err
+		},
+	}
+	text, err := prompt.Run()
+	if err != nil {
+		return current, BackupQuery{}, err
+	}
+	q, err := parseBackupQuery(text)
+	return text, q, err
+}
+
+// pickBackup lets the user choose one backup. Typing searches the list; the
+// item after the backups changes the filter and the order. Cancelling
+// returns errCancelled.
+func pickBackup(label string, backups []Backup) (Backup, error) {
+	text := ""
+	query, _ := parseBackupQuery(text)
+	for {
+		shown := query.apply(backups)
+		filter := "all"
+		if text != "" {
+			filter = text
+		}
+		items := make([]string, 0, len(shown)+2)
+		for _, b := range shown {
+			items = append(items, backupLabel(b))
+		}
+		items = append(items, fmt.Sprintf("Filter and sort... (%s; %d of %d shown)", filter, len(shown), len(backups)), "Cancel")
+
+		prompt := promptui.Select{
+			Label:             white(label + " (type to search)"),
+			Items:             items,
+			Size:              10,
+			StartInSearchMode: true,
+			Searcher: func(input string, index int) bool {
+				return index >= len(shown) || searchBackups(input, shown[index])
+			},
+		}
+		index, _, err := prompt.Run()
+		switch {
+		case err != nil:
+			return Backup{}, err
+		case index == len(shown):
+			if t, q, err := promptForQuery(text); err == nil {
+				text, query = t, q
+			}
+			continue
+		case index > len(shown):
+			return Backup{}, errCancelled
+		}
+		return shown[index], nil
+	}
+}
+
+// pickBackups lets the user choose several backups. Typing in the list
+// searches it; choosing the item after the backups changes the filter and the
+// order first. Nothing chosen returns errCancelled.
+func pickBackups(message string, backups []Backup) ([]Backup, error) {
+	text := ""
+	query, _ := parseBackupQuery(text)
+	for {
+		shown := query.apply(backups)
+		filter := "all"
+		if text != "" {
+			filter = text
+		}
+		items := make([]string, 0, len(shown)+1)
+		for _, b := range shown {
+			items = append(items, backupLabel(b))
+		}
+		items = append(items, fmt.Sprintf("Filter and sort... (%s; %d of %d shown)", filter, len(shown), len(backups)))
+
+		var indexes []int
+		prompt := &survey.MultiSelect{
+			Message:  message + " (type to search, space to select, enter to confirm):",
+			Options:  items,
+			PageSize: 15,
+			Filter: func(input, _ string, index int) bool {
+				return index >= len(shown) || searchBackups(input, shown[index])
+			},
+		}
+		if err := survey.AskOne(prompt, &indexes); err != nil || len(indexes) == 0 {
+			return nil, errCancelled
+		}
+		if slices.Contains(indexes, len(shown)) {
+			if t, q, err := promptForQuery(text); err == nil {
+				text, query = t, q
+			}
+			continue
+		}
+		selected := make([]Backup, len(indexes))
+		for i, index := range indexes {
+			selected[i] = shown[index]
+		}
+		return selected, nil
+	}
+}
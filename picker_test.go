@@ -0,0 +1,115 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseBackupQuery(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+	for _, tc := range []struct {
+		text string
+		want BackupQuery
+	}{
+		{"", BackupQuery{MinSize: -1, MaxSize: -1, Sort: SortNewest}},
+		{"Boss  fight", BackupQuery{Words: []string{"boss", "fight"}, MinSize: -1, MaxSize: -1, Sort: SortNewest}},
+		{"after:2026-01-02 before:2026-01-31", BackupQuery{After: day(2026, time.January, 2), Before: day(2026, time.February, 1), MinSize: -1, MaxSize: -1, Sort: SortNewest}},
+		{"after:2026-01-02T18:30", BackupQuery{After: time.Date(2026, time.January, 2, 18, 30, 0, 0, time.Local), MinSize: -1, MaxSize: -1, Sort: SortNewest}},
+		{"size>1MB size<2gb", BackupQuery{MinSize: 1 << 20, MaxSize: 2 << 30, Sort: SortNewest}},
+		{"is:pinned", BackupQuery{MinSize: -1, MaxSize: -1, Kind: "pinned", Sort: SortNewest}},
+		{"boss SORT:Size", BackupQuery{Words: []string{"boss"}, MinSize: -1, MaxSize: -1, Sort: SortSize, sorted: true}},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			got, err := parseBackupQuery(tc.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got.Words, tc.want.Words) || !got.After.Equal(tc.want.After) || !got.Before.Equal(tc.want.Before) ||
+				got.MinSize != tc.want.MinSize || got.MaxSize != tc.want.MaxSize || got.Kind != tc.want.Kind ||
+				got.Sort != tc.want.Sort || got.sorted != tc.want.sorted {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("ages", func(t *testing.T) {
+		for text, age := range map[string]time.Duration{
+			"after:12h": 12 * time.Hour,
+			"after:7d":  7 * 24 * time.Hour,
+			"after:2w":  14 * 24 * time.Hour,
+			"after:0d":  0,
+		} {
+			before := time.Now()
+			q, err := parseBackupQuery(text)
+			if err != nil {
+				t.Errorf("%s: %v", text, err)
+				continue
+			}
+			if q.After.Before(before.Add(-age)) || q.After.After(time.Now().Add(-age)) {
+				t.Errorf("%s: got %v, want %v ago", text, q.After, age)
+			}
+		}
+	})
+
+	for _, text := range []string{
+		"after:7dd", "after:3hd", "after:5dw", "after:d", "after:-1d", "after:1.5d", "after:yesterday",
+		"before:2026-13-01", "size>lots", "size<1xb", "is:broken", "sort:random",
+	} {
+		t.Run("rejects "+text, func(t *testing.T) {
+			if _, err := parseBackupQuery(text); err == nil {
+				t.Errorf("parsed %q, want an error", text)
+			}
+		})
+	}
+}
+
+func TestParseQuerySize(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  int64
+	}{
+		{"0", 0},
+		{"500", 500},
+		{"500b", 500},
+		{"300kb", 300 << 10},
+		{"300k", 300 << 10},
+		{"300kib", 300 << 10},
+		{"1.5mb", 3 << 19},
+		{"2gib", 2 << 30},
+		{"1tb", 1 << 40},
+	} {
+		got, err := parseQuerySize(tc.value)
+		if err != nil || got != tc.want {
+			t.Errorf("parseQuerySize(%q) = %d, %v; want %d", tc.value, got, err, tc.want)
+		}
+	}
+	for _, value := range []string{"", "mb", "-5mb", "5xb", "5kmb", "5ib", "5bb", "inf", "nan"} {
+		if got, err := parseQuerySize(value); err == nil {
+			t.Errorf("parseQuerySize(%q) = %d, want an error", value, got)
+		}
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, s string
+		gaps       int
+		ok         bool
+	}{
+		{"boss", "Boss Fight", 0, true},
+		{"fight", "boss fight", 0, true},
+		{"bsfg", "boss fight", 4, true},
+		{"", "anything", 0, true},
+		{"fb", "boss fight", 0, false},
+		{"bosss", "boss", 0, false},
+		{"ü", "Über", 0, true},
+	} {
+		gaps, ok := fuzzyMatch(tc.pattern, tc.s)
+		if ok != tc.ok || (ok && gaps != tc.gaps) {
+			t.Errorf("fuzzyMatch(%q, %q) = %d, %v; want %d, %v", tc.pattern, tc.s, gaps, ok, tc.gaps, tc.ok)
+		}
+	}
+}
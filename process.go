@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+)
+
+// GamePolicy says what to do when the game is running during a restore or backup.
+type GamePolicy string
+
+const (
+	GamePolicyOff   GamePolicy = "off"   // don't check
+	GamePolicyWarn  GamePolicy = "warn"  // print a warning and carry on
+	GamePolicyBlock GamePolicy = "block" // refuse until the game is closed
+	GamePolicyWait  GamePolicy = "wait"  // wait for the game to exit
+)
+
+var gamePolicies = []GamePolicy{GamePolicyOff, GamePolicyWarn, GamePolicyBlock, GamePolicyWait}
+
+// gamePollInterval is how often a waiting restore or backup checks the game again.
+const gamePollInterval = time.Second
+
+// errGameRunning is returned when the block policy stops an operation.
+var errGameRunning = errors.New("the game is running")
+
+// GameCheck names the game's executable and what to do while it runs.
+type GameCheck struct {
+	Executable string     `json:"executable,omitempty"` // file name (e.g. "eldenring.exe") or full path
+	Restore    GamePolicy `json:"restore,omitempty"`    // default warn
+	Backup     GamePolicy `json:"backup,omitempty"`     // default off
+}
+
+// GameProcess is a running process that matched a profile's executable.
+type GameProcess struct {
+	PID  int
+	Name string
+}
+
+// processEntry is a running process and the paths or names it can be matched by.
+type processEntry struct {
+	PID   int
+	paths []string
+}
+
+// parseGamePolicy validates a policy name.
+func parseGamePolicy(value string) (GamePolicy, error) {
+	for _, p := range gamePolicies {
+		if strings.EqualFold(value, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown policy %q (use off, warn, block or wait)", value)
+}
+
+// restorePolicy returns the policy applied before restores.
+func (g GameCheck) restorePolicy() GamePolicy {
+	if g.Restore == "" {
+		return GamePolicyWarn
+	}
+	return g.Restore
+}
+
+// backupPolicy returns the policy applied before manual backups.
+func (g GameCheck) backupPolicy() GamePolicy {
+	if g.Backup == "" {
+		return GamePolicyOff
+	}
+	return g.Backup
+}
+
+// describeGameCheck renders the settings for display.
+func describeGameCheck(g GameCheck) string {
+	if g.Executable == "" {
+		return "off (no game executable set)"
+	}
+	return fmt.Sprintf("%s (restore: %s, backup: %s)", g.Executable, g.restorePolicy(), g.backupPolicy())
+}
+
+// matchesExecutable reports whether a process's executable path or name refers
+// to the configured executable. A bare name matches by file name, ignoring case
+// and a trailing .exe, so "eldenring" also finds a game started through Wine.
+func matchesExecutable(want, path string) bool {
+	if path == "" {
+		return false
+	}
+	if filepath.IsAbs(want) && filepath.Clean(want) == filepath.Clean(path) {
+		return true
+	}
+	base := func(p string) string {
+		p = p[strings.LastIndexAny(p, `/\`)+1:]
+		return strings.TrimSuffix(strings.ToLower(p), ".exe")
+	}
+	return base(want) == base(path)
+}
+
+// findGameProcesses lists running processes that match the executable,
+// leaving out this program itself.
+func findGameProcesses(executable string) ([]GameProcess, error) {
+	if executable == "" {
+		return nil, nil
+	}
+	procs, err := listProcesses()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list running processes: %w", err)
+	}
+	var found []GameProcess
+	for _, p := range procs {
+		if p.PID == os.Getpid() {
+			continue
+		}
+		for _, candidate := range p.paths {
+			if matchesExecutable(executable, candidate) {
+				found = append(found, GameProcess{PID: p.PID, Name: filepath.Base(p.paths[0])})
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// describeProcesses renders matched processes as "name (pid 123), ...".
+func describeProcesses(procs []GameProcess) string {
+	parts := make([]string, len(procs))
+	for i, p := range procs {
+		parts[i] = fmt.Sprintf("%s (pid %d)", p.Name, p.PID)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkGameRunning applies a policy before action ("restore" or "back up").
+// It returns errGameRunning when the block policy applies, and for the wait
+// policy returns once the game has exited.
+func checkGameRunning(profile Profile, policy GamePolicy, action string) error {
+	if policy == GamePolicyOff || profile.GameCheck.Executable == "" {
+		return nil
+	}
+	procs, err := findGameProcesses(profile.GameCheck.Executable)
+	if err != nil {
+		// Not being able to look is no reason to stop the user.
+		fmt.Printf("%s %s Could not check whether the game is running: %v\n", iconError, yellow("WARNING:"), err)
+		return nil
+	}
+	if len(procs) == 0 {
+		return nil
+	}
+
+	switch policy {
+	case GamePolicyWarn:
+		fmt.Printf("%s %s The game is running: %s. It may overwrite the save after you %s.\n", iconError, yellow("WARNING:"), describeProcesses(procs), action)
+		return nil
+	case GamePolicyBlock:
+		return fmt.Errorf("%w (%s); close it before you %s", errGameRunning, describeProcesses(procs), action)
+	}
+
+	fmt.Printf("%s %s The game is running: %s. Waiting for it to exit before you %s (Ctrl+C to cancel)...\n", iconInfo, yellow("INFO:"), describeProcesses(procs), action)
+	for len(procs) > 0 {
+		time.Sleep(gamePollInterval)
+		if procs, err = findGameProcesses(profile.GameCheck.Executable); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s %s The game has exited.\n", iconSuccess, green("INFO:"))
+	return nil
+}
+
+// promptForGameCheck asks for the game executable and the policies to apply.
+func promptForGameCheck(check *GameCheck) error {
+	fmt.Printf("%s %s Current executable: %s\n", iconInfo, white("INFO:"), describeGameCheck(*check))
+	executable, err := promptForInput("Enter the game's executable name or path (Enter keeps it, '-' turns the check off)")
+	if err != nil {
+		return err
+	}
+	switch executable {
+	case "":
+	case "-":
+		check.Executable = ""
+		return nil
+	default:
+		check.Executable = executable
+	}
+	if check.Executable == "" {
+		return nil
+	}
+
+	items := make([]string, len(gamePolicies))
+	for i, p := range gamePolicies {
+		items[i] = string(p)
+	}
+	for _, step := range []struct {
+		label  string
+		policy *GamePolicy
+	}{
+		{"When the game is running before a restore", &check.Restore},
+		{"When the game is running before a backup", &check.Backup},
+	} {
+		prompt := promptui.Select{Label: white(step.label), Items: items}
+		index, _, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		*step.policy = gamePolicies[index]
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// listProcesses reads running processes from /proc. Each process is matched by
+// its executable, its argv[0] (which is how Wine and Proton games show up) and
+// its short command name.
+func listProcesses() ([]processEntry, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var procs []processEntry
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		if stat, err := os.ReadFile(filepath.Join(dir, "stat")); err == nil {
+			// Zombies have exited and are only waiting to be reaped.
+			if i := strings.LastIndexByte(string(stat), ')'); i >= 0 && strings.HasPrefix(string(stat[i+1:]), " Z") {
+				continue
+			}
+		}
+		var paths []string
+		// exe is unreadable for other users' processes; the rest still works.
+		if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+			paths = append(paths, strings.TrimSuffix(exe, " (deleted)"))
+		}
+		if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+			argv0, _, _ := strings.Cut(string(cmdline), "\x00")
+			paths = append(paths, argv0)
+		}
+		if comm, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+			paths = append(paths, strings.TrimSpace(string(comm)))
+		}
+		if len(paths) > 0 {
+			procs = append(procs, processEntry{PID: pid, paths: paths})
+		}
+	}
+	return procs, nil
+}
@@ -0,0 +1,30 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// listProcesses asks ps for the command of every running process.
+func listProcesses() ([]processEntry, error) {
+	out, err := exec.Command("ps", "-axo", "pid=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	var procs []processEntry
+	for _, line := range strings.Split(string(out), "\n") {
+		pidField, comm, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidField)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, processEntry{PID: pid, paths: []string{strings.TrimSpace(comm)}})
+	}
+	return procs, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGameEnv makes the test binary act as a running game: it stays up until
+// its stdin is closed.
+const fakeGameEnv = "BACKUP_MANAGER_FAKE_GAME"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeGameEnv) == "1" {
+		io.Copy(io.Discard, os.Stdin)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeGame is a helper process standing in for the game.
+type fakeGame struct {
+	name  string // executable name to configure in the game check
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// startFakeGame copies the test binary under a name no other process has and
+// runs it until the test ends or exit is called.
+func startFakeGame(t *testing.T) *fakeGame {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("fakegame%d", time.Now().UnixNano()%1_000_000)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	exe := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(exe, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), fakeGameEnv+"=1")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	game := &fakeGame{name: name, cmd: cmd, stdin: stdin}
+	t.Cleanup(game.exit)
+	return game
+}
+
+// exit closes the game and waits for it, so it no longer shows up as running.
+func (g *fakeGame) exit() {
+	if g.stdin.Close() == nil {
+		g.cmd.Wait()
+	}
+}
+
+func TestFindGameProcesses(t *testing.T) {
+	game := startFakeGame(t)
+
+	procs, err := findGameProcesses(strings.TrimSuffix(strings.ToUpper(game.name), ".EXE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pids := make([]int, len(procs))
+	for i, p := range procs {
+		pids[i] = p.PID
+	}
+	if !slices.Contains(pids, game.cmd.Process.Pid) {
+		t.Fatalf("got %v, want the fake game (pid %d) found by name", procs, game.cmd.Process.Pid)
+	}
+	if procs, _ := findGameProcesses(filepath.Base(os.Args[0])); len(procs) != 0 {
+		t.Errorf("got %v, want this program itself left out", procs)
+	}
+
+	game.exit()
+	if procs, err := findGameProcesses(game.name); err != nil || len(procs) != 0 {
+		t.Errorf("after the game exited: got %v, %v; want nothing", procs, err)
+	}
+}
+
+func TestCheckGameRunning(t *testing.T) {
+	game := startFakeGame(t)
+	profile := newTestProfile(t)
+	profile.GameCheck.Executable = game.name
+
+	t.Run("off", func(t *testing.T) {
+		if err := checkGameRunning(profile, GamePolicyOff, "restore"); err != nil {
+			t.Errorf("got %v, want nil", err)
+		}
+	})
+	t.Run("block", func(t *testing.T) {
+		err := checkGameRunning(profile, GamePolicyBlock, "restore")
+		if !errors.Is(err, errGameRunning) || !strings.Contains(err.Error(), fmt.Sprint(game.cmd.Process.Pid)) {
+			t.Errorf("got %v, want errGameRunning naming the game's pid", err)
+		}
+	})
+	t.Run("warn", func(t *testing.T) {
+		var err error
+		out := captureStdout(t, func() { err = checkGameRunning(profile, GamePolicyWarn, "restore") })
+		if err != nil {
+			t.Errorf("got %v, want nil", err)
+		}
+		if !strings.Contains(out, "The game is running") {
+			t.Errorf("got output %q, want a warning", out)
+		}
+	})
+	t.Run("wait", func(t *testing.T) {
+		exited := make(chan time.Time, 1)
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			game.exit()
+			exited <- time.Now()
+		}()
+		var err error
+		out := captureStdout(t, func() { err = checkGameRunning(profile, GamePolicyWait, "restore") })
+		returned := time.Now()
+		if err != nil {
+			t.Fatalf("got %v, want nil once the game exits", err)
+		}
+		if at := <-exited; returned.Before(at) {
+			t.Error("returned before the game exited")
+		}
+		if !strings.Contains(out, "Waiting for it to exit") || !strings.Contains(out, "The game has exited") {
+			t.Errorf("got output %q, want the wait to be reported", out)
+		}
+	})
+	t.Run("not running", func(t *testing.T) {
+		if err := checkGameRunning(profile, GamePolicyBlock, "restore"); err != nil {
+			t.Errorf("got %v after the game exited, want nil", err)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/csv"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// listProcesses asks tasklist for the image name of every running process.
+func listProcesses() ([]processEntry, error) {
+	out, err := exec.Command("tasklist", "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return nil, err
+	}
+	records, err := csv.NewReader(strings.NewReader(string(out))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var procs []processEntry
+	for _, rec := range records {
+		if len(rec) < 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(rec[1])
+		if err != nil {
+			continue
+		}
+		procs = append(procs, processEntry{PID: pid, paths: []string{rec[0]}})
+	}
+	return procs, nil
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// defaultProfileName is used for the first profile and for migrated single-game configs.
+const defaultProfileName = "default"
+
+// Profile holds the settings for a single game
+type Profile struct {
+	Name       string    `json:"name"`
+	SavePath   string    `json:"save_path"`
+	BackupDir  string    `json:"backup_dir"`
+	AutoBackup bool      `json:"auto_backup"`
+	Retention  Retention `json:"retention"`
+
+	// Format is the ArchiveFormat used for new backups; empty means plain.
+	Format string `json:"format,omitempty"`
+
+	// Include and Exclude filter the files captured from a save directory.
+	// They are ignored when SavePath is a single file.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	// StableFor is how long the save must go unmodified before it is
+	// copied, as a duration such as "2s"; empty means one second.
+	StableFor string `json:"stable_for,omitempty"`
+
+	// Encryption turns on encryption for new backups.
+	Encryption EncryptionSettings `json:"encryption,omitzero"`
+
+	// Duplicates decides what happens when the save is identical to the
+	// latest backup; empty means ask.
+	Duplicates DuplicatePolicy `json:"duplicates,omitempty"`
+
+	// GameCheck detects the running game before restores and backups.
+	GameCheck GameCheck `json:"game_check,omitzero"`
+
+	// Storage selects where backups are kept; empty means BackupDir.
+	Storage StorageSettings `json:"storage,omitzero"`
+
+	// Mirrors receive a copy of every backup.
+	Mirrors []Mirror `json:"mirrors,omitempty"`
+
+	// Watch tunes the watch command for this game.
+	Watch WatchSettings `json:"watch,omitzero"`
+
+	// Schedule tells the daemon command when to back up this game, as an
+	// interval, "daily HH:MM" or a cron expression; empty means never.
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// activeProfile returns a copy of the currently selected profile.
+func (c Config) activeProfile() Profile {
+	if p, err := c.profile(c.ActiveProfile); err == nil {
+		return *p
+	}
+	return c.Profiles[0]
+}
+
+// profile returns a pointer to the named profile so callers can edit it in place.
+func (c *Config) profile(name string) (*Profile, error) {
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			return &c.Profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("game profile not found: %s", name)
+}
+
+// migrateLegacyConfig moves the old top-level save settings into a "default"
+// profile. It reports whether the config was changed.
+func migrateLegacyConfig(config *Config) bool {
+	if len(config.Profiles) > 0 || config.SavePath == "" {
+		return false
+	}
+	config.Profiles = []Profile{{
+		Name:       defaultProfileName,
+		SavePath:   config.SavePath,
+		BackupDir:  config.BackupDir,
+		AutoBackup: config.AutoBackup,
+	}}
+	config.ActiveProfile = defaultProfileName
+	config.SavePath = ""
+	config.BackupDir = ""
+	config.AutoBackup = false
+	return true
+}
+
+// validateProfileName checks that name is usable and not already taken.
+func validateProfileName(config Config, name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("profile name cannot contain slashes")
+	}
+	if _, err := config.profile(name); err == nil {
+		return fmt.Errorf("a profile named %q already exists", name)
+	}
+	return nil
+}
+
+// setupProfile walks the user through creating a new game profile. An empty
+// name falls back to fallbackName when it is set.
+func setupProfile(config Config, fallbackName string) (Profile, error) {
+	var profile Profile
+	var err error
+
+	// Get save file path with improved validation
+	profile.SavePath, err = getSaveFilePath()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	if info, err := os.Stat(profile.SavePath); err == nil && info.IsDir() {
+		profile.Include, profile.Exclude, err = promptForPatterns(nil, nil)
+		if err != nil {
+			return Profile{}, fmt.Errorf("setup cancelled by user")
+		}
+	}
+
+	// Get backup directory with validation
+	profile.BackupDir, err = getBackupDirectory()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	for {
+		fmt.Printf("%s %s GAME PROFILE NAME\n", iconSettings, cyan("STEP 3:"))
+		if fallbackName != "" {
+			fmt.Printf("Enter a name for this game (press Enter for '%s').\n", fallbackName)
+		} else {
+			fmt.Println("Enter a name for this game.")
+		}
+		fmt.Println()
+
+		name, err := promptForInput("Game profile name")
+		if err != nil {
+			if err == promptui.ErrInterrupt {
+				return Profile{}, fmt.Errorf("setup cancelled by user")
+			}
+			continue
+		}
+		if name == "" {
+			name = fallbackName
+		}
+		if err := validateProfileName(config, name); err != nil {
+			fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+			fmt.Println()
+			continue
+		}
+		profile.Name = name
+		break
+	}
+
+	// Set default auto-backup to true
+	profile.AutoBackup = true
+	return profile, nil
+}
+
+// selectProfile shows a picker over all profiles and returns the chosen index,
+// or -1 if the user cancelled.
+func selectProfile(config Config, label string) int {
+	items := make([]string, len(config.Profiles))
+	for i, p := range config.Profiles {
+		marker := ""
+		if p.Name == config.ActiveProfile {
+			marker = " (active)"
+		}
+		items[i] = fmt.Sprintf("%s%s - %s", p.Name, marker, p.SavePath)
+	}
+
+	prompt := promptui.Select{
+		Label: white(label),
+		Items: append(items, "Cancel"),
+		Size:  7,
+	}
+	index, _, err := prompt.Run()
+	if err != nil || index == len(items) {
+		return -1
+	}
+	return index
+}
+
+func switchProfile(config Config, configPath string) Config {
+	clearScreen()
+	fmt.Println(cyan("====================================="))
+	fmt.Printf("%s %s SWITCH GAME\n", iconRestore, cyan("SWITCH GAME"))
+	fmt.Println(cyan("====================================="))
+	fmt.Println()
+
+	if len(config.Profiles) < 2 {
+		fmt.Printf("%s %s Only one game profile is configured. Add more from Settings.\n", iconInfo, yellow("INFO:"))
+		waitForEnter()
+		return config
+	}
+
+	index := selectProfile(config, "Select a game")
+	if index < 0 {
+		return config
+	}
+
+	config.ActiveProfile = config.Profiles[index].Name
+	if err := saveConfig(config, configPath); err != nil {
+		fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return config
+	}
+	fmt.Printf("%s %s Switched to %s\n", iconSuccess, green("SUCCESS:"), config.ActiveProfile)
+	waitForEnter()
+	return config
+}
+
+func addProfile(config Config, configPath string) Config {
+	clearScreen()
+	fmt.Println(cyan("====================================="))
+	fmt.Printf("%s %s ADD GAME\n", iconSettings, cyan("ADD GAME"))
+	fmt.Println(cyan("====================================="))
+	fmt.Println()
+
+	profile, err := setupProfile(config, "")
+	if err != nil {
+		fmt.Printf("%s %s %v\n", iconError, yellow("INFO:"), err)
+		waitForEnter()
+		return config
+	}
+
+	config.Profiles = append(config.Profiles, profile)
+	config.ActiveProfile = profile.Name
+	if err := saveConfig(config, configPath); err != nil {
+		fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+	} else {
+		fmt.Printf("%s %s Game profile %s added and selected.\n", iconSuccess, green("SUCCESS:"), profile.Name)
+	}
+	waitForEnter()
+	return config
+}
+
+func removeProfile(config Config, configPath string) Config {
+	clearScreen()
+	fmt.Println(cyan("====================================="))
+	fmt.Printf("%s %s REMOVE GAME\n", iconDelete, cyan("REMOVE GAME"))
+	fmt.Println(cyan("====================================="))
+	fmt.Println()
+
+	if len(config.Profiles) < 2 {
+		fmt.Printf("%s %s The last game profile cannot be removed.\n", iconError, yellow("INFO:"))
+		waitForEnter()
+		return config
+	}
+
+	index := selectProfile(config, "Select a game to remove")
+	if index < 0 {
+		return config
+	}
+	name := config.Profiles[index].Name
+
+	fmt.Printf("%s %s Backups in %s will be kept on disk.\n", iconInfo, yellow("INFO:"), config.Profiles[index].BackupDir)
+	confirm, err := promptForInput(fmt.Sprintf("Remove game profile %s? (y/N)", name))
+	if err != nil || strings.ToLower(confirm) != "y" {
+		fmt.Printf("%s %s Removal cancelled.\n", iconError, yellow("INFO:"))
+		waitForEnter()
+		return config
+	}
+
+	config.Profiles = slices.Delete(config.Profiles, index, index+1)
+	if config.ActiveProfile == name {
+		config.ActiveProfile = config.Profiles[0].Name
+	}
+	if err := saveConfig(config, configPath); err != nil {
+		fmt.Printf("%s %s Failed to save config: %v\n", iconError, red("ERROR:"), err)
+	} else {
+		fmt.Printf("%s %s Game profile %s removed.\n", iconSuccess, green("SUCCESS:"), name)
+	}
+	waitForEnter()
+	return config
+}
+
+// promptForCount asks for a non-negative number, keeping current on empty input.
+func promptForCount(prompt string, current int) (int, error) {
+	for {
+		input, err := promptForInput(fmt.Sprintf("%s [%d]", prompt, current))
+		if err != nil {
+			return current, err
+		}
+		if input == "" {
+			return current, nil
+		}
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 0 {
+			fmt.Printf("%s %s Please enter a whole number, 0 or greater.\n", iconError, red("ERROR:"))
+			continue
+		}
+		return n, nil
+	}
+}
+
+// promptForPatterns asks for comma-separated include and exclude glob patterns
+// for a save directory. Empty input keeps the current list; "-" clears it.
+func promptForPatterns(include, exclude []string) ([]string, []string, error) {
+	fmt.Printf("%s %s FOLDER FILTERS\n", iconSettings, cyan("OPTIONAL:"))
+	fmt.Println("Enter comma-separated glob patterns, e.g. *.sav, slot*, screenshots/*")
+	fmt.Println("Patterns without a slash match at any depth. Press Enter to keep the current value, '-' to clear it.")
+	fmt.Println()
+
+	ask := func(label string, current []string) ([]string, error) {
+		shown := strings.Join(current, ", ")
+		if shown == "" {
+			shown = "none"
+		}
+		input, err := promptForInput(fmt.Sprintf("%s [%s]", label, shown))
+		if err != nil {
+			return current, err
+		}
+		switch input {
+		case "":
+			return current, nil
+		case "-":
+			return nil, nil
+		}
+		return parsePatterns(input), nil
+	}
+
+	include, err := ask("Include only (empty = all files)", include)
+	if err != nil {
+		return include, exclude, err
+	}
+	exclude, err = ask("Exclude", exclude)
+	return include, exclude, err
+}
+
+// describeFilters renders a profile's folder filters for display.
+func describeFilters(p Profile) string {
+	if len(p.Include) == 0 && len(p.Exclude) == 0 {
+		return "all files"
+	}
+	var parts []string
+	if len(p.Include) > 0 {
+		parts = append(parts, "include "+strings.Join(p.Include, ", "))
+	}
+	if len(p.Exclude) > 0 {
+		parts = append(parts, "exclude "+strings.Join(p.Exclude, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// profileFormat returns the archive format for new backups, treating an empty
+// or unknown setting as plain.
+func profileFormat(p Profile) ArchiveFormat {
+	if f, err := parseArchiveFormat(p.Format); err == nil {
+		return f
+	}
+	return FormatPlain
+}
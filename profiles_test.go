@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	savePath := filepath.Join(dir, "save.dat")
+	backupDir := filepath.Join(dir, "backups")
+	legacy, err := json.Marshal(map[string]any{"save_path": savePath, "backup_dir": backupDir, "auto_backup": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, configPath, string(legacy))
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Profile{Name: defaultProfileName, SavePath: savePath, BackupDir: backupDir, AutoBackup: true}
+	if len(config.Profiles) != 1 || config.ActiveProfile != defaultProfileName {
+		t.Fatalf("got %+v, want a single active %q profile", config, defaultProfileName)
+	}
+	if p := config.Profiles[0]; p.Name != want.Name || p.SavePath != want.SavePath || p.BackupDir != want.BackupDir || p.AutoBackup != want.AutoBackup {
+		t.Errorf("got profile %+v, want %+v", p, want)
+	}
+
+	// The migrated config is saved without the old fields.
+	var saved map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(readTestFile(t, configPath)), &saved); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"save_path", "backup_dir", "auto_backup"} {
+		if _, ok := saved[key]; ok {
+			t.Errorf("saved config still has the legacy %s", key)
+		}
+	}
+	if _, ok := saved["profiles"]; !ok {
+		t.Error("saved config has no profiles")
+	}
+
+	for _, tc := range []struct {
+		name   string
+		config Config
+	}{
+		{"already migrated", Config{ActiveProfile: "elden", Profiles: []Profile{{Name: "elden", SavePath: savePath}}, SavePath: "stale"}},
+		{"empty", Config{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(tc.config.Profiles)
+			if migrateLegacyConfig(&tc.config) {
+				t.Error("reported a migration")
+			}
+			if len(tc.config.Profiles) != before {
+				t.Errorf("got %d profiles, want %d", len(tc.config.Profiles), before)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RestoreError reports which step of a restore failed and whether the
+// previous save could be put back.
+type RestoreError struct {
+	Step        string
+	Err         error
+	RolledBack  bool   // the previous save was put back in place
+	Unchanged   bool   // the failure happened before the save was touched
+	LeftoverDir string // where the previous save was left if rollback failed
+	RollbackErr error
+}
+
+func (e *RestoreError) Error() string {
+	msg := fmt.Sprintf("%s failed: %v", e.Step, e.Err)
+	switch {
+	case e.Unchanged:
+		return msg + "; the current save was not changed"
+	case e.RolledBack:
+		return msg + "; the previous save was restored"
+	case e.LeftoverDir != "":
+		return fmt.Sprintf("%s; rollback also failed (%v) - the previous save is at %s", msg, e.RollbackErr, e.LeftoverDir)
+	default:
+		return fmt.Sprintf("%s; rollback also failed: %v", msg, e.RollbackErr)
+	}
+}
+
+func (e *RestoreError) Unwrap() error { return e.Err }
+
+// replaceSaveFile atomically replaces the save file with src: the data is
+// written to a temp file in the save's directory, synced to disk and renamed
+// over the save, so a crash leaves either the old or the new save in place.
+func replaceSaveFile(savePath, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return &RestoreError{Step: "reading backup", Err: err, Unchanged: true}
+	}
+	defer in.Close()
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(savePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(savePath)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(savePath)+".restore-")
+	if err != nil {
+		return &RestoreError{Step: "creating temp file", Err: err, Unchanged: true}
+	}
+	tmpPath := tmp.Name()
+
+	_, err = io.Copy(tmp, in)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, mode)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return &RestoreError{Step: "writing temp file", Err: err, Unchanged: true}
+	}
+
+	if err := os.Rename(tmpPath, savePath); err != nil {
+		os.Remove(tmpPath)
+		return &RestoreError{Step: "replacing save file", Err: err, Unchanged: true}
+	}
+	syncDir(dir)
+	return nil
+}
+
+// renameSaveTree moves the save folders aside and into place during a
+// restore. Tests replace it to make one of the moves fail.
+var renameSaveTree = os.Rename
+
+// replaceSaveTree atomically swaps a save directory for the contents of
+// backupDir. The new tree is staged next to the save, including any files the
+// profile's filters exclude so they survive the restore. The live directory is
+// then renamed aside and the staged tree renamed into place; if that fails the
+// old directory is moved back.
+func replaceSaveTree(profile Profile, backupDir string) error {
+	parent := filepath.Dir(profile.SavePath)
+	base := filepath.Base(profile.SavePath)
+
+	staging, err := os.MkdirTemp(parent, "."+base+".restore-")
+	if err != nil {
+		return &RestoreError{Step: "creating staging folder", Err: err, Unchanged: true}
+	}
+	if err := stageSaveTree(profile, backupDir, staging); err != nil {
+		os.RemoveAll(staging)
+		return &RestoreError{Step: "staging restored files", Err: err, Unchanged: true}
+	}
+
+	// MkdirTemp creates private folders; give the new save the old one's permissions.
+	mode := os.FileMode(0755)
+	info, statErr := os.Stat(profile.SavePath)
+	hadPrevious := statErr == nil
+	if hadPrevious {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(staging, mode); err != nil {
+		os.RemoveAll(staging)
+		return &RestoreError{Step: "staging restored files", Err: err, Unchanged: true}
+	}
+	previous := ""
+	if hadPrevious {
+		previous = staging + ".previous"
+		if err := renameSaveTree(profile.SavePath, previous); err != nil {
+			os.RemoveAll(staging)
+			return &RestoreError{Step: "moving current save aside", Err: err, Unchanged: true}
+		}
+	}
+
+	if err := renameSaveTree(staging, profile.SavePath); err != nil {
+		restoreErr := &RestoreError{Step: "swapping in restored save", Err: err}
+		if !hadPrevious {
+			os.RemoveAll(staging)
+			restoreErr.Unchanged = true
+			return restoreErr
+		}
+		if rbErr := renameSaveTree(previous, profile.SavePath); rbErr != nil {
+			restoreErr.RollbackErr = rbErr
+			restoreErr.LeftoverDir = previous
+			return restoreErr
+		}
+		os.RemoveAll(staging)
+		restoreErr.RolledBack = true
+		return restoreErr
+	}
+	syncDir(parent)
+
+	if hadPrevious {
+		// The restore has succeeded; failing to clean up only leaves a hidden folder.
+		os.RemoveAll(previous)
+	}
+	return nil
+}
+
+// stageSaveTree fills staging with every file from backupDir plus the files in
+// the live save that the profile's filters exclude.
+func stageSaveTree(profile Profile, backupDir, staging string) error {
+	backupFiles, err := collectSaveFiles(Profile{}, backupDir)
+	if err != nil {
+		return err
+	}
+	for _, rel := range backupFiles {
+		if err := copyFile(filepath.Join(backupDir, filepath.FromSlash(rel)), filepath.Join(staging, filepath.FromSlash(rel))); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", rel, err)
+		}
+	}
+
+	if _, err := os.Stat(profile.SavePath); os.IsNotExist(err) {
+		return nil
+	}
+	allFiles, err := collectSaveFiles(Profile{}, profile.SavePath)
+	if err != nil {
+		return err
+	}
+	for _, rel := range allFiles {
+		if includeFile(profile, rel) {
+			continue
+		}
+		if err := copyFile(filepath.Join(profile.SavePath, filepath.FromSlash(rel)), filepath.Join(staging, filepath.FromSlash(rel))); err != nil {
+			return fmt.Errorf("failed to keep excluded file %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// syncDir flushes a directory entry to disk after a rename. Not every platform
+// supports syncing directories, so errors are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
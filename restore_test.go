@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRestoreStopsWhenAutoBackupFails(t *testing.T) {
+	profile := newTestProfile(t)
+	backup, err := writeBackup(profile, "old", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, profile.SavePath, "slot 2")
+
+	// A missing key file makes every new backup, including the auto-backup, fail.
+	profile.AutoBackup = true
+	profile.Encryption = EncryptionSettings{Enabled: true, KeyFile: profile.SavePath + ".missing-key"}
+
+	if _, err := applyBackup(profile, backup, false); !errors.Is(err, errAutoBackupFailed) {
+		t.Fatalf("applyBackup: got %v, want errAutoBackupFailed", err)
+	}
+	if got := readTestFile(t, profile.SavePath); got != "slot 2" {
+		t.Errorf("save was overwritten with %q although its auto-backup failed", got)
+	}
+
+	if _, err := applyBackup(profile, backup, true); err != nil {
+		t.Fatalf("forced applyBackup: %v", err)
+	}
+	if got := readTestFile(t, profile.SavePath); got != "slot 1" {
+		t.Errorf("forced restore left %q, want the backup's content", got)
+	}
+}
+
+func TestReplaceSaveTreeRollsBack(t *testing.T) {
+	newProfile := func(t *testing.T) (Profile, Backup, map[string]string) {
+		t.Helper()
+		profile := newTestProfile(t)
+		profile.SavePath = filepath.Join(t.TempDir(), "SaveData")
+		writeTestFile(t, filepath.Join(profile.SavePath, "slots", "1.sav"), "level 3")
+		backup, err := writeBackup(profile, "old", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, filepath.Join(profile.SavePath, "slots", "1.sav"), "level 12")
+		writeTestFile(t, filepath.Join(profile.SavePath, "slots", "2.sav"), "level 1")
+		writeTestFile(t, filepath.Join(profile.SavePath, "profile.dat"), "options")
+		return profile, backup, readTestTree(t, profile.SavePath)
+	}
+	// failRename makes the renames whose source matches fail.
+	failRename := func(t *testing.T, fail func(from string) bool) {
+		t.Helper()
+		renameSaveTree = func(from, to string) error {
+			if fail(from) {
+				return &os.LinkError{Op: "rename", Old: from, New: to, Err: errors.New("injected failure")}
+			}
+			return os.Rename(from, to)
+		}
+		t.Cleanup(func() { renameSaveTree = os.Rename })
+	}
+	staged := func(from string) bool {
+		return strings.Contains(filepath.Base(from), ".restore-") && !strings.HasSuffix(from, ".previous")
+	}
+	leftovers := func(t *testing.T, profile Profile) []string {
+		t.Helper()
+		entries, err := os.ReadDir(filepath.Dir(profile.SavePath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			if e.Name() != filepath.Base(profile.SavePath) {
+				names = append(names, e.Name())
+			}
+		}
+		return names
+	}
+
+	t.Run("swap fails", func(t *testing.T) {
+		profile, backup, want := newProfile(t)
+		failRename(t, staged)
+
+		_, err := applyBackup(profile, backup, false)
+		var restoreErr *RestoreError
+		if !errors.As(err, &restoreErr) || !restoreErr.RolledBack {
+			t.Fatalf("got %v, want a rolled back RestoreError", err)
+		}
+		if got := readTestTree(t, profile.SavePath); !maps.Equal(got, want) {
+			t.Errorf("save after the failed restore: got %v, want %v", got, want)
+		}
+		if got := leftovers(t, profile); len(got) != 0 {
+			t.Errorf("left %v next to the save", got)
+		}
+	})
+
+	t.Run("rollback fails too", func(t *testing.T) {
+		profile, backup, want := newProfile(t)
+		failRename(t, func(from string) bool { return staged(from) || strings.HasSuffix(from, ".previous") })
+
+		_, err := applyBackup(profile, backup, false)
+		var restoreErr *RestoreError
+		if !errors.As(err, &restoreErr) || restoreErr.RolledBack || restoreErr.LeftoverDir == "" {
+			t.Fatalf("got %v, want a RestoreError naming where the save was left", err)
+		}
+		if got := readTestTree(t, restoreErr.LeftoverDir); !maps.Equal(got, want) {
+			t.Errorf("save left at %s: got %v, want %v", restoreErr.LeftoverDir, got, want)
+		}
+	})
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RetentionRules decide which backups of one kind to keep. Zero means the rule
+// is off. A backup survives pruning if any enabled rule keeps it; with no
+// rules enabled every backup is kept.
+type RetentionRules struct {
+	KeepLast    int `json:"keep_last,omitempty"`    // the N newest backups
+	KeepDays    int `json:"keep_days,omitempty"`    // everything from the last D days
+	KeepDaily   int `json:"keep_daily,omitempty"`   // newest backup of each of the last N days with backups
+	KeepWeekly  int `json:"keep_weekly,omitempty"`  // newest backup of each of the last N weeks with backups
+	KeepMonthly int `json:"keep_monthly,omitempty"` // newest backup of each of the last N months with backups
+}
+
+// Retention holds the rules for manual backups and, separately, for the
+// automatic ones: the AutoBackup_ copies taken before each restore and the
+// backups of watch mode and the daemon.
+type Retention struct {
+	RetentionRules
+	Auto RetentionRules `json:"auto,omitzero"`
+}
+
+// enabled reports whether any rule is set.
+func (r RetentionRules) enabled() bool {
+	return r != RetentionRules{}
+}
+
+// retentionField maps a config key such as keep_daily to its rule field.
+// Keys prefixed with auto_ address the auto-backup rules.
+func retentionField(r *Retention, key string) (*int, bool) {
+	rules := &r.RetentionRules
+	if strings.HasPrefix(key, "auto_") {
+		rules = &r.Auto
+		key = strings.TrimPrefix(key, "auto_")
+	}
+	switch key {
+	case "keep_last":
+		return &rules.KeepLast, true
+	case "keep_days":
+		return &rules.KeepDays, true
+	case "keep_daily":
+		return &rules.KeepDaily, true
+	case "keep_weekly":
+		return &rules.KeepWeekly, true
+	case "keep_monthly":
+		return &rules.KeepMonthly, true
+	}
+	return nil, false
+}
+
+// autoBackupKinds are the tags and name prefixes of backups taken without the
+// user asking for one.
+var autoBackupKinds = []struct{ tag, prefix string }{
+	{"auto", "AutoBackup_"},     // before a restore
+	{"watch", "Watch_"},         // by watch mode
+	{"scheduled", "Scheduled_"}, // by the daemon
+}
+
+// isAutoBackup reports whether a backup was taken automatically, so the
+// auto-backup retention rules apply to it rather than the manual ones.
+func isAutoBackup(b Backup) bool {
+	for _, kind := range autoBackupKinds {
+		if b.Meta != nil && slices.Contains(b.Meta.Tags, kind.tag) {
+			return true
+		}
+		if strings.HasPrefix(b.Name, kind.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPinned reports whether a backup is protected from pruning.
+func isPinned(b Backup) bool {
+	return b.Meta != nil && b.Meta.Pinned
+}
+
+// selectKept returns the set of backups (by path) that the rules keep.
+// backups must be sorted newest first.
+func selectKept(backups []Backup, rules RetentionRules, now time.Time) map[string]bool {
+	kept := make(map[string]bool)
+	if !rules.enabled() {
+		for _, b := range backups {
+			kept[b.Path] = true
+		}
+		return kept
+	}
+
+	for i, b := range backups {
+		if i < rules.KeepLast {
+			kept[b.Path] = true
+		}
+		if rules.KeepDays > 0 && now.Sub(b.CreatedAt) < time.Duration(rules.KeepDays)*24*time.Hour {
+			kept[b.Path] = true
+		}
+	}
+
+	// Grandfather-father-son: keep the newest backup in each of the most recent buckets.
+	bucketed := func(limit int, bucket func(time.Time) string) {
+		seen := make(map[string]bool)
+		for _, b := range backups {
+			if len(seen) >= limit {
+				return
+			}
+			key := bucket(b.CreatedAt.Local())
+			if !seen[key] {
+				seen[key] = true
+				kept[b.Path] = true
+			}
+		}
+	}
+	bucketed(rules.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") })
+	bucketed(rules.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	bucketed(rules.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") })
+	return kept
+}
+
+// planPrune returns the backups the profile's retention policy would delete.
+// Pinned backups are never included, and neither is the backup that undoing
+// the last restore needs. Backups whose manifest can't be read are left out
+// of retention altogether: whether they are pinned is unknown.
+func planPrune(profile Profile, now time.Time) ([]Backup, error) {
+	backups, err := listBackupsInternal(profile)
+	if err != nil {
+		return nil, err
+	}
+	undo := undoTarget(profile)
+
+	var manual, auto []Backup
+	for _, b := range backups {
+		if b.MetaErr != nil {
+			continue
+		}
+		if isAutoBackup(b) {
+			auto = append(auto, b)
+		} else {
+			manual = append(manual, b)
+		}
+	}
+
+	var doomed []Backup
+	for _, group := range []struct {
+		backups []Backup
+		rules   RetentionRules
+	}{
+		{manual, profile.Retention.RetentionRules},
+		{auto, profile.Retention.Auto},
+	} {
+		kept := selectKept(group.backups, group.rules, now)
+		for _, b := range group.backups {
+			if !kept[b.Path] && !isPinned(b) && b.Name != undo {
+				doomed = append(doomed, b)
+			}
+		}
+	}
+	return doomed, nil
+}
+
+// pruneBackups applies the profile's retention policy, also on the mirrors,
+// drops blobs no backup uses any more and returns the backups that were
+// deleted. Deletion continues past individual failures; the first error is
+// returned along with everything that was removed.
+func pruneBackups(profile Profile) ([]Backup, error) {
+	doomed, err := planPrune(profile, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var removed []Backup
+	var firstErr error
+	for _, b := range doomed {
+		if err := removeBackup(b); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to delete %s: %w", b.Name, err)
+			}
+			continue
+		}
+		removed = append(removed, b)
+	}
+	if st, err := openStorage(profile); err == nil {
+		if _, _, err := collectGarbage(st); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := removeFromMirrors(profile, removed); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return removed, firstErr
+}
+
+// reportAutoPrune applies retention after a new backup and prints the result.
+func reportAutoPrune(profile Profile) {
+	removed, err := pruneBackups(profile)
+	if len(removed) > 0 {
+		fmt.Printf("%s %s Retention policy removed %d old backup(s).\n", iconDelete, green("INFO:"), len(removed))
+	}
+	if err != nil {
+		fmt.Printf("%s %s Retention cleanup failed: %v\n", iconError, red("ERROR:"), err)
+	}
+}
+
+// describeRules renders one set of retention rules for display.
+func describeRules(r RetentionRules) string {
+	var parts []string
+	if r.KeepLast > 0 {
+		parts = append(parts, fmt.Sprintf("last %d", r.KeepLast))
+	}
+	if r.KeepDays > 0 {
+		parts = append(parts, fmt.Sprintf("%d day(s)", r.KeepDays))
+	}
+	if r.KeepDaily > 0 {
+		parts = append(parts, fmt.Sprintf("%d daily", r.KeepDaily))
+	}
+	if r.KeepWeekly > 0 {
+		parts = append(parts, fmt.Sprintf("%d weekly", r.KeepWeekly))
+	}
+	if r.KeepMonthly > 0 {
+		parts = append(parts, fmt.Sprintf("%d monthly", r.KeepMonthly))
+	}
+	if len(parts) == 0 {
+		return "keep everything"
+	}
+	return "keep " + strings.Join(parts, ", ")
+}
+
+// describeRetention renders a profile's retention policy for display.
+func describeRetention(r Retention) string {
+	return fmt.Sprintf("manual: %s; auto: %s", describeRules(r.RetentionRules), describeRules(r.Auto))
+}
+
+// promptForRules asks for each retention rule, keeping current values on empty input.
+func promptForRules(rules RetentionRules) (RetentionRules, error) {
+	fields := []struct {
+		label string
+		value *int
+	}{
+		{"Keep the last N backups", &rules.KeepLast},
+		{"Keep everything from the last D days", &rules.KeepDays},
+		{"Keep one per day for the last N days", &rules.KeepDaily},
+		{"Keep one per week for the last N weeks", &rules.KeepWeekly},
+		{"Keep one per month for the last N months", &rules.KeepMonthly},
+	}
+	for _, f := range fields {
+		n, err := promptForCount(f.label, *f.value)
+		if err != nil {
+			return rules, err
+		}
+		*f.value = n
+	}
+	return rules, nil
+}
+
+func pruneMenu(profile Profile) {
+	clearScreen()
+	fmt.Println(cyan("====================================="))
+	fmt.Printf("%s %s PRUNE OLD BACKUPS\n", iconDelete, cyan("PRUNE OLD BACKUPS"))
+	fmt.Println(cyan("====================================="))
+	fmt.Println()
+	fmt.Printf("%s %s Retention: %s\n", iconSettings, white("INFO:"), describeRetention(profile.Retention))
+	fmt.Println()
+
+	doomed, err := planPrune(profile, time.Now())
+	if err != nil {
+		fmt.Printf("%s %s Failed to list backups: %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return
+	}
+	if len(doomed) == 0 {
+		fmt.Printf("%s %s Nothing to prune.\n", iconSuccess, green("INFO:"))
+		waitForEnter()
+		return
+	}
+
+	fmt.Printf("%s %s The following backups will be permanently deleted:\n", iconError, yellow("WARNING:"))
+	for _, b := range doomed {
+		fmt.Printf(" - %s %s\n", iconDelete, yellow(backupLabel(b)))
+	}
+	fmt.Println()
+
+	confirm, err := promptForInput("Are you sure? (y/N)")
+	if err != nil || strings.ToLower(confirm) != "y" {
+		fmt.Printf("%s %s Pruning cancelled.\n", iconError, yellow("INFO:"))
+		waitForEnter()
+		return
+	}
+
+	removed, err := pruneBackups(profile)
+	if err != nil {
+		fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+	}
+	fmt.Printf("%s %s %d backup(s) pruned.\n", iconSuccess, green("SUCCESS:"), len(removed))
+	waitForEnter()
+}
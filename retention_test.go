@@ -0,0 +1,164 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSelectKept(t *testing.T) {
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2024, month, day, hour, 0, 0, 0, time.Local)
+	}
+	now := at(time.March, 20, 12) // a Wednesday, in ISO week 12
+	backups := []Backup{
+		{Path: "a", CreatedAt: at(time.March, 20, 10)},
+		{Path: "b", CreatedAt: at(time.March, 20, 8)},
+		{Path: "c", CreatedAt: at(time.March, 19, 20)},
+		{Path: "d", CreatedAt: at(time.March, 18, 9)},    // Monday of week 12
+		{Path: "e", CreatedAt: at(time.March, 14, 9)},    // week 11
+		{Path: "f", CreatedAt: at(time.March, 2, 9)},     // week 9
+		{Path: "g", CreatedAt: at(time.February, 27, 9)}, // week 9, February
+		{Path: "h", CreatedAt: at(time.January, 10, 9)},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		rules RetentionRules
+		want  []string
+	}{
+		{"no rules keep everything", RetentionRules{}, []string{"a", "b", "c", "d", "e", "f", "g", "h"}},
+		{"keep_last", RetentionRules{KeepLast: 3}, []string{"a", "b", "c"}},
+		{"keep_days", RetentionRules{KeepDays: 3}, []string{"a", "b", "c", "d"}},
+		{"keep_daily", RetentionRules{KeepDaily: 3}, []string{"a", "c", "d"}},
+		{"keep_daily counts days with backups", RetentionRules{KeepDaily: 10}, []string{"a", "c", "d", "e", "f", "g", "h"}},
+		{"keep_weekly", RetentionRules{KeepWeekly: 3}, []string{"a", "e", "f"}},
+		{"keep_monthly", RetentionRules{KeepMonthly: 2}, []string{"a", "g"}},
+		{"rules add up", RetentionRules{KeepLast: 2, KeepMonthly: 3}, []string{"a", "b", "g", "h"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			kept := selectKept(backups, tc.rules, now)
+			var got []string
+			for _, b := range backups {
+				if kept[b.Path] {
+					got = append(got, b.Path)
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPruneSparesPinnedAndUndoBackups(t *testing.T) {
+	profile := newTestProfile(t)
+	profile.Retention.KeepLast = 1
+	for _, name := range []string{"oldest", "pinned", "undo", "newest"} {
+		writeTestFile(t, profile.SavePath, "saved before "+name)
+		if _, err := writeBackup(profile, name, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pinned, err := findBackup(profile, "pinned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := editBackup(profile, pinned, func(m *Manifest) { m.Pinned = true }); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordRestore(profile, RestoreRecord{Time: time.Now(), Backup: "oldest", SavedAs: "undo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := pruneBackups(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Name != "oldest" {
+		t.Errorf("removed %d backup(s), want only oldest", len(removed))
+	}
+	got := backupNames(t, profile)
+	slices.Sort(got)
+	if want := []string{"newest", "pinned", "undo"}; !slices.Equal(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+}
+
+func TestPruneSparesDamagedBackups(t *testing.T) {
+	profile := newTestProfile(t)
+	profile.Retention.KeepLast = 1
+	for _, name := range []string{"oldest", "damaged", "newest"} {
+		writeTestFile(t, profile.SavePath, "saved before "+name)
+		if _, err := writeBackup(profile, name, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	damaged, err := findBackup(profile, "damaged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := editBackup(profile, damaged, func(m *Manifest) { m.Pinned = true }); err != nil {
+		t.Fatal(err)
+	}
+	// The pin is lost with the rest of the manifest.
+	if err := os.WriteFile(manifestPath(damaged.Path), []byte(`{"name": "damaged", "pinned": tr`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := pruneBackups(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Name != "oldest" {
+		t.Errorf("removed %d backup(s), want only oldest", len(removed))
+	}
+	got := backupNames(t, profile)
+	slices.Sort(got)
+	if want := []string{"damaged", "newest"}; !slices.Equal(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+}
+
+func TestPruneCollectsUnusedBlobs(t *testing.T) {
+	profile := newTestProfile(t)
+	profile.Format = string(FormatStore)
+	for _, content := range []string{"level 1", "level 2", "level 3"} {
+		writeTestFile(t, profile.SavePath, content)
+		if _, err := writeBackup(profile, "", "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blobs := func() []string {
+		t.Helper()
+		var names []string
+		err := filepath.WalkDir(filepath.Join(profile.BackupDir, blobDirName), func(p string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				names = append(names, d.Name())
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+	if got := blobs(); len(got) != 3 {
+		t.Fatalf("got %d blobs before pruning, want 3", len(got))
+	}
+
+	profile.Retention.KeepLast = 1
+	if _, err := pruneBackups(profile); err != nil {
+		t.Fatal(err)
+	}
+	sum, _, err := hashFile(profile.SavePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := blobs(); !slices.Equal(got, []string{sum}) {
+		t.Errorf("blobs after pruning: got %v, want only the kept backup's %s", got, sum)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// errEmptySave is returned when a save directory has no files to back up,
+// since a backup of nothing could never be restored.
+var errEmptySave = errors.New("the save folder has no files to back up")
+
+// matchesPattern reports whether the slash-separated relative path rel matches
+// a filter pattern. Patterns without a slash match any single path element, so
+// "*.bak" or "cache" apply at every depth; patterns with a slash match the
+// path from the save directory root, including everything below it.
+func matchesPattern(pattern, rel string) bool {
+	pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		for _, elem := range strings.Split(rel, "/") {
+			if ok, _ := path.Match(pattern, elem); ok {
+				return true
+			}
+		}
+		return false
+	}
+	for p := rel; p != "."; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether rel matches at least one of the patterns.
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchesPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeFile applies the profile's include and exclude filters to a file
+// inside a save directory. An empty include list includes everything.
+func includeFile(profile Profile, rel string) bool {
+	if matchesAny(profile.Exclude, rel) {
+		return false
+	}
+	return len(profile.Include) == 0 || matchesAny(profile.Include, rel)
+}
+
+// collectSaveFiles walks a save directory and returns the relative, slash-separated
+// paths of every regular file that passes the profile's filters.
+func collectSaveFiles(profile Profile, root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if matchesAny(profile.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && includeFile(profile, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan save directory: %w", err)
+	}
+	return files, nil
+}
+
+// copyFile copies a single file and syncs it to disk, creating parent
+// directories as needed.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copySaveTree copies the filtered contents of a save directory into dst.
+func copySaveTree(profile Profile, src, dst string) error {
+	files, err := collectSaveFiles(profile, src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	for _, rel := range files {
+		if err := copyFile(filepath.Join(src, filepath.FromSlash(rel)), filepath.Join(dst, filepath.FromSlash(rel))); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// parsePatterns splits a comma-separated list of glob patterns.
+func parsePatterns(value string) []string {
+	var patterns []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatchesPattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.bak", "slot.bak", true},
+		{"*.bak", "slots/1/slot.bak", true},
+		{"*.bak", "slots/1/slot.sav", false},
+		{"cache", "cache/shader.bin", true},
+		{"cache", "slots/cache/shader.bin", true},
+		{"cache", "slots/cached.sav", false},
+		{"slots/*.sav", "slots/1.sav", true},
+		{"slots/*.sav", "old/slots/1.sav", false},
+		{"slots/1", "slots/1/deep/map.bin", true},
+		{"/slots/1/", "slots/1/slot.sav", true},
+		{`slots\1`, "slots/1/slot.sav", filepath.Separator == '\\'},
+		{"", "slot.sav", false},
+		{"[", "slot.sav", false},
+	} {
+		if got := matchesPattern(tc.pattern, tc.rel); got != tc.want {
+			t.Errorf("matchesPattern(%q, %q) = %v, want %v", tc.pattern, tc.rel, got, tc.want)
+		}
+	}
+}
+
+func TestCollectSaveFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{
+		"profile.dat",
+		"slots/1/slot.sav",
+		"slots/1/slot.bak",
+		"slots/2/slot.sav",
+		"slots/2/deep/map.bin",
+		"cache/shader.bin",
+		"logs/today.log",
+	} {
+		writeTestFile(t, filepath.Join(root, filepath.FromSlash(rel)), rel)
+	}
+
+	for _, tc := range []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{"no filters", nil, nil, []string{"cache/shader.bin", "logs/today.log", "profile.dat", "slots/1/slot.bak", "slots/1/slot.sav", "slots/2/deep/map.bin", "slots/2/slot.sav"}},
+		{"exclude at any depth", nil, []string{"*.bak", "cache", "logs"}, []string{"profile.dat", "slots/1/slot.sav", "slots/2/deep/map.bin", "slots/2/slot.sav"}},
+		{"exclude a nested folder", nil, []string{"slots/2/deep"}, []string{"cache/shader.bin", "logs/today.log", "profile.dat", "slots/1/slot.bak", "slots/1/slot.sav", "slots/2/slot.sav"}},
+		{"include by name", []string{"*.sav"}, nil, []string{"slots/1/slot.sav", "slots/2/slot.sav"}},
+		{"include a folder", []string{"slots"}, nil, []string{"slots/1/slot.bak", "slots/1/slot.sav", "slots/2/deep/map.bin", "slots/2/slot.sav"}},
+		{"exclude beats include", []string{"slots", "profile.dat"}, []string{"*.bak", "deep"}, []string{"profile.dat", "slots/1/slot.sav", "slots/2/slot.sav"}},
+		{"nothing left", []string{"*.sav"}, []string{"slots"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := collectSaveFiles(Profile{Include: tc.include, Exclude: tc.exclude}, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
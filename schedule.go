@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/manifoldco/promptui"
+)
+
+// scheduleHelp explains the schedule syntax next to the prompts.
+const scheduleHelp = `Use an interval such as "15m" or "every 2h", "daily 03:00", a cron expression such as "*/30 18-23 * * *" (minute hour day month weekday), or @hourly, @daily, @weekly or @monthly.`
+
+// backupSchedule says when the daemon backs up a profile: every interval, or
+// whenever the cron expression matches.
+type backupSchedule struct {
+	every time.Duration
+	cron  *cronSchedule
+}
+
+// cronAliases are the shorthands accepted in place of a cron expression.
+var cronAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// parseSchedule reads a profile's schedule setting as described by
+// scheduleHelp. An empty value or "off" means no schedule.
+func parseSchedule(value string) (backupSchedule, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "" || value == "off" {
+		return backupSchedule{}, nil
+	}
+	if expr, ok := cronAliases[value]; ok {
+		value = expr
+	}
+	if rest, ok := strings.CutPrefix(value, "daily"); ok {
+		at := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "at"))
+		t, err := time.Parse("15:04", at)
+		if err != nil {
+			return backupSchedule{}, fmt.Errorf("invalid time %q in schedule (use daily HH:MM)", at)
+		}
+		value = fmt.Sprintf("%d %d * * *", t.Minute(), t.Hour())
+	}
+	if interval, ok := strings.CutPrefix(value, "every"); ok || len(strings.Fields(value)) == 1 {
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || d <= 0 {
+			return backupSchedule{}, fmt.Errorf("invalid schedule interval %q (use values like 15m or 2h)", strings.TrimSpace(interval))
+		}
+		return backupSchedule{every: d}, nil
+	}
+	c, err := parseCron(value)
+	if err != nil {
+		return backupSchedule{}, err
+	}
+	return backupSchedule{cron: c}, nil
+}
+
+// isZero reports whether the schedule is off.
+func (s backupSchedule) isZero() bool {
+	return s.every == 0 && s.cron == nil
+}
+
+// next returns the first run after t. Interval schedules count from t.
+func (s backupSchedule) next(t time.Time) (time.Time, error) {
+	if s.cron != nil {
+		return s.cron.next(t)
+	}
+	return t.Add(s.every), nil
+}
+
+// normalizeSchedule checks a schedule setting and returns the value to store,
+// which is empty when the schedule is off.
+func normalizeSchedule(value string) (string, error) {
+	s, err := parseSchedule(value)
+	if err != nil {
+		return "", err
+	}
+	if s.isZero() {
+		return "", nil
+	}
+	if _, err := s.next(time.Now()); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// promptForSchedule asks for a schedule, starting from the current one.
+func promptForSchedule(current string) (string, error) {
+	fmt.Printf("%s %s Current schedule: %s\n", iconInfo, white("INFO:"), describeSchedule(current))
+	fmt.Printf("%s %s %s\n", iconInfo, white("INFO:"), scheduleHelp)
+	prompt := promptui.Prompt{
+		Label:     white("Enter the schedule (off turns it off)"),
+		Default:   current,
+		AllowEdit: true,
+		Validate: func(input string) error {
+			_, err := normalizeSchedule(input)
+			return err
+		},
+	}
+	value, err := prompt.Run()
+	if err != nil {
+		return current, err
+	}
+	return normalizeSchedule(value)
+}
+
+// describeSchedule renders a schedule setting for display.
+func describeSchedule(value string) string {
+	s, err := parseSchedule(value)
+	switch {
+	case err != nil:
+		return err.Error()
+	case s.isZero():
+		return "off"
+	case s.every > 0:
+		return "every " + strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "every"))
+	}
+	if next, err := s.next(time.Now()); err == nil {
+		return fmt.Sprintf("%s (next %s)", strings.TrimSpace(value), next.Format("2006-01-02 15:04"))
+	}
+	return strings.TrimSpace(value)
+}
+
+// cronSchedule is a parsed five-field cron expression; each field is a bit set
+// of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // the field was *, so only the other one restricts the day
+}
+
+var (
+	cronMonths   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// parseCron parses "minute hour day-of-month month day-of-week". Fields take
+// *, numbers, names (jan, mon), ranges (1-5), lists (1,15) and steps (*/15).
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("cron day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	// Both 0 and 7 are Sunday.
+	if c.dow, err = parseCronField(fields[4], 0, 7, cronWeekdays); err != nil {
+		return nil, fmt.Errorf("cron weekday: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+// parseCronField turns one cron field into a bit set of the values between lo
+// and hi that it matches. names, if given, spell out the values from lo on.
+func parseCronField(field string, lo, hi int, names []string) (uint64, error) {
+	value := func(s string) (int, error) {
+		if i := slices.Index(names, s); i >= 0 {
+			return lo + i, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < lo || n > hi {
+			return 0, fmt.Errorf("invalid value %q (use %d-%d)", s, lo, hi)
+		}
+		return n, nil
+	}
+
+	var set uint64
+	for part := range strings.SplitSeq(field, ",") {
+		span, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+		start, end := lo, hi
+		if span != "*" {
+			from, to, isRange := strings.Cut(span, "-")
+			var err error
+			if start, err = value(from); err != nil {
+				return 0, err
+			}
+			end = start
+			if isRange {
+				if end, err = value(to); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				end = hi
+			}
+			if end < start {
+				return 0, fmt.Errorf("invalid range %q", span)
+			}
+		}
+		for v := start; v <= end; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// dayMatches applies the usual cron rule: when both the day of the month and
+// the weekday are restricted, a day matching either one runs.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first matching minute after t. Expressions that never
+// match, such as 30 February, return an error.
+func (c *cronSchedule) next(t time.Time) (time.Time, error) {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<t.Minute()) == 0:
+			// Jump straight to the next matching minute of this hour.
+			if later := c.minute >> t.Minute(); later != 0 {
+				t = t.Add(time.Duration(bits.TrailingZeros64(later)) * time.Minute)
+			} else {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			}
+		default:
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("the cron expression never matches")
+}
+
+// daemonLog prints a timestamped daemon message for one profile.
+func daemonLog(profile, icon, label, format string, args ...any) {
+	fmt.Printf("[%s] [%s] %s %s %s\n", time.Now().Format("2006-01-02 15:04:05"), profile, icon, label, fmt.Sprintf(format, args...))
+}
+
+// runDaemon backs up every profile on its schedule until ctx is done.
+func runDaemon(ctx context.Context, profiles []Profile) error {
+	schedules := make([]backupSchedule, len(profiles))
+	for i, p := range profiles {
+		s, err := parseSchedule(p.Schedule)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+		if s.cron != nil {
+			if _, err := s.next(time.Now()); err != nil {
+				return fmt.Errorf("profile %s: %w", p.Name, err)
+			}
+		}
+		schedules[i] = s
+	}
+
+	fmt.Printf("%s %s Backing up %d profile(s) on schedule. Press Ctrl+C to stop.\n", iconInfo, white("INFO:"), len(profiles))
+	var wg sync.WaitGroup
+	for i, p := range profiles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runSchedule(ctx, p, schedules[i])
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("%s %s Daemon stopped.\n", iconExit, yellow("INFO:"))
+	return nil
+}
+
+// runSchedule runs one profile's scheduled backups. Interval schedules run
+// once at start, then every interval after the previous run began.
+func runSchedule(ctx context.Context, profile Profile, s backupSchedule) {
+	next := time.Now()
+	if s.cron != nil {
+		next, _ = s.next(next)
+	}
+	for {
+		daemonLog(profile.Name, iconInfo, white("INFO:"), "Next backup at %s (%s).", next.Format("2006-01-02 15:04:05"), describeSchedule(profile.Schedule))
+		if !sleepUntil(ctx, next) {
+			return
+		}
+		started := time.Now()
+		scheduledBackup(profile)
+		var err error
+		if next, err = s.next(started); err != nil {
+			daemonLog(profile.Name, iconError, red("ERROR:"), "No further runs: %v", err)
+			return
+		}
+		// A run that took longer than the interval doesn't queue a burst.
+		if now := time.Now(); next.Before(now) {
+			next = now
+		}
+	}
+}
+
+// sleepUntil waits until the wall clock reaches t and reports false if ctx is
+// done first. It looks at the clock at least once a minute, so a run missed
+// while the machine was suspended happens soon after it wakes.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	for {
+		wait := time.Until(t)
+		if wait <= 0 {
+			return true
+		}
+		timer := time.NewTimer(min(wait, time.Minute))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// scheduledBackup makes one scheduled backup of the profile, unless the save
+// is unchanged since the latest backup, then applies retention and copies the
+// backup to the mirrors. Every outcome is logged.
+func scheduledBackup(profile Profile) {
+	log := func(icon, label, format string, args ...any) {
+		daemonLog(profile.Name, icon, label, format, args...)
+	}
+	if _, err := os.Stat(profile.SavePath); err != nil {
+		log(iconError, red("ERROR:"), "Skipped: save not found at %s.", profile.SavePath)
+		return
+	}
+	dup, err := unchangedSince(profile)
+	if err != nil {
+		log(iconError, red("ERROR:"), "Skipped: could not compare the save with the latest backup: %v", err)
+		return
+	}
+	if dup != nil {
+		log(iconInfo, white("INFO:"), "Skipped: the save is unchanged since %s.", dup.Name)
+		return
+	}
+	if err := checkGameRunning(profile, profile.GameCheck.backupPolicy(), "back up"); err != nil {
+		log(iconError, red("ERROR:"), "Skipped: %v", err)
+		return
+	}
+
+	name := fmt.Sprintf("Scheduled_%s", time.Now().Format("2006-01-02_15-04-05"))
+	backup, err := writeBackup(profile, name, "Scheduled backup", []string{"scheduled"})
+	if err != nil {
+		log(iconError, red("ERROR:"), "Failed to create backup: %v", err)
+		return
+	}
+	log(iconSuccess, green("SUCCESS:"), "Backup created: %s (%s)", backup.Name, formatSize(backup.Meta.Size))
+
+	removed, err := pruneBackups(profile)
+	if len(removed) > 0 {
+		log(iconDelete, green("INFO:"), "Retention policy removed %d old backup(s).", len(removed))
+	}
+	if err != nil {
+		log(iconError, red("ERROR:"), "Retention cleanup failed: %v", err)
+	}
+	for _, m := range profile.Mirrors {
+		status := syncMirror(profile, m, false, false)
+		switch {
+		case status.Err != nil:
+			log(iconError, red("ERROR:"), "Mirror %s failed: %v", m.Name, status.Err)
+		case len(status.Pushed) > 0:
+			log(iconSuccess, green("INFO:"), "Mirrored %d backup(s) to %s.", len(status.Pushed), m.Name)
+		}
+	}
+}
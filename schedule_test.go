@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScheduledBackupsKeepManualBackups(t *testing.T) {
+	profile := newTestProfile(t)
+	profile.Retention = Retention{
+		RetentionRules: RetentionRules{KeepLast: 1},
+		Auto:           RetentionRules{KeepLast: 2},
+	}
+	if _, err := writeBackup(profile, "before-boss", "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const runs = 5
+	for i := range runs {
+		writeTestFile(t, profile.SavePath, fmt.Sprintf("slot %d", i+2))
+		scheduledBackup(profile)
+	}
+
+	names := backupNames(t, profile)
+	if !slices.Contains(names, "before-boss") {
+		t.Fatalf("manual backup was pruned after %d scheduled runs; left: %v", runs, names)
+	}
+	scheduled := 0
+	for _, name := range names {
+		if strings.HasPrefix(name, "Scheduled_") {
+			scheduled++
+		}
+	}
+	if scheduled != 2 {
+		t.Errorf("got %d scheduled backups, want 2 (auto keep_last); left: %v", scheduled, names)
+	}
+}
+
+func TestScheduledBackupSkipsUnchangedSave(t *testing.T) {
+	profile := newTestProfile(t)
+	scheduledBackup(profile)
+	scheduledBackup(profile)
+	if names := backupNames(t, profile); len(names) != 1 {
+		t.Errorf("got backups %v, want a single one for an unchanged save", names)
+	}
+}
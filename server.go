@@ -0,0 +1,427 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultServeAddr keeps the API on this machine unless --listen says
+// otherwise.
+const defaultServeAddr = "127.0.0.1:8765"
+
+// maxImportSize caps the export archives the API accepts, so a bad or hostile
+// request can't fill the disk.
+const maxImportSize = 1 << 30
+
+// tokenEnv can hold the API token, so it stays the same across restarts.
+const tokenEnv = "BACKUP_MANAGER_TOKEN"
+
+//go:embed web
+var webFiles embed.FS
+
+// apiServer serves the REST API and the web UI for one profile. API requests
+// are handled one at a time, since they touch the same save and backup
+// directory; only receiving an upload runs alongside them.
+type apiServer struct {
+	game  string // profile name; "" follows the active profile
+	token string
+	mu    sync.Mutex
+	mux   *http.ServeMux
+}
+
+// newAPIServer builds the handler. The profile is loaded afresh for every
+// request, so changes made from the menu or the CLI are picked up.
+func newAPIServer(game, token string) *apiServer {
+	s := &apiServer{game: game, token: token, mux: http.NewServeMux()}
+	web, _ := fs.Sub(webFiles, "web")
+	s.mux.Handle("GET /", http.FileServerFS(web))
+	s.mux.HandleFunc("GET /api/status", s.locked(s.handleStatus))
+	s.mux.HandleFunc("GET /api/backups", s.locked(s.handleList))
+	s.mux.HandleFunc("POST /api/backups", s.locked(s.handleCreate))
+	s.mux.HandleFunc("POST /api/backups/import", s.handleImport) // locks once the upload is in
+	s.mux.HandleFunc("GET /api/backups/{name}", s.locked(s.handleShow))
+	s.mux.HandleFunc("DELETE /api/backups/{name}", s.locked(s.handleDelete))
+	s.mux.HandleFunc("POST /api/backups/{name}/restore", s.locked(s.handleRestore))
+	s.mux.HandleFunc("GET /api/backups/{name}/download", s.locked(s.handleDownload))
+	return s
+}
+
+// locked runs handler while holding the server's lock.
+func (s *apiServer) locked(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		handler(w, r)
+	}
+}
+
+// ServeHTTP checks the token on API requests and logs every request.
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	if strings.HasPrefix(r.URL.Path, "/api/") && !s.authorized(r) {
+		writeAPIError(rec, http.StatusUnauthorized, errors.New("missing or wrong token"))
+	} else {
+		s.mux.ServeHTTP(rec, r)
+	}
+	fmt.Printf("%s %s %s %s %s -> %d\n", iconInfo, white("INFO:"), time.Now().Format("15:04:05"), r.Method, r.URL.Path, rec.status)
+}
+
+// authorized reports whether the request carries the token as a bearer token.
+func (s *apiServer) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *apiServer) profile() (Profile, error) {
+	_, _, profile, err := loadCLIProfile(s.game)
+	if err != nil {
+		return Profile{}, err
+	}
+	return *profile, nil
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// apiErrorStatus picks the HTTP status for an operation's error.
+func apiErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBackupNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errGameRunning), errors.Is(err, errBackupDamaged), errors.Is(err, errAutoBackupFailed), errors.Is(err, errPinned), errors.Is(err, errDuplicateSave), errors.Is(err, errEmptySave):
+		return http.StatusConflict
+	case errors.Is(err, errUsage):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// errDuplicateSave is returned by the API when a backup would only repeat
+// the latest one.
+var errDuplicateSave = errors.New("the save hasn't changed since the latest backup")
+
+// decodeBody reads an optional JSON request body into v.
+func decodeBody(r *http.Request, v any) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", errUsage, err)
+}
+
+// serverPolicy adapts a running game policy for requests, which can't wait
+// for the game to exit.
+func serverPolicy(policy GamePolicy) GamePolicy {
+	if policy == GamePolicyWait {
+		return GamePolicyBlock
+	}
+	return policy
+}
+
+// findRequested loads the profile and the backup named in the request path.
+func (s *apiServer) findRequested(r *http.Request) (Profile, Backup, error) {
+	profile, err := s.profile()
+	if err != nil {
+		return profile, Backup{}, err
+	}
+	backup, err := findBackup(profile, r.PathValue("name"))
+	return profile, backup, err
+}
+
+func (s *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	profile, err := s.profile()
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	report, err := profileStatus(profile)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, report)
+}
+
+func (s *apiServer) handleList(w http.ResponseWriter, r *http.Request) {
+	profile, err := s.profile()
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	backups, err := listBackupsInternal(profile)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	records := make([]BackupRecord, len(backups))
+	for i, b := range backups {
+		records[i] = newBackupRecord(profile, b)
+	}
+	writeAPIJSON(w, http.StatusOK, records)
+}
+
+func (s *apiServer) handleShow(w http.ResponseWriter, r *http.Request) {
+	profile, backup, err := s.findRequested(r)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, newBackupRecord(profile, backup))
+}
+
+// createRequest is the body of POST /api/backups. Every field is optional.
+type createRequest struct {
+	Name           string   `json:"name"`
+	Note           string   `json:"note"`
+	Tags           []string `json:"tags"`
+	AllowDuplicate bool     `json:"allow_duplicate"`
+	IgnoreGame     bool     `json:"ignore_game"`
+}
+
+func (s *apiServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	profile, err := s.profile()
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	if req.Name = strings.TrimSpace(req.Name); req.Name != "" {
+		if err := validateBackupName(req.Name); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if _, err := os.Stat(profile.SavePath); err != nil {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("save file not found at: %s", profile.SavePath))
+		return
+	}
+	if !req.AllowDuplicate && duplicatePolicy(profile) != DuplicatesAllow {
+		if dup, err := unchangedSince(profile); err == nil && dup != nil {
+			writeAPIError(w, http.StatusConflict, fmt.Errorf("%w, %s", errDuplicateSave, dup.Name))
+			return
+		}
+	}
+	if !req.IgnoreGame {
+		if err := checkGameRunning(profile, serverPolicy(profile.GameCheck.backupPolicy()), "back up"); err != nil {
+			writeAPIError(w, apiErrorStatus(err), err)
+			return
+		}
+	}
+
+	backup, err := writeBackup(profile, req.Name, strings.TrimSpace(req.Note), parseTags(strings.Join(req.Tags, ",")))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), fmt.Errorf("failed to create backup: %w", err))
+		return
+	}
+	reportAutoPrune(profile)
+	reportMirrors(profile)
+	writeAPIJSON(w, http.StatusCreated, newBackupRecord(profile, backup))
+}
+
+// restoreRequest is the body of POST /api/backups/{name}/restore.
+type restoreRequest struct {
+	Force      bool `json:"force"`       // restore even if verification fails
+	IgnoreGame bool `json:"ignore_game"` // skip the running game check
+}
+
+// restoreResponse reports a finished restore.
+type restoreResponse struct {
+	Restored   string `json:"restored"`
+	AutoBackup string `json:"auto_backup,omitempty"`
+}
+
+func (s *apiServer) handleRestore(w http.ResponseWriter, r *http.Request) {
+	var req restoreRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	profile, backup, err := s.findRequested(r)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	if !req.IgnoreGame {
+		if err := checkGameRunning(profile, serverPolicy(profile.GameCheck.restorePolicy()), "restore"); err != nil {
+			writeAPIError(w, apiErrorStatus(err), err)
+			return
+		}
+	}
+	autoBackupName, err := applyBackup(profile, backup, req.Force)
+	if autoBackupName != "" {
+		reportAutoPrune(profile)
+		reportMirrors(profile)
+	}
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), fmt.Errorf("failed to restore backup: %w", err))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, restoreResponse{Restored: backup.Name, AutoBackup: autoBackupName})
+}
+
+func (s *apiServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	profile, backup, err := s.findRequested(r)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	if isPinned(backup) {
+		writeAPIError(w, http.StatusConflict, errPinned)
+		return
+	}
+	if err := removeBackup(backup); err != nil {
+		writeAPIError(w, apiErrorStatus(err), fmt.Errorf("failed to delete %s: %w", backup.Name, err))
+		return
+	}
+	reportGarbage(profile)
+	reportMirrorRemoval(profile, []Backup{backup})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDownload sends a backup as an export archive, which holds its
+// metadata and works for every format, so it can be uploaded again as is.
+func (s *apiServer) handleDownload(w http.ResponseWriter, r *http.Request) {
+	profile, backup, err := s.findRequested(r)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	dir, err := scratchDir("download")
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("failed to prepare download: %w", err))
+		return
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, backup.Name+".zip")
+	if err := exportBackups(profile, []Backup{backup}, archive); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", backup.Name+".zip"))
+	http.ServeContent(w, r, backup.Name+".zip", time.Time{}, f)
+}
+
+// handleImport adds the backups of an export archive sent as the request body.
+// The upload can be large and slow, so the body is received before taking the
+// lock, and other requests are served meanwhile.
+func (s *apiServer) handleImport(w http.ResponseWriter, r *http.Request) {
+	// The archive is staged in the system temp folder, away from the backups.
+	dir, err := scratchDir("import")
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("failed to prepare upload: %w", err))
+		return
+	}
+	defer os.RemoveAll(dir)
+	f, err := os.Create(filepath.Join(dir, "upload.zip"))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("failed to prepare upload: %w", err))
+		return
+	}
+	_, err = io.Copy(f, http.MaxBytesReader(w, r.Body, maxImportSize))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the upload is larger than %s", formatSize(tooLarge.Limit)))
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("failed to receive upload: %w", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, err := s.profile()
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	result, err := importBackups(profile, f.Name())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(result.Imported) > 0 {
+		reportMirrors(profile)
+	}
+	imported := make([]BackupRecord, len(result.Imported))
+	for i, b := range result.Imported {
+		imported[i] = newBackupRecord(profile, b)
+	}
+	skipped := result.Skipped
+	if skipped == nil {
+		skipped = []string{}
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]any{"imported": imported, "skipped": skipped})
+}
+
+// serveToken returns the token from $BACKUP_MANAGER_TOKEN or a new random one.
+func serveToken() (string, error) {
+	if t := os.Getenv(tokenEnv); t != "" {
+		return t, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isLoopback reports whether a listen address only accepts local connections.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
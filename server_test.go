@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "s3cret"
+
+// newTestServer writes a config holding only profile where the program looks
+// for it, and serves the API for it.
+func newTestServer(t *testing.T, profile Profile) *httptest.Server {
+	t.Helper()
+	writeTestConfig(t, profile)
+	srv := httptest.NewServer(newAPIServer("", testToken))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// apiRequest sends a request with the test token and decodes a JSON response
+// into v, if given. It returns the status code.
+func apiRequest(t *testing.T, srv *httptest.Server, method, path string, body io.Reader, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServerAuth(t *testing.T) {
+	srv := newTestServer(t, newTestProfile(t))
+
+	for _, tc := range []struct {
+		name, header string
+		want         int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "Bearer nope", http.StatusUnauthorized},
+		{"not bearer", testToken, http.StatusUnauthorized},
+		{"correct", "Bearer " + testToken, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/backups", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.want {
+				t.Errorf("got %d, want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+}
+
+func TestServerBackups(t *testing.T) {
+	profile := newTestProfile(t)
+	srv := newTestServer(t, profile)
+
+	var created BackupRecord
+	if status := apiRequest(t, srv, http.MethodPost, "/api/backups", strings.NewReader(`{"name": "first", "note": "boss"}`), &created); status != http.StatusCreated {
+		t.Fatalf("create: got %d, want %d", status, http.StatusCreated)
+	}
+	if created.Name != "first" || created.Note != "boss" {
+		t.Errorf("create: got %+v, want first with its note", created)
+	}
+	if status := apiRequest(t, srv, http.MethodPost, "/api/backups", nil, nil); status != http.StatusConflict {
+		t.Errorf("create of an unchanged save: got %d, want %d", status, http.StatusConflict)
+	}
+
+	writeTestFile(t, profile.SavePath, "slot 2")
+	if status := apiRequest(t, srv, http.MethodPost, "/api/backups", strings.NewReader(`{"name": "second"}`), nil); status != http.StatusCreated {
+		t.Fatalf("create: got %d, want %d", status, http.StatusCreated)
+	}
+
+	var list []BackupRecord
+	if status := apiRequest(t, srv, http.MethodGet, "/api/backups", nil, &list); status != http.StatusOK {
+		t.Fatalf("list: got %d, want %d", status, http.StatusOK)
+	}
+	if len(list) != 2 || list[0].Name != "second" || list[1].Name != "first" {
+		t.Errorf("list: got %+v, want second and first, newest first", list)
+	}
+	if status := apiRequest(t, srv, http.MethodGet, "/api/backups/missing", nil, nil); status != http.StatusNotFound {
+		t.Errorf("show of a missing backup: got %d, want %d", status, http.StatusNotFound)
+	}
+
+	var restored restoreResponse
+	if status := apiRequest(t, srv, http.MethodPost, "/api/backups/first/restore", nil, &restored); status != http.StatusOK {
+		t.Fatalf("restore: got %d, want %d", status, http.StatusOK)
+	}
+	if restored.Restored != "first" {
+		t.Errorf("restore: got %+v, want first", restored)
+	}
+	if got := readTestFile(t, profile.SavePath); got != "slot 1" {
+		t.Errorf("restore left %q in the save, want %q", got, "slot 1")
+	}
+
+	first, err := findBackup(profile, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := editBackup(profile, first, func(m *Manifest) { m.Pinned = true }); err != nil {
+		t.Fatal(err)
+	}
+	if status := apiRequest(t, srv, http.MethodDelete, "/api/backups/first", nil, nil); status != http.StatusConflict {
+		t.Errorf("delete of a pinned backup: got %d, want %d", status, http.StatusConflict)
+	}
+	if status := apiRequest(t, srv, http.MethodDelete, "/api/backups/second", nil, nil); status != http.StatusNoContent {
+		t.Errorf("delete: got %d, want %d", status, http.StatusNoContent)
+	}
+	if got := backupNames(t, profile); len(got) != 1 || got[0] != "first" {
+		t.Errorf("after deletes: got %v, want only the pinned backup", got)
+	}
+}
+
+func TestServerImport(t *testing.T) {
+	source := newTestProfile(t)
+	backup, err := writeBackup(source, "shared", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "shared.zip")
+	if err := exportBackups(source, []Backup{backup}, archive); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := newTestProfile(t)
+	srv := newTestServer(t, profile)
+	upload := func() (int, map[string]json.RawMessage) {
+		f, err := os.Open(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var result map[string]json.RawMessage
+		status := apiRequest(t, srv, http.MethodPost, "/api/backups/import", f, &result)
+		return status, result
+	}
+
+	status, result := upload()
+	if status != http.StatusOK {
+		t.Fatalf("import: got %d, want %d", status, http.StatusOK)
+	}
+	var imported []BackupRecord
+	if err := json.Unmarshal(result["imported"], &imported); err != nil || len(imported) != 1 || imported[0].Name != "shared" {
+		t.Errorf("import: got %s, want shared imported", result["imported"])
+	}
+	if got := backupNames(t, profile); len(got) != 1 || got[0] != "shared" {
+		t.Errorf("after import: got %v, want only the imported backup", got)
+	}
+
+	status, result = upload()
+	var skipped []string
+	if err := json.Unmarshal(result["skipped"], &skipped); status != http.StatusOK || err != nil || len(skipped) != 1 {
+		t.Errorf("second import: got %d, %s; want the taken name skipped", status, result["skipped"])
+	}
+
+	if status := apiRequest(t, srv, http.MethodPost, "/api/backups/import", strings.NewReader("not a zip"), nil); status != http.StatusBadRequest {
+		t.Errorf("import of a non-archive: got %d, want %d", status, http.StatusBadRequest)
+	}
+	entries, err := os.ReadDir(profile.BackupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".upload") {
+			t.Errorf("upload staged in the backup directory: %s", e.Name())
+		}
+	}
+}
+
+func TestServerServesDuringUpload(t *testing.T) {
+	source := newTestProfile(t)
+	backup, err := writeBackup(source, "shared", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "shared.zip")
+	if err := exportBackups(source, []Backup{backup}, archive); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := newTestProfile(t)
+	srv := newTestServer(t, profile)
+	body, upload := io.Pipe()
+	defer upload.Close() // ends the upload if the test fails early
+	imported := make(chan int, 1)
+	go func() {
+		// apiRequest can't be used off the test goroutine, since it may call t.Fatal.
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/backups/import", body)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			imported <- 0
+			return
+		}
+		resp.Body.Close()
+		imported <- resp.StatusCode
+	}()
+	if _, err := upload.Write(data[:len(data)/2]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Half the archive is in; other requests still get through.
+	listed := make(chan int, 1)
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/backups", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			listed <- 0
+			return
+		}
+		resp.Body.Close()
+		listed <- resp.StatusCode
+	}()
+	select {
+	case status := <-listed:
+		if status != http.StatusOK {
+			t.Errorf("list during the upload: got %d, want %d", status, http.StatusOK)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("list waited for the upload to finish")
+	}
+
+	upload.Write(data[len(data)/2:])
+	upload.Close()
+	if status := <-imported; status != http.StatusOK {
+		t.Fatalf("import: got %d, want %d", status, http.StatusOK)
+	}
+	if got := backupNames(t, profile); len(got) != 1 || got[0] != "shared" {
+		t.Errorf("after import: got %v, want only the imported backup", got)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// defaultStableFor is how long the save must go unmodified before it is copied.
+	defaultStableFor = time.Second
+	// captureAttempts is how many times a copy is retried when the save
+	// changes while it is being read.
+	captureAttempts = 3
+)
+
+// The timings of the wait for a settled save. Tests shorten them.
+var (
+	// stabilityPoll is the longest pause between two looks at the save.
+	stabilityPoll = 250 * time.Millisecond
+	// stabilityTimeout caps how long a backup waits for the save to settle.
+	stabilityTimeout = 30 * time.Second
+)
+
+var (
+	errSaveUnstable = errors.New("the save kept changing; is the game still writing it?")
+	errSaveChanged  = errors.New("the save changed while it was being copied")
+)
+
+// saveSnapshot fingerprints the names, sizes and modification times of the
+// save's files and returns the newest modification time among them.
+func saveSnapshot(profile Profile) (uint64, time.Time, error) {
+	h := fnv.New64a()
+	info, err := os.Stat(profile.SavePath)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if !info.IsDir() {
+		fmt.Fprintf(h, "%d|%d", info.Size(), info.ModTime().UnixNano())
+		return h.Sum64(), info.ModTime(), nil
+	}
+
+	files, err := collectSaveFiles(profile, profile.SavePath)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	newest := info.ModTime()
+	for _, rel := range files {
+		fi, err := os.Stat(filepath.Join(profile.SavePath, filepath.FromSlash(rel)))
+		if err != nil {
+			// A file vanishing mid-scan is itself a change.
+			fmt.Fprintf(h, "%s|gone\n", rel)
+			newest = time.Now()
+			continue
+		}
+		fmt.Fprintf(h, "%s|%d|%d\n", rel, fi.Size(), fi.ModTime().UnixNano())
+		if fi.ModTime().After(newest) {
+			newest = fi.ModTime()
+		}
+	}
+	return h.Sum64(), newest, nil
+}
+
+// stableFor returns the profile's stability window.
+func stableFor(profile Profile) (time.Duration, error) {
+	d, err := parseWatchDuration(profile.StableFor, defaultStableFor)
+	if err != nil {
+		return 0, fmt.Errorf("stable_for: %w", err)
+	}
+	return d, nil
+}
+
+// describeStableFor renders the profile's stability window for display.
+func describeStableFor(profile Profile) string {
+	window, err := stableFor(profile)
+	if err != nil {
+		return err.Error()
+	}
+	if window == 0 {
+		return "off"
+	}
+	return window.String()
+}
+
+// waitForStableSave blocks until the save's sizes and modification times have
+// not changed for window and returns the fingerprint it settled on, and how
+// long it waited. A save that was last written longer than window ago is
+// stable immediately.
+func waitForStableSave(profile Profile, window time.Duration) (uint64, time.Duration, error) {
+	fp, newest, err := saveSnapshot(profile)
+	if err != nil || window <= 0 {
+		return fp, 0, err
+	}
+
+	poll := min(window/4, stabilityPoll)
+	start := time.Now()
+	deadline := start.Add(max(stabilityTimeout, 3*window))
+	quietSince := start
+	for {
+		if time.Since(newest) >= window || time.Since(quietSince) >= window {
+			return fp, time.Since(start), nil
+		}
+		if time.Now().After(deadline) {
+			return 0, time.Since(start), errSaveUnstable
+		}
+		time.Sleep(poll)
+
+		current, currentNewest, err := saveSnapshot(profile)
+		if err != nil {
+			return 0, time.Since(start), err
+		}
+		if current != fp {
+			fp, newest, quietSince = current, currentNewest, time.Now()
+		}
+	}
+}
+
+// captureStableSave runs capture once the save has settled and checks that the
+// save was not modified while it ran. A copy that raced with the game is
+// discarded with cleanup and retried before giving up with errSaveChanged.
+// It returns how long it waited for the save to settle in total.
+func captureStableSave(profile Profile, capture func() error, cleanup func()) (time.Duration, error) {
+	window, err := stableFor(profile)
+	if err != nil {
+		return 0, err
+	}
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		before, w, err := waitForStableSave(profile, window)
+		waited += w
+		if err != nil {
+			return waited, err
+		}
+		if err := capture(); err != nil {
+			cleanup()
+			return waited, err
+		}
+		after, _, err := saveSnapshot(profile)
+		if err == nil && after == before {
+			return waited, nil
+		}
+		cleanup()
+		if attempt == captureAttempts {
+			return waited, fmt.Errorf("%w (tried %d times)", errSaveChanged, captureAttempts)
+		}
+	}
+}
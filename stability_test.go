@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// shortenStability makes the waits for a settled save quick for the test.
+func shortenStability(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	poll, limit := stabilityPoll, stabilityTimeout
+	stabilityPoll, stabilityTimeout = 5*time.Millisecond, timeout
+	t.Cleanup(func() { stabilityPoll, stabilityTimeout = poll, limit })
+}
+
+// keepWriting rewrites the save every interval until the returned stop is
+// called, which waits for the last write.
+func keepWriting(t *testing.T, profile Profile, interval time.Duration) (stop func()) {
+	t.Helper()
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(interval):
+				writeTestFile(t, profile.SavePath, fmt.Sprintf("autosave %d", i))
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func TestWaitForStableSave(t *testing.T) {
+	shortenStability(t, time.Second)
+	const window = 100 * time.Millisecond
+
+	t.Run("already settled", func(t *testing.T) {
+		profile := newTestProfile(t)
+		time.Sleep(window)
+		fp, waited, err := waitForStableSave(profile, window)
+		if err != nil || waited > window/2 {
+			t.Fatalf("got waited %s, %v; want no wait", waited, err)
+		}
+		if fp != saveFingerprint(profile) {
+			t.Error("returned a fingerprint other than the save's")
+		}
+	})
+
+	t.Run("settles", func(t *testing.T) {
+		profile := newTestProfile(t)
+		stop := keepWriting(t, profile, 20*time.Millisecond)
+		time.AfterFunc(300*time.Millisecond, stop)
+
+		fp, waited, err := waitForStableSave(profile, window)
+		returned := time.Now()
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(profile.SavePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if quiet := returned.Sub(info.ModTime()); quiet < window || waited < 200*time.Millisecond {
+			t.Errorf("returned %s after the last write, having waited %s; want once the writes stopped and the window passed", quiet, waited)
+		}
+		if fp != saveFingerprint(profile) {
+			t.Error("returned a fingerprint other than the settled save's")
+		}
+	})
+
+	t.Run("never settles", func(t *testing.T) {
+		shortenStability(t, 300*time.Millisecond)
+		profile := newTestProfile(t)
+		stop := keepWriting(t, profile, 20*time.Millisecond)
+		defer stop()
+		if _, _, err := waitForStableSave(profile, window); !errors.Is(err, errSaveUnstable) {
+			t.Errorf("got %v, want errSaveUnstable", err)
+		}
+	})
+}
+
+func TestCaptureStableSaveRetries(t *testing.T) {
+	shortenStability(t, time.Second)
+
+	for _, tc := range []struct {
+		name        string
+		racedCopies int // how many copies the game writes during
+		wantErr     error
+	}{
+		{"quiet", 0, nil},
+		{"one raced copy", 1, nil},
+		{"every copy raced", captureAttempts, errSaveChanged},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			profile := newTestProfile(t)
+			profile.StableFor = "50ms"
+			captures, cleanups := 0, 0
+			capture := func() error {
+				captures++
+				if captures <= tc.racedCopies {
+					writeTestFile(t, profile.SavePath, fmt.Sprintf("written during copy %d", captures))
+				}
+				return nil
+			}
+			_, err := captureStableSave(profile, capture, func() { cleanups++ })
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got %v, want %v", err, tc.wantErr)
+			}
+			wantCaptures := min(tc.racedCopies+1, captureAttempts)
+			if captures != wantCaptures || cleanups != tc.racedCopies {
+				t.Errorf("got %d copies and %d cleanups, want %d and %d", captures, cleanups, wantCaptures, tc.racedCopies)
+			}
+		})
+	}
+
+	t.Run("failed copy", func(t *testing.T) {
+		profile := newTestProfile(t)
+		failed := errors.New("disk full")
+		cleanups := 0
+		if _, err := captureStableSave(profile, func() error { return failed }, func() { cleanups++ }); !errors.Is(err, failed) || cleanups != 1 {
+			t.Errorf("got %v with %d cleanups, want the copy's error after one cleanup", err, cleanups)
+		}
+	})
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/manifoldco/promptui"
+)
+
+// Storage is where a profile's backups are kept. Names are slash-separated
+// paths relative to the storage root, such as "Backup_1.zip" or
+// "Backup_2/slot1.sav" for a file inside a folder backup.
+type Storage interface {
+	// Put stores the content of r under name, replacing any existing file.
+	Put(name string, r io.Reader) error
+	// Get opens a stored file.
+	Get(name string) (io.ReadCloser, error)
+	// List returns the direct children of dir; "" lists the root.
+	List(dir string) ([]StorageEntry, error)
+	// Delete removes a file, or a folder and everything in it. Deleting
+	// something that doesn't exist is not an error.
+	Delete(name string) error
+	// Stat describes a file or folder. Missing names return an error
+	// matching os.ErrNotExist.
+	Stat(name string) (StorageEntry, error)
+	// String describes the location for display.
+	String() string
+}
+
+// renamer is implemented by storages that can move a file or folder to a new
+// name without copying it.
+type renamer interface {
+	Rename(from, to string) error
+}
+
+// StorageEntry describes one stored file or folder.
+type StorageEntry struct {
+	Name    string // base name
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// Storage types a profile can select.
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+	StorageSFTP  = "sftp"
+)
+
+var storageTypes = []string{StorageLocal, StorageS3, StorageSFTP}
+
+// StorageSettings select and configure a profile's backend. The local backend
+// uses the profile's backup_dir; remote backends only keep the restore
+// history there.
+type StorageSettings struct {
+	Type string `json:"type,omitempty"` // local (default), s3 or sftp
+	Path string `json:"path,omitempty"` // key prefix in the bucket, or folder on the SFTP server
+
+	// S3-compatible services such as AWS S3 or MinIO. Credentials fall back to
+	// the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.
+	Endpoint  string `json:"endpoint,omitempty"` // host[:port]
+	Bucket    string `json:"bucket,omitempty"`
+	Region    string `json:"region,omitempty"`
+	AccessKey string `json:"access_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+	Insecure  bool   `json:"insecure,omitempty"` // use plain HTTP
+
+	// SFTP servers. Without a password or key file the SSH agent is used; the
+	// password can also come from BACKUP_MANAGER_SFTP_PASSWORD.
+	Host       string `json:"host,omitempty"` // host[:port]
+	User       string `json:"user,omitempty"`
+	Password   string `json:"password,omitempty"`
+	KeyFile    string `json:"key_file,omitempty"`    // private key
+	KnownHosts string `json:"known_hosts,omitempty"` // defaults to ~/.ssh/known_hosts
+}
+
+// storageField maps a config key such as storage_bucket to its setting.
+func storageField(s *StorageSettings, key string) (*string, bool) {
+	switch key {
+	case "storage_path":
+		return &s.Path, true
+	case "storage_endpoint":
+		return &s.Endpoint, true
+	case "storage_bucket":
+		return &s.Bucket, true
+	case "storage_region":
+		return &s.Region, true
+	case "storage_access_key":
+		return &s.AccessKey, true
+	case "storage_secret_key":
+		return &s.SecretKey, true
+	case "storage_host":
+		return &s.Host, true
+	case "storage_user":
+		return &s.User, true
+	case "storage_password":
+		return &s.Password, true
+	case "storage_key_file":
+		return &s.KeyFile, true
+	case "storage_known_hosts":
+		return &s.KnownHosts, true
+	}
+	return nil, false
+}
+
+// parseStorageType validates a storage type. An empty string means local.
+func parseStorageType(value string) (string, error) {
+	if value == "" {
+		return StorageLocal, nil
+	}
+	for _, t := range storageTypes {
+		if strings.EqualFold(value, t) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown storage type %q (use local, s3 or sftp)", value)
+}
+
+// describeStorage renders a profile's storage location for display.
+func describeStorage(profile Profile) string {
+	return describeStorageAt(profile.Storage, profile.BackupDir)
+}
+
+// describeStorageAt renders storage settings for display; localDir is the
+// folder used when the type is local.
+func describeStorageAt(s StorageSettings, localDir string) string {
+	switch s.Type {
+	case StorageS3:
+		return fmt.Sprintf("s3://%s/%s (%s)", s.Bucket, strings.Trim(s.Path, "/"), s.Endpoint)
+	case StorageSFTP:
+		return fmt.Sprintf("sftp://%s@%s/%s", s.User, s.Host, strings.TrimPrefix(s.Path, "/"))
+	}
+	return "local " + localDir
+}
+
+// promptForStorage chooses the storage type and asks for the settings it needs.
+// Pressing Enter keeps the current value of a setting.
+func promptForStorage(settings *StorageSettings) error {
+	prompt := promptui.Select{
+		Label: white("Where should backups be kept?"),
+		Items: []string{
+			"local - the backup directory on this machine",
+			"s3 - an S3-compatible bucket (AWS S3, MinIO, ...)",
+			"sftp - a folder on an SFTP server",
+		},
+	}
+	index, _, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	next := *settings
+	next.Type = storageTypes[index]
+
+	var fields []struct {
+		label string
+		value *string
+	}
+	add := func(label string, value *string) {
+		fields = append(fields, struct {
+			label string
+			value *string
+		}{label, value})
+	}
+	switch next.Type {
+	case StorageLocal:
+		*settings = StorageSettings{}
+		return nil
+	case StorageS3:
+		add("Endpoint (host[:port])", &next.Endpoint)
+		add("Bucket", &next.Bucket)
+		add("Region (optional)", &next.Region)
+		add("Key prefix (optional)", &next.Path)
+		add("Access key (empty uses $AWS_ACCESS_KEY_ID)", &next.AccessKey)
+	case StorageSFTP:
+		add("Host (host[:port])", &next.Host)
+		add("User", &next.User)
+		add("Remote folder", &next.Path)
+		add("Private key file (optional)", &next.KeyFile)
+		add("Known hosts file (empty uses ~/.ssh/known_hosts)", &next.KnownHosts)
+	}
+	for _, field := range fields {
+		label := field.label
+		if *field.value != "" {
+			label += " [" + *field.value + "]"
+		}
+		value, err := promptForInput(label)
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			*field.value = value
+		}
+	}
+	if next.Type == StorageS3 && next.AccessKey != "" && (next.SecretKey == "" || next.AccessKey != settings.AccessKey) {
+		secret, err := promptForPassphrase("Secret key")
+		if err != nil {
+			return err
+		}
+		next.SecretKey = secret
+	}
+	if next.Type == StorageS3 && (next.Endpoint == "" || next.Bucket == "") {
+		return errors.New("s3 storage needs an endpoint and a bucket")
+	}
+	if next.Type == StorageSFTP && (next.Host == "" || next.User == "") {
+		return errors.New("sftp storage needs a host and a user")
+	}
+	if next.Type == StorageSFTP {
+		fmt.Printf("%s %s Without a key file the SSH agent is used; a password can be set with $%s.\n", iconInfo, white("INFO:"), sftpPasswordEnv)
+	}
+	*settings = next
+	return nil
+}
+
+// openStorages keeps backends open for the life of the process, so remote
+// connections are made once per run rather than once per operation. Storage
+// may be opened from several goroutines; the lock is held while connecting,
+// so each backend is dialled once.
+var (
+	openStoragesMu sync.Mutex
+	openStorages   = map[StorageSettings]Storage{}
+)
+
+// openStorage returns the backend selected by the profile.
+func openStorage(profile Profile) (Storage, error) {
+	if profile.Storage.Type == "" || profile.Storage.Type == StorageLocal {
+		return localStorage{root: profile.BackupDir}, nil
+	}
+	return openRemoteStorage(profile.Storage)
+}
+
+// openRemoteStorage connects to an S3 or SFTP backend, reusing an open one.
+func openRemoteStorage(settings StorageSettings) (Storage, error) {
+	openStoragesMu.Lock()
+	defer openStoragesMu.Unlock()
+	if st, ok := openStorages[settings]; ok {
+		return st, nil
+	}
+
+	var st Storage
+	var err error
+	switch settings.Type {
+	case StorageS3:
+		st, err = newS3Storage(settings)
+	case StorageSFTP:
+		st, err = newSFTPStorage(settings)
+	default:
+		_, err = parseStorageType(settings.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open backup storage: %w", err)
+	}
+	openStorages[settings] = st
+	return st, nil
+}
+
+// localStorage keeps backups in a directory on this machine.
+type localStorage struct {
+	root string
+}
+
+func (s localStorage) path(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+// Put writes to a temp file and renames it into place, so readers never see a
+// partial file.
+func (s localStorage) Put(name string, r io.Reader) error {
+	target := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (s localStorage) Get(name string) (io.ReadCloser, error) {
+	return os.Open(s.path(name))
+}
+
+func (s localStorage) List(dir string) ([]StorageEntry, error) {
+	entries, err := os.ReadDir(s.path(dir))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]StorageEntry, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed while listing
+		}
+		result = append(result, StorageEntry{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime(), IsDir: entry.IsDir()})
+	}
+	return result, nil
+}
+
+func (s localStorage) Delete(name string) error {
+	return os.RemoveAll(s.path(name))
+}
+
+func (s localStorage) Stat(name string) (StorageEntry, error) {
+	info, err := os.Stat(s.path(name))
+	if err != nil {
+		return StorageEntry{}, err
+	}
+	return StorageEntry{Name: info.Name(), Size: info.Size(), ModTime: info.ModTime(), IsDir: info.IsDir()}, nil
+}
+
+func (s localStorage) Rename(from, to string) error {
+	return os.Rename(s.path(from), s.path(to))
+}
+
+func (s localStorage) String() string { return s.root }
+
+// localPath returns where a stored name lives on disk when the storage is
+// local, so callers can skip copying.
+func localPath(st Storage, name string) (string, bool) {
+	if ls, ok := st.(localStorage); ok {
+		return ls.path(name), true
+	}
+	return "", false
+}
+
+// putFile uploads a local file.
+func putFile(st Storage, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return st.Put(name, f)
+}
+
+// putTree uploads every file below the local folder src under name.
+func putTree(st Storage, name, src string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		return putFile(st, path.Join(name, filepath.ToSlash(rel)), p)
+	})
+}
+
+// getFile downloads a stored file to the local path dst.
+func getFile(st Storage, name, dst string) error {
+	r, err := st.Get(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return writeExtractedFile(dst, r)
+}
+
+// getTree downloads a stored folder into the local folder dst.
+func getTree(st Storage, name, dst string) error {
+	entries, err := st.List(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		child := path.Join(name, entry.Name)
+		target := filepath.Join(dst, entry.Name)
+		if entry.IsDir {
+			err = getTree(st, child, target)
+		} else {
+			err = getFile(st, child, target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchBackup returns a local path holding the backup's data. Local backups
+// are used in place; remote ones are downloaded below scratch, a folder the
+// caller removes when done.
+func fetchBackup(b Backup, scratch string) (string, error) {
+	if p, ok := localPath(b.storage, b.Key); ok {
+		return p, nil
+	}
+	entry, err := b.storage.Stat(b.Key)
+	if err != nil {
+		return "", err
+	}
+	dst := filepath.Join(scratch, path.Base(b.Key))
+	if entry.IsDir {
+		err = getTree(b.storage, b.Key, dst)
+	} else {
+		err = getFile(b.storage, b.Key, dst)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", b.Name, err)
+	}
+	return dst, nil
+}
+
+// storageExists reports whether name exists in the storage. Failing to find
+// out, e.g. because a remote is unreachable, is an error rather than an
+// answer, so callers never skip an upload or take a name on a guess.
+func storageExists(st Storage, name string) (bool, error) {
+	_, err := st.Stat(name)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, os.ErrNotExist):
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check for %s: %w", name, err)
+}
+
+// sizedReader tells Put how much data to expect, so backends that want the
+// length up front can upload in a single request.
+type sizedReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (s *sizedReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.left -= int64(n)
+	return n, err
+}
+
+func (s *sizedReader) Len() int {
+	return int(max(s.left, 0))
+}
+
+// copyStored copies a file, or a folder and everything in it, from one storage
+// to another under the same name.
+func copyStored(src, dst Storage, name string) error {
+	return copyStoredAs(src, dst, name, name)
+}
+
+// copyStoredAs copies a file or folder like copyStored, writing it to dst
+// under a new name.
+func copyStoredAs(src, dst Storage, from, to string) error {
+	entry, err := src.Stat(from)
+	if err != nil {
+		return err
+	}
+	if entry.IsDir {
+		children, err := src.List(from)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err := copyStoredAs(src, dst, path.Join(from, child.Name), path.Join(to, child.Name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	r, err := src.Get(from)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return dst.Put(to, &sizedReader{r: r, left: entry.Size})
+}
+
+// renameStored moves a file or folder to a new name within one storage,
+// copying it and deleting the original where the storage can't rename.
+func renameStored(st Storage, from, to string) error {
+	if r, ok := st.(renamer); ok {
+		return r.Rename(from, to)
+	}
+	if err := copyStoredAs(st, st, from, to); err != nil {
+		st.Delete(to)
+		return err
+	}
+	return st.Delete(from)
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testStorageBackend runs the same checks against every backend, so they all
+// behave the way the rest of the program expects.
+func testStorageBackend(t *testing.T, st Storage) {
+	t.Helper()
+	get := func(name string) string {
+		t.Helper()
+		r, err := st.Get(name)
+		if err != nil {
+			t.Fatalf("Get %s: %v", name, err)
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Get %s: %v", name, err)
+		}
+		return string(data)
+	}
+
+	if err := st.Put("saves/slot1.sav", strings.NewReader("level 12")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := get("saves/slot1.sav"); got != "level 12" {
+		t.Errorf("Get: got %q, want %q", got, "level 12")
+	}
+	if err := st.Put("saves/slot1.sav", strings.NewReader("level 13!")); err != nil {
+		t.Fatalf("Put over an existing file: %v", err)
+	}
+	if got := get("saves/slot1.sav"); got != "level 13!" {
+		t.Errorf("Get after overwrite: got %q, want %q", got, "level 13!")
+	}
+
+	entry, err := st.Stat("saves/slot1.sav")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if entry.Name != "slot1.sav" || entry.Size != 9 || entry.IsDir {
+		t.Errorf("Stat: got %+v, want a 9-byte file named slot1.sav", entry)
+	}
+	if entry, err := st.Stat("saves"); err != nil || !entry.IsDir {
+		t.Errorf("Stat of a folder: got %+v, %v; want a folder", entry, err)
+	}
+
+	if err := st.Put("saves/deep/slot2.sav", strings.NewReader("x")); err != nil {
+		t.Fatalf("Put into a new subfolder: %v", err)
+	}
+	entries, err := st.List("saves")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+		if e.Name == "slot1.sav" && (e.Size != 9 || e.IsDir) {
+			t.Errorf("List: got %+v for slot1.sav, want a 9-byte file", e)
+		}
+		if e.Name == "deep" && !e.IsDir {
+			t.Errorf("List: got %+v for deep, want a folder", e)
+		}
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "deep,slot1.sav" {
+		t.Errorf("List: got %v, want [deep slot1.sav] and no leftover temp files", names)
+	}
+
+	if _, err := st.Get("saves/missing.sav"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Get of a missing file: got %v, want os.ErrNotExist", err)
+	}
+	if _, err := st.Stat("saves/missing.sav"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat of a missing file: got %v, want os.ErrNotExist", err)
+	}
+	if ok, err := storageExists(st, "saves/missing.sav"); ok || err != nil {
+		t.Errorf("storageExists of a missing file: got %v, %v; want false, nil", ok, err)
+	}
+
+	if r, ok := st.(renamer); ok {
+		if err := r.Rename("saves/deep", "saves/deeper"); err != nil {
+			t.Fatalf("Rename: %v", err)
+		}
+		if got := get("saves/deeper/slot2.sav"); got != "x" {
+			t.Errorf("Get after Rename: got %q, want %q", got, "x")
+		}
+		if _, err := st.Stat("saves/deep"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Stat of the old name after Rename: got %v, want os.ErrNotExist", err)
+		}
+	}
+
+	if err := st.Delete("saves"); err != nil {
+		t.Fatalf("Delete of a folder: %v", err)
+	}
+	if _, err := st.Stat("saves/slot1.sav"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat after Delete: got %v, want os.ErrNotExist", err)
+	}
+	if err := st.Delete("saves/missing.sav"); err != nil {
+		t.Errorf("Delete of a missing file: got %v, want nil", err)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	testStorageBackend(t, localStorage{root: t.TempDir()})
+}
+
+func TestSFTPStorage(t *testing.T) {
+	dir := t.TempDir()
+	settings := startSFTPServer(t, dir)
+
+	st, err := newSFTPStorage(settings)
+	if err != nil {
+		t.Fatalf("newSFTPStorage: %v", err)
+	}
+	t.Cleanup(func() { st.client.Close() })
+	if _, err := os.Stat(filepath.Join(dir, "backups")); err != nil {
+		t.Errorf("the remote folder wasn't created up front: %v", err)
+	}
+	testStorageBackend(t, st)
+}
+
+func TestSFTPStorageChecksHostKey(t *testing.T) {
+	settings := startSFTPServer(t, t.TempDir())
+	other, err := os.CreateTemp(t.TempDir(), "known_hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+	settings.KnownHosts = other.Name()
+
+	if _, err := newSFTPStorage(settings); err == nil {
+		t.Fatal("newSFTPStorage connected to a server missing from known_hosts")
+	}
+}
+
+// startSFTPServer serves dir over SFTP on a local port and returns settings
+// that log in to it with a password.
+func startSFTPServer(t *testing.T, dir string) StorageSettings {
+	t.Helper()
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv(sftpPasswordEnv, "")
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == "player" && string(password) == "hunter2" {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config, dir)
+		}
+	}()
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(ln.Addr().String())}, hostKey.PublicKey())
+	writeTestFile(t, knownHosts, line+"\n")
+
+	return StorageSettings{
+		Type:       StorageSFTP,
+		Host:       ln.Addr().String(),
+		User:       "player",
+		Password:   "hunter2",
+		KnownHosts: knownHosts,
+		Path:       "backups",
+	}
+}
+
+// serveSFTP handles one SSH connection, answering requests for the sftp
+// subsystem.
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig, dir string) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, requests, err := newChan.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					server, err := sftp.NewServer(channel, sftp.WithServerWorkingDirectory(dir))
+					if err != nil {
+						channel.Close()
+						return
+					}
+					go func() {
+						server.Serve()
+						server.Close()
+					}()
+				}
+			}
+		}()
+	}
+}
+
+// startFakeS3 serves an empty fake S3 bucket and returns settings for it.
+func startFakeS3(t *testing.T) (*fakeS3, StorageSettings) {
+	t.Helper()
+	fake := &fakeS3{bucket: "saves", objects: map[string]fakeS3Object{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, StorageSettings{
+		Type:      StorageS3,
+		Endpoint:  strings.TrimPrefix(srv.URL, "http://"),
+		Bucket:    "saves",
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Insecure:  true,
+		Path:      "backups",
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	fake, settings := startFakeS3(t)
+	st, err := newS3Storage(settings)
+	if err != nil {
+		t.Fatalf("newS3Storage: %v", err)
+	}
+	testStorageBackend(t, st)
+
+	for key := range fake.objects {
+		if !strings.HasPrefix(key, "backups/") {
+			t.Errorf("object %s was stored outside the configured prefix", key)
+		}
+	}
+
+	settings.Bucket = "missing"
+	if _, err := newS3Storage(settings); err == nil {
+		t.Error("newS3Storage accepted a bucket that doesn't exist")
+	}
+}
+
+// TestOpenRemoteStorageConcurrently is meant for go test -race: the TUI opens
+// storage from several goroutines at once.
+func TestOpenRemoteStorageConcurrently(t *testing.T) {
+	fake, settings := startFakeS3(t)
+	t.Cleanup(func() {
+		openStoragesMu.Lock()
+		delete(openStorages, settings)
+		openStoragesMu.Unlock()
+	})
+
+	const n = 8
+	var wg sync.WaitGroup
+	opened := make([]Storage, n)
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opened[i], errs[i] = openRemoteStorage(settings)
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		if errs[i] != nil {
+			t.Fatalf("open %d: %v", i, errs[i])
+		}
+		if opened[i] != opened[0] {
+			t.Errorf("open %d got a different backend than open 0", i)
+		}
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.bucketChecks != 1 {
+		t.Errorf("connected %d times, want once", fake.bucketChecks)
+	}
+}
+
+// fakeS3 is an in-memory S3 service with just enough of the API for
+// s3Storage: bucket checks, object puts, gets, stats, listings and bulk
+// deletes, all path-style.
+type fakeS3 struct {
+	bucket       string
+	mu           sync.Mutex
+	objects      map[string]fakeS3Object
+	bucketChecks int
+}
+
+type fakeS3Object struct {
+	data    []byte
+	modTime time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		fakeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	query := r.URL.Query()
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		f.bucketChecks++
+		w.WriteHeader(http.StatusOK)
+	case key == "" && r.Method == http.MethodGet && query.Get("list-type") == "2":
+		f.list(w, query.Get("prefix"), query.Get("delimiter"))
+	case key == "" && r.Method == http.MethodPost && query.Has("delete"):
+		f.deleteObjects(w, r)
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(fakeS3Body(r))
+		if err != nil {
+			fakeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = fakeS3Object{data: data, modTime: time.Now().UTC()}
+		w.Header().Set("ETag", `"`+strconv.Itoa(len(data))+`"`)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			fakeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		w.Header().Set("ETag", `"`+strconv.Itoa(len(obj.data))+`"`)
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	default:
+		fakeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix, delimiter string) {
+	type content struct {
+		Key          string
+		Size         int64
+		LastModified string
+		ETag         string
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Name           string
+		Prefix         string
+		KeyCount       int
+		IsTruncated    bool
+		Contents       []content
+		CommonPrefixes []commonPrefix
+	}{Name: f.bucket, Prefix: prefix}
+
+	seen := map[string]bool{}
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(rest, delimiter); i >= 0 {
+				p := prefix + rest[:i+len(delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{p})
+				}
+				continue
+			}
+		}
+		obj := f.objects[key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			Size:         int64(len(obj.data)),
+			LastModified: obj.modTime.Format(time.RFC3339),
+			ETag:         `"` + strconv.Itoa(len(obj.data)) + `"`,
+		})
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) deleteObjects(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Objects []struct{ Key string } `xml:"Object"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		fakeS3Error(w, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+	type deleted struct {
+		Key string
+	}
+	result := struct {
+		XMLName xml.Name `xml:"DeleteResult"`
+		Deleted []deleted
+	}{}
+	for _, obj := range req.Objects {
+		delete(f.objects, obj.Key)
+		result.Deleted = append(result.Deleted, deleted{obj.Key})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// fakeS3Body strips the aws-chunked framing that signed uploads over plain
+// HTTP use, returning the object's bytes.
+func fakeS3Body(r *http.Request) io.Reader {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return r.Body
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		br := bufio.NewReader(r.Body)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+			size, err := strconv.ParseInt(sizeHex, 16, 64)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if size == 0 {
+				pw.Close()
+				return
+			}
+			if _, err := io.CopyN(pw, br, size); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			br.ReadString('\n') // the CRLF after each chunk
+		}
+	}()
+	return pr
+}
+
+func fakeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// s3Storage keeps backups in a bucket of an S3-compatible service. Folders are
+// key prefixes ending in "/".
+type s3Storage struct {
+	client *minio.Client
+	bucket string
+	prefix string // "" or ending in "/"
+	label  string
+}
+
+func newS3Storage(s StorageSettings) (*s3Storage, error) {
+	if s.Endpoint == "" || s.Bucket == "" {
+		return nil, errors.New("s3 storage needs an endpoint and a bucket")
+	}
+	creds := credentials.NewEnvAWS()
+	if s.AccessKey != "" {
+		creds = credentials.NewStaticV4(s.AccessKey, s.SecretKey, "")
+	}
+	client, err := minio.New(s.Endpoint, &minio.Options{Creds: creds, Secure: !s.Insecure, Region: s.Region})
+	if err != nil {
+		return nil, err
+	}
+	ok, err := client.BucketExists(context.Background(), s.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach %s: %w", s.Endpoint, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("bucket %s does not exist on %s", s.Bucket, s.Endpoint)
+	}
+
+	prefix := strings.Trim(s.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Storage{client: client, bucket: s.Bucket, prefix: prefix, label: "s3://" + s.Bucket + "/" + prefix}, nil
+}
+
+func (s *s3Storage) key(name string) string {
+	return s.prefix + strings.Trim(name, "/")
+}
+
+// notFound maps S3's missing-key errors to os.ErrNotExist.
+func (s *s3Storage) notFound(name string, err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return &os.PathError{Op: "stat", Path: s.label + name, Err: os.ErrNotExist}
+	}
+	return err
+}
+
+func (s *s3Storage) Put(name string, r io.Reader) error {
+	// Pass the size when it is known, so small files aren't buffered as
+	// multipart uploads.
+	size := int64(-1)
+	switch r := r.(type) {
+	case *os.File:
+		if info, err := r.Stat(); err == nil {
+			size = info.Size()
+		}
+	case interface{ Len() int }:
+		size = int64(r.Len())
+	}
+	_, err := s.client.PutObject(context.Background(), s.bucket, s.key(name), r, size,
+		minio.PutObjectOptions{ContentType: "application/octet-stream"})
+	return err
+}
+
+func (s *s3Storage) Get(name string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s.notFound(name, err)
+	}
+	// GetObject is lazy; stat it so a missing key fails here rather than on
+	// the first read.
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, s.notFound(name, err)
+	}
+	return obj, nil
+}
+
+func (s *s3Storage) List(dir string) ([]StorageEntry, error) {
+	prefix := s.key(dir)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var entries []StorageEntry
+	for obj := range s.client.ListObjects(context.Background(), s.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		name := strings.TrimPrefix(obj.Key, prefix)
+		if strings.HasSuffix(name, "/") {
+			entries = append(entries, StorageEntry{Name: strings.TrimSuffix(name, "/"), IsDir: true})
+			continue
+		}
+		entries = append(entries, StorageEntry{Name: name, Size: obj.Size, ModTime: obj.LastModified})
+	}
+	return entries, nil
+}
+
+func (s *s3Storage) Delete(name string) error {
+	ctx := context.Background()
+	// A name can be both an object and a prefix; remove everything below it too.
+	objects := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objects)
+		objects <- minio.ObjectInfo{Key: s.key(name)}
+		for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.key(name) + "/", Recursive: true}) {
+			if obj.Err == nil {
+				objects <- obj
+			}
+		}
+	}()
+	for result := range s.client.RemoveObjects(ctx, s.bucket, objects, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil && !errors.Is(s.notFound(name, result.Err), os.ErrNotExist) {
+			return result.Err
+		}
+	}
+	return nil
+}
+
+func (s *s3Storage) Stat(name string) (StorageEntry, error) {
+	ctx := context.Background()
+	info, err := s.client.StatObject(ctx, s.bucket, s.key(name), minio.StatObjectOptions{})
+	if err == nil {
+		return StorageEntry{Name: path.Base(name), Size: info.Size, ModTime: info.LastModified}, nil
+	}
+	if err = s.notFound(name, err); !errors.Is(err, os.ErrNotExist) {
+		return StorageEntry{}, err
+	}
+	// Folders only exist as the prefix of the objects inside them.
+	opts := minio.ListObjectsOptions{Prefix: s.key(name) + "/", MaxKeys: 1}
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for obj := range s.client.ListObjects(listCtx, s.bucket, opts) {
+		if obj.Err != nil {
+			return StorageEntry{}, obj.Err
+		}
+		return StorageEntry{Name: path.Base(name), IsDir: true}, nil
+	}
+	return StorageEntry{}, err
+}
+
+func (s *s3Storage) String() string { return s.label }
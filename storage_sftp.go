@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpPasswordEnv names the environment variable that supplies the SFTP
+// password, so it needn't be kept in the config file.
+const sftpPasswordEnv = "BACKUP_MANAGER_SFTP_PASSWORD"
+
+// sftpStorage keeps backups in a folder on an SFTP server. Relative paths are
+// resolved against the login's home folder.
+type sftpStorage struct {
+	client *sftp.Client
+	root   string
+	label  string
+}
+
+func newSFTPStorage(s StorageSettings) (*sftpStorage, error) {
+	if s.Host == "" || s.User == "" {
+		return nil, errors.New("sftp storage needs a host and a user")
+	}
+	addr := s.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	knownHostsFile := s.KnownHosts
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeys, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read known hosts from %s (connect once with ssh to add the server's key): %w", knownHostsFile, err)
+	}
+	auth, err := sftpAuth(s)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            s.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         15 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to %s: %w", addr, err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot start sftp on %s: %w", addr, err)
+	}
+	root := strings.TrimSuffix(s.Path, "/")
+	if root == "" {
+		root = "."
+	}
+	// Like the local backup directory, the remote folder is created up front.
+	if err := client.MkdirAll(root); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("cannot create %s on %s: %w", root, addr, err)
+	}
+	return &sftpStorage{client: client, root: root, label: "sftp://" + s.User + "@" + s.Host + "/" + strings.TrimPrefix(s.Path, "/")}, nil
+}
+
+// sftpAuth collects the ways to log in: the key file, the password and the
+// SSH agent, in that order.
+func sftpAuth(s StorageSettings) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if s.KeyFile != "" {
+		data, err := os.ReadFile(s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			var passphrase string
+			if passphrase, err = promptForPassphrase("Enter the passphrase for " + s.KeyFile); err == nil {
+				signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key: %w", err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	password := s.Password
+	if p := os.Getenv(sftpPasswordEnv); p != "" {
+		password = p
+	}
+	if password != "" {
+		methods = append(methods, ssh.Password(password))
+	}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("sftp storage needs a key file, a password (or $%s) or a running SSH agent", sftpPasswordEnv)
+	}
+	return methods, nil
+}
+
+func (s *sftpStorage) path(name string) string {
+	return path.Join(s.root, name)
+}
+
+// Put uploads to a temp file and renames it into place, so readers never see
+// a partial file.
+func (s *sftpStorage) Put(name string, r io.Reader) error {
+	target := s.path(name)
+	if err := s.client.MkdirAll(path.Dir(target)); err != nil {
+		return err
+	}
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	tmp := path.Join(path.Dir(target), ".tmp-"+hex.EncodeToString(suffix))
+
+	err := s.upload(tmp, r)
+	if err == nil {
+		err = s.rename(tmp, target)
+	}
+	if err != nil {
+		s.client.Remove(tmp)
+	}
+	return err
+}
+
+// upload writes r to a new file at p.
+func (s *sftpStorage) upload(p string, r io.Reader) error {
+	f, err := s.client.Create(p)
+	if err != nil {
+		return err
+	}
+	_, err = f.ReadFrom(r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// rename moves oldPath over newPath. Plain SFTP renames refuse to replace an
+// existing file, so the OpenSSH extension is used where the server offers it.
+func (s *sftpStorage) rename(oldPath, newPath string) error {
+	if _, ok := s.client.HasExtension("posix-rename@openssh.com"); ok {
+		return s.client.PosixRename(oldPath, newPath)
+	}
+	if err := s.client.Remove(newPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.client.Rename(oldPath, newPath)
+}
+
+func (s *sftpStorage) Get(name string) (io.ReadCloser, error) {
+	return s.client.Open(s.path(name))
+}
+
+func (s *sftpStorage) List(dir string) ([]StorageEntry, error) {
+	infos, err := s.client.ReadDir(s.path(dir))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]StorageEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = sftpEntry(info)
+	}
+	return entries, nil
+}
+
+func (s *sftpStorage) Delete(name string) error {
+	err := s.client.RemoveAll(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *sftpStorage) Stat(name string) (StorageEntry, error) {
+	info, err := s.client.Stat(s.path(name))
+	if err != nil {
+		return StorageEntry{}, err
+	}
+	entry := sftpEntry(info)
+	entry.Name = path.Base(name)
+	return entry, nil
+}
+
+func (s *sftpStorage) Rename(from, to string) error {
+	target := s.path(to)
+	if err := s.client.MkdirAll(path.Dir(target)); err != nil {
+		return err
+	}
+	return s.rename(s.path(from), target)
+}
+
+func (s *sftpStorage) String() string { return s.label }
+
+// sftpEntry describes a remote file or folder.
+func sftpEntry(info os.FileInfo) StorageEntry {
+	entry := StorageEntry{Name: info.Name(), ModTime: info.ModTime(), IsDir: info.IsDir()}
+	if !entry.IsDir {
+		entry.Size = info.Size()
+	}
+	return entry
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// unreachableStorage is a local storage whose Stat fails like a remote that
+// can't be reached.
+type unreachableStorage struct {
+	localStorage
+}
+
+var errUnreachable = errors.New("connection reset by peer")
+
+func (unreachableStorage) Stat(string) (StorageEntry, error) {
+	return StorageEntry{}, errUnreachable
+}
+
+func TestStorageExists(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "present"), "x")
+	st := localStorage{root: dir}
+
+	if ok, err := storageExists(st, "present"); !ok || err != nil {
+		t.Errorf("present: got %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := storageExists(st, "missing"); ok || err != nil {
+		t.Errorf("missing: got %v, %v; want false, nil", ok, err)
+	}
+	if ok, err := storageExists(unreachableStorage{st}, "present"); ok || !errors.Is(err, errUnreachable) {
+		t.Errorf("unreachable: got %v, %v; want false and the Stat error", ok, err)
+	}
+}
+
+func TestStoreBlobFailsWhenStorageCantBeChecked(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "slot.sav")
+	writeTestFile(t, src, "level 12")
+	st := unreachableStorage{localStorage{root: filepath.Join(dir, "backups")}}
+
+	if _, err := storeBlob(st, src); !errors.Is(err, errUnreachable) {
+		t.Fatalf("storeBlob: got %v, want the Stat error instead of assuming the blob exists", err)
+	}
+}
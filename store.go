@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// blobDirName is the hidden folder in the backup storage that holds the
+// content of every file saved by store-format backups, named by SHA-256.
+const blobDirName = ".blobs"
+
+// Snapshot is the content of a store-format backup: the save's files, each
+// pointing at a blob. Entry names follow the archive layout, so a save file is
+// a single entry and a save folder is a top-level folder holding its files.
+type Snapshot struct {
+	Entries []SnapshotEntry `json:"entries"`
+}
+
+// SnapshotEntry is one file in a snapshot.
+type SnapshotEntry struct {
+	Name   string      `json:"name"`
+	SHA256 string      `json:"sha256"`
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"`
+}
+
+// blobPath returns the storage name of the blob with the given hash.
+func blobPath(sum string) string {
+	return path.Join(blobDirName, sum[:2], sum)
+}
+
+// writeSnapshot stores the save's files as blobs in st, skipping any whose
+// content is already stored, and writes the snapshot listing them to dst.
+func writeSnapshot(profile Profile, st Storage, dst string) error {
+	info, err := os.Stat(profile.SavePath)
+	if err != nil {
+		return fmt.Errorf("failed to read save: %w", err)
+	}
+
+	base := filepath.Base(profile.SavePath)
+	sources := map[string]string{base: profile.SavePath}
+	names := []string{base}
+	if info.IsDir() {
+		files, err := collectSaveFiles(profile, profile.SavePath)
+		if err != nil {
+			return err
+		}
+		sources = make(map[string]string, len(files))
+		names = names[:0]
+		for _, rel := range files {
+			name := base + "/" + rel
+			sources[name] = filepath.Join(profile.SavePath, filepath.FromSlash(rel))
+			names = append(names, name)
+		}
+	}
+
+	var snapshot Snapshot
+	for _, name := range names {
+		entry, err := storeBlob(st, sources[name])
+		if err != nil {
+			return fmt.Errorf("failed to store %s: %w", name, err)
+		}
+		entry.Name = name
+		snapshot.Entries = append(snapshot.Entries, entry)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// storeBlob makes sure the file's content is in the blob store and returns its
+// entry. Storage writes are atomic, so a blob is either complete or absent.
+func storeBlob(st Storage, src string) (SnapshotEntry, error) {
+	sum, size, err := hashFile(src)
+	if err != nil {
+		return SnapshotEntry{}, err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return SnapshotEntry{}, err
+	}
+	entry := SnapshotEntry{SHA256: sum, Size: size, Mode: info.Mode().Perm()}
+
+	target := blobPath(sum)
+	exists, err := storageExists(st, target)
+	if err != nil {
+		return SnapshotEntry{}, err
+	}
+	if exists {
+		return entry, nil
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return SnapshotEntry{}, err
+	}
+	defer f.Close()
+	// The save may have changed between hashing and copying; never store a
+	// blob under a hash that doesn't match its content.
+	if err := st.Put(target, &verifyingReader{r: f, h: sha256.New(), want: sum, left: size}); err != nil {
+		st.Delete(target)
+		return SnapshotEntry{}, err
+	}
+	return entry, nil
+}
+
+// verifyingReader fails the final read if the content doesn't hash to want,
+// so an upload of changed content is abandoned rather than stored.
+type verifyingReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want string
+	left int64 // bytes expected before EOF
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	v.left -= int64(n)
+	if err == io.EOF && hex.EncodeToString(v.h.Sum(nil)) != v.want {
+		return n, errSaveChanged
+	}
+	return n, err
+}
+
+// Len reports how much is left to read, so backends that want the length up
+// front can upload the blob in a single request.
+func (v *verifyingReader) Len() int {
+	return int(max(v.left, 0))
+}
+
+// readSnapshot loads a store-format backup from its storage.
+func readSnapshot(st Storage, key string) (*Snapshot, error) {
+	r, err := st.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("snapshot %s is corrupted: %w", path.Base(key), err)
+	}
+	return &s, nil
+}
+
+// extractSnapshot copies the blobs of the snapshot at the local path src into
+// dir under their entry names, and returns the path of the single top-level
+// entry like extractArchive.
+func extractSnapshot(st Storage, src, dir string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	var snapshot Snapshot
+	err = json.NewDecoder(f).Decode(&snapshot)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("snapshot %s is corrupted: %w", filepath.Base(src), err)
+	}
+	for _, entry := range snapshot.Entries {
+		target, err := archiveTarget(dir, entry.Name)
+		if err != nil {
+			return "", err
+		}
+		if err := getFile(st, blobPath(entry.SHA256), target); err != nil {
+			return "", fmt.Errorf("failed to restore %s: %w", entry.Name, err)
+		}
+		if entry.Mode != 0 {
+			if err := os.Chmod(target, entry.Mode); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) != 1 {
+		return "", fmt.Errorf("snapshot %s should contain exactly one top-level entry, found %d", filepath.Base(src), len(entries))
+	}
+	return filepath.Join(dir, entries[0].Name()), nil
+}
+
+// verifySnapshotBlobs re-hashes every blob a snapshot points at.
+func verifySnapshotBlobs(st Storage, key string) (VerifyStatus, string) {
+	snapshot, err := readSnapshot(st, key)
+	if err != nil {
+		return VerifyCorrupt, err.Error()
+	}
+	for _, entry := range snapshot.Entries {
+		sum, size, err := hashStoredFile(st, blobPath(entry.SHA256))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			return VerifyMissing, fmt.Sprintf("content of %s not found", entry.Name)
+		case err != nil:
+			return VerifyCorrupt, err.Error()
+		case size < entry.Size:
+			return VerifyTruncated, fmt.Sprintf("content of %s is %s of %s", entry.Name, formatSize(size), formatSize(entry.Size))
+		case sum != entry.SHA256:
+			return VerifyCorrupt, fmt.Sprintf("content of %s fails its SHA-256", entry.Name)
+		}
+	}
+	return VerifyOK, ""
+}
+
+// collectGarbage deletes blobs that no snapshot in the storage refers to,
+// returning how many were removed and the space freed. Snapshots that can't be
+// read stop the collection, so their blobs are never lost.
+func collectGarbage(st Storage) (int, int64, error) {
+	if exists, err := storageExists(st, blobDirName); err != nil || !exists {
+		return 0, 0, err
+	}
+
+	entries, err := st.List("")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		if _, format, ok := parseBackupFileName(entry.Name, entry.IsDir); !ok || format != FormatStore {
+			continue
+		}
+		snapshot, err := readSnapshot(st, entry.Name)
+		if err != nil {
+			return 0, 0, fmt.Errorf("blob cleanup skipped: %w", err)
+		}
+		for _, e := range snapshot.Entries {
+			referenced[e.SHA256] = true
+		}
+	}
+
+	prefixes, err := st.List(blobDirName)
+	if err != nil {
+		return 0, 0, err
+	}
+	removed, freed := 0, int64(0)
+	for _, prefix := range prefixes {
+		if !prefix.IsDir {
+			continue
+		}
+		dir := path.Join(blobDirName, prefix.Name)
+		blobs, err := st.List(dir)
+		if err != nil {
+			return removed, freed, err
+		}
+		for _, blob := range blobs {
+			if blob.IsDir || strings.HasPrefix(blob.Name, ".tmp-") || referenced[blob.Name] {
+				continue
+			}
+			if err := st.Delete(path.Join(dir, blob.Name)); err != nil {
+				return removed, freed, err
+			}
+			removed++
+			freed += blob.Size
+		}
+	}
+	return removed, freed, nil
+}
+
+// reportGarbage runs blob cleanup after backups were deleted and prints the result.
+func reportGarbage(profile Profile) {
+	st, err := openStorage(profile)
+	removed, freed := 0, int64(0)
+	if err == nil {
+		removed, freed, err = collectGarbage(st)
+	}
+	if removed > 0 {
+		fmt.Printf("%s %s Freed %s of stored content no backup uses any more.\n", iconDelete, green("INFO:"), formatSize(freed))
+	}
+	if err != nil {
+		fmt.Printf("%s %s Blob cleanup failed: %v\n", iconError, red("ERROR:"), err)
+	}
+}
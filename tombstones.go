@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// tombstonesFileName is the hidden file in the backup directory that records
+// deleted backups some mirror may still hold.
+const tombstonesFileName = ".deleted.json"
+
+// Tombstone is a deleted or pruned backup that couldn't be removed from every
+// mirror, e.g. because a drive was unplugged. The next sync with one of those
+// mirrors removes it there instead of pulling it back.
+type Tombstone struct {
+	Key       string    `json:"key"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Mirrors   []string  `json:"mirrors"` // mirrors that may still hold the backup
+}
+
+func tombstonesPath(profile Profile) string {
+	return filepath.Join(profile.BackupDir, tombstonesFileName)
+}
+
+// readTombstones loads the profile's tombstones. A missing file means every
+// deletion reached every mirror.
+func readTombstones(profile Profile) ([]Tombstone, error) {
+	data, err := os.ReadFile(tombstonesPath(profile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tombstones []Tombstone
+	if err := json.Unmarshal(data, &tombstones); err != nil {
+		return nil, fmt.Errorf("list of deleted backups is corrupted: %w", err)
+	}
+	return tombstones, nil
+}
+
+// writeTombstones saves the tombstones, dropping mirrors the profile no longer
+// has and tombstones no mirror is left for.
+func writeTombstones(profile Profile, tombstones []Tombstone) error {
+	var kept []Tombstone
+	for _, t := range tombstones {
+		t.Mirrors = slices.DeleteFunc(t.Mirrors, func(name string) bool {
+			_, err := findMirror(profile, name)
+			return err != nil
+		})
+		if len(t.Mirrors) > 0 {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		err := os.Remove(tombstonesPath(profile))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tombstonesPath(profile), data, 0644)
+}
+
+// addTombstones records that the backups may still be on the mirror.
+func addTombstones(profile Profile, mirror string, backups []Backup) error {
+	tombstones, err := readTombstones(profile)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, b := range backups {
+		i := slices.IndexFunc(tombstones, func(t Tombstone) bool { return t.Key == b.Key })
+		if i < 0 {
+			tombstones = append(tombstones, Tombstone{Key: b.Key, DeletedAt: now})
+			i = len(tombstones) - 1
+		}
+		if !slices.Contains(tombstones[i].Mirrors, mirror) {
+			tombstones[i].Mirrors = append(tombstones[i].Mirrors, mirror)
+		}
+	}
+	return writeTombstones(profile, tombstones)
+}
+
+// deletedOn returns the keys of deleted backups the mirror may still hold.
+func deletedOn(tombstones []Tombstone, mirror string) map[string]bool {
+	keys := map[string]bool{}
+	for _, t := range tombstones {
+		if slices.Contains(t.Mirrors, mirror) {
+			keys[t.Key] = true
+		}
+	}
+	return keys
+}
+
+// clearTombstones forgets the deletions pending on the mirror, once they have
+// been carried out there.
+func clearTombstones(profile Profile, mirror string) error {
+	tombstones, err := readTombstones(profile)
+	if err != nil || len(tombstones) == 0 {
+		return err
+	}
+	for i := range tombstones {
+		tombstones[i].Mirrors = slices.DeleteFunc(tombstones[i].Mirrors, func(name string) bool { return name == mirror })
+	}
+	return writeTombstones(profile, tombstones)
+}
@@ -0,0 +1,1044 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// The full-screen interface keeps the backups of the active game on screen
+// with the details of the selected one, and runs the everyday actions from
+// single keys. Their output goes to a log under the list. Screens with many
+// questions of their own (settings, profiles, export and import, editing a
+// backup) hand the terminal back to the classic menus until they finish.
+
+var (
+	tuiTitleStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14"))
+	tuiPaneStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("6"))
+	tuiCursorStyle = lipgloss.NewStyle().Reverse(true)
+	tuiLabelStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	tuiDimStyle    = lipgloss.NewStyle().Faint(true)
+	tuiStatusStyle = lipgloss.NewStyle().Reverse(true)
+)
+
+// tuiLogLines is how many lines of the log stay visible under the list.
+const tuiLogLines = 4
+
+type tuiMode int
+
+const (
+	tuiList   tuiMode = iota
+	tuiFilter         // typing in the filter box
+	tuiModal          // answering a question in the detail pane
+	tuiView           // reading scrollable output, such as a comparison
+	tuiHelp
+)
+
+// tuiQuestion is a question asked in place of the detail pane. Without an input
+// it is a yes/no question that defaults to no, like the classic prompts.
+type tuiQuestion struct {
+	title  string
+	body   []string
+	input  *textinput.Model
+	submit func(m *tuiModel, value string) tea.Cmd
+	cancel string // logged when the question is dismissed
+}
+
+// tuiSender lets work running in the background post messages to the program.
+type tuiSender struct{ p *tea.Program }
+
+func (s *tuiSender) send(msg tea.Msg) {
+	if s.p != nil {
+		s.p.Send(msg)
+	}
+}
+
+type (
+	backupsLoadedMsg struct {
+		backups []Backup
+		err     error
+	}
+	saveStateMsg string
+	logLineMsg   string
+	opDoneMsg    struct{ next func(m *tuiModel) tea.Cmd }
+	viewMsg      struct{ title, text string }
+	configMsg    struct {
+		config Config
+		path   string
+	}
+)
+
+type tuiModel struct {
+	config     Config
+	configPath string
+	profile    Profile
+	source     string // mirror the list comes from; "" for the backup directory
+	sender     *tuiSender
+
+	backups []Backup
+	visible []int // indexes into backups that match the filter
+	cursor  int   // position in visible
+	offset  int   // first visible row shown
+	marked  map[string]bool
+
+	mode      tuiMode
+	filter    textinput.Model
+	filterErr error
+	question  *tuiQuestion
+	view      viewport.Model
+	viewTitle string
+
+	log       []string
+	saveState string
+	busy      string // what is running; actions wait until it is ""
+	width     int
+	height    int
+}
+
+// runTUI runs the full-screen interface until the user quits.
+func runTUI(config Config, configPath string) error {
+	filter := textinput.New()
+	filter.Prompt = "/"
+	filter.Placeholder = "filter"
+	m := &tuiModel{
+		config:     config,
+		configPath: configPath,
+		profile:    config.activeProfile(),
+		sender:     &tuiSender{},
+		marked:     map[string]bool{},
+		filter:     filter,
+		saveState:  "checking...",
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	m.sender.p = p
+	if _, err := p.Run(); err != nil {
+		return err
+	}
+	fmt.Printf("%s %s Thank you for using Game Save Backup Manager!\n", iconSuccess, green("INFO:"))
+	return nil
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return m.reload()
+}
+
+// reload lists the backups of the current source again.
+func (m *tuiModel) reload() tea.Cmd {
+	profile, source := m.profile, m.source
+	return func() tea.Msg {
+		st, err := backupSource(profile, source)
+		if err != nil {
+			return backupsLoadedMsg{err: err}
+		}
+		backups, err := listBackupsIn(st)
+		return backupsLoadedMsg{backups: backups, err: err}
+	}
+}
+
+// checkSave works out whether the latest backup holds the current save.
+func (m *tuiModel) checkSave() tea.Cmd {
+	profile := m.profile
+	return func() tea.Msg {
+		if _, err := os.Stat(profile.SavePath); err != nil {
+			return saveStateMsg("save missing")
+		}
+		backups, err := listBackupsInternal(profile)
+		if err != nil || len(backups) == 0 {
+			return saveStateMsg("not backed up")
+		}
+		want := contentSum(backups[0])
+		if want == "" {
+			return saveStateMsg("unknown")
+		}
+		sum, _, err := hashSave(profile)
+		switch {
+		case err != nil:
+			return saveStateMsg("unknown")
+		case sum == want:
+			return saveStateMsg("backed up")
+		}
+		return saveStateMsg("changed since " + backups[0].Name)
+	}
+}
+
+// captureMu serializes captureOutput: os.Stdout is process-wide, so two
+// background operations redirecting it at once would swap each other's
+// output and restore the wrong file.
+var captureMu sync.Mutex
+
+// captureOutput runs fn with os.Stdout redirected and hands each non-empty
+// printed line to line. The program keeps drawing on the terminal it opened
+// at start. Only one capture runs at a time; others wait their turn.
+func captureOutput(fn func(), line func(string)) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	r, w, err := os.Pipe()
+	if err != nil {
+		line(fmt.Sprintf("%s %s %v", iconError, red("ERROR:"), err))
+		return
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if text := strings.TrimRight(scanner.Text(), " \r"); text != "" {
+				line(text)
+			}
+		}
+		io.Copy(io.Discard, r)
+		close(done)
+	}()
+	defer func() {
+		os.Stdout = stdout
+		w.Close()
+		<-done
+		r.Close()
+	}()
+	fn()
+}
+
+// run does fn in the background, streaming what it prints to the log, then
+// calls next, if set, back on the program.
+func (m *tuiModel) run(what string, fn func(), next func(m *tuiModel) tea.Cmd) tea.Cmd {
+	m.busy = what
+	sender := m.sender
+	return func() tea.Msg {
+		captureOutput(fn, func(line string) { sender.send(logLineMsg(line)) })
+		return opDoneMsg{next: next}
+	}
+}
+
+// show does fn in the background and opens what it prints in the viewer.
+func (m *tuiModel) show(what, title string, fn func()) tea.Cmd {
+	m.busy = what
+	return func() tea.Msg {
+		var out strings.Builder
+		captureOutput(fn, func(line string) { out.WriteString(line + "\n") })
+		return viewMsg{title: title, text: out.String()}
+	}
+}
+
+// legacyScreen runs one of the classic menu screens while the program has
+// released the terminal.
+type legacyScreen func()
+
+func (f legacyScreen) Run() error {
+	clearScreen()
+	f()
+	return nil
+}
+
+func (legacyScreen) SetStdin(io.Reader)  {}
+func (legacyScreen) SetStdout(io.Writer) {}
+func (legacyScreen) SetStderr(io.Writer) {}
+
+// legacy hands the terminal to a classic screen and picks up the
+// configuration it returns.
+func (m *tuiModel) legacy(screen func(config Config, path string) (Config, string)) tea.Cmd {
+	config, path := m.config, m.configPath
+	var result configMsg
+	return tea.Exec(legacyScreen(func() {
+		result.config, result.path = screen(config, path)
+	}), func(error) tea.Msg { return result })
+}
+
+// logf adds a line to the log.
+func (m *tuiModel) logf(format string, args ...any) {
+	m.log = append(m.log, fmt.Sprintf(format, args...))
+	if len(m.log) > 200 {
+		m.log = m.log[len(m.log)-200:]
+	}
+}
+
+// ask shows a question with a text input.
+func (m *tuiModel) ask(title string, body []string, password bool, cancel string, submit func(m *tuiModel, value string) tea.Cmd) tea.Cmd {
+	input := textinput.New()
+	input.Prompt = "> "
+	if password {
+		input.EchoMode = textinput.EchoPassword
+		input.EchoCharacter = '*'
+	}
+	m.question = &tuiQuestion{title: title, body: body, input: &input, submit: submit, cancel: cancel}
+	m.mode = tuiModal
+	return input.Focus()
+}
+
+// confirm shows a yes/no question; yes is called only on "y".
+func (m *tuiModel) confirm(title string, body []string, cancel string, yes func(m *tuiModel) tea.Cmd) tea.Cmd {
+	m.question = &tuiQuestion{title: title, body: body, cancel: cancel, submit: func(m *tuiModel, _ string) tea.Cmd {
+		return yes(m)
+	}}
+	m.mode = tuiModal
+	return nil
+}
+
+// withPassphrase asks for the backup passphrase first when backupPassphrase
+// would otherwise prompt on the terminal the program is drawing on.
+func (m *tuiModel) withPassphrase(needed, confirm bool, then func(m *tuiModel) tea.Cmd) tea.Cmd {
+	if !needed || passphraseKnown(m.profile) {
+		return then(m)
+	}
+	return m.ask("Enter the backup passphrase", nil, true, "Cancelled.", func(m *tuiModel, p string) tea.Cmd {
+		if p == "" {
+			m.logf("%s %s The passphrase can't be empty.", iconError, red("ERROR:"))
+			return nil
+		}
+		if !confirm {
+			rememberPassphrase(m.profile, p)
+			return then(m)
+		}
+		return m.ask("Enter it again to confirm", nil, true, "Cancelled.", func(m *tuiModel, again string) tea.Cmd {
+			if again != p {
+				m.logf("%s %s Passphrases do not match.", iconError, red("ERROR:"))
+				return nil
+			}
+			rememberPassphrase(m.profile, p)
+			return then(m)
+		})
+	})
+}
+
+// current returns the backup under the cursor.
+func (m *tuiModel) current() (Backup, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return Backup{}, false
+	}
+	return m.backups[m.visible[m.cursor]], true
+}
+
+// applyFilter shows the backups that match the filter, a query as in the
+// pickers, in its order and keeps the cursor on the backup named keep where
+// possible. An unfinished or invalid query shows nothing.
+func (m *tuiModel) applyFilter(keep string) {
+	query, err := parseBackupQuery(m.filter.Value())
+	m.filterErr = err
+	m.visible = m.visible[:0]
+	m.cursor = 0
+	if err != nil {
+		return
+	}
+	for _, i := range query.order(m.backups) {
+		if m.backups[i].Name == keep {
+			m.cursor = len(m.visible)
+		}
+		m.visible = append(m.visible, i)
+	}
+}
+
+// currentName is the name of the backup under the cursor, or "".
+func (m *tuiModel) currentName() string {
+	b, _ := m.current()
+	return b.Name
+}
+
+// markedBackups returns the marked backups, or the one under the cursor when
+// none are marked.
+func (m *tuiModel) markedBackups() []Backup {
+	var selected []Backup
+	for _, b := range m.backups {
+		if m.marked[b.Name] {
+			selected = append(selected, b)
+		}
+	}
+	if len(selected) == 0 {
+		if b, ok := m.current(); ok {
+			selected = append(selected, b)
+		}
+	}
+	return selected
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.view.Width, m.view.Height = msg.Width, msg.Height-2
+		return m, nil
+
+	case backupsLoadedMsg:
+		if msg.err != nil {
+			m.logf("%s %s Failed to list backups: %v", iconError, red("ERROR:"), msg.err)
+		}
+		selected, _ := m.current()
+		m.backups = msg.backups
+		for name := range m.marked {
+			if _, err := findIn(m.backups, name); err != nil {
+				delete(m.marked, name)
+			}
+		}
+		m.applyFilter(selected.Name)
+		return m, m.checkSave()
+
+	case saveStateMsg:
+		m.saveState = string(msg)
+		return m, nil
+
+	case logLineMsg:
+		m.logf("%s", string(msg))
+		return m, nil
+
+	case opDoneMsg:
+		m.busy = ""
+		var next tea.Cmd
+		if msg.next != nil {
+			next = msg.next(m)
+		}
+		return m, tea.Batch(next, m.reload())
+
+	case viewMsg:
+		m.busy = ""
+		m.viewTitle = msg.title
+		m.view = viewport.New(m.width, m.height-2)
+		m.view.SetContent(msg.text)
+		m.mode = tuiView
+		return m, nil
+
+	case configMsg:
+		m.config, m.configPath = msg.config, msg.path
+		m.profile = m.config.activeProfile()
+		if _, err := findMirror(m.profile, m.source); m.source != "" && err != nil {
+			m.source = ""
+		}
+		m.marked = map[string]bool{}
+		return m, m.reload()
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.mode {
+		case tuiFilter:
+			return m.updateFilter(msg)
+		case tuiModal:
+			return m.updateQuestion(msg)
+		case tuiView:
+			switch msg.String() {
+			case "esc", "q":
+				m.mode = tuiList
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.view, cmd = m.view.Update(msg)
+			return m, cmd
+		case tuiHelp:
+			m.mode = tuiList
+			return m, nil
+		}
+		return m.updateList(msg)
+	}
+
+	if m.mode == tuiModal && m.question != nil && m.question.input != nil {
+		var cmd tea.Cmd
+		*m.question.input, cmd = m.question.input.Update(msg)
+		return m, cmd
+	}
+	if m.mode == tuiFilter {
+		var cmd tea.Cmd
+		m.filter, cmd = m.filter.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// findIn looks a backup up by name in a list.
+func findIn(backups []Backup, name string) (Backup, error) {
+	for _, b := range backups {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+	return Backup{}, fmt.Errorf("%w: %s", errBackupNotFound, name)
+}
+
+func (m *tuiModel) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.filter.Blur()
+		m.mode = tuiList
+		return m, nil
+	case "esc":
+		m.filter.SetValue("")
+		m.filter.Blur()
+		m.mode = tuiList
+		m.applyFilter(m.currentName())
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.applyFilter(m.currentName())
+	return m, cmd
+}
+
+func (m *tuiModel) updateQuestion(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	q := m.question
+	dismiss := func() (tea.Model, tea.Cmd) {
+		m.question, m.mode = nil, tuiList
+		if q.cancel != "" {
+			m.logf("%s %s %s", iconError, yellow("INFO:"), q.cancel)
+		}
+		return m, nil
+	}
+	accept := func(value string) (tea.Model, tea.Cmd) {
+		m.question, m.mode = nil, tuiList
+		return m, q.submit(m, value)
+	}
+
+	if q.input == nil {
+		switch msg.String() {
+		case "y", "Y":
+			return accept("y")
+		case "n", "N", "esc", "enter", "q":
+			return dismiss()
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "esc":
+		return dismiss()
+	case "enter":
+		return accept(q.input.Value())
+	}
+	var cmd tea.Cmd
+	*q.input, cmd = q.input.Update(msg)
+	return m, cmd
+}
+
+func (m *tuiModel) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.cursor--
+	case "down", "j":
+		m.cursor++
+	case "pgup":
+		m.cursor -= m.listRows()
+	case "pgdown":
+		m.cursor += m.listRows()
+	case "home":
+		m.cursor = 0
+	case "end":
+		m.cursor = len(m.visible) - 1
+	case " ":
+		if b, ok := m.current(); ok {
+			if m.marked[b.Name] {
+				delete(m.marked, b.Name)
+			} else {
+				m.marked[b.Name] = true
+			}
+			m.cursor++
+		}
+	case "/":
+		m.mode = tuiFilter
+		return m, m.filter.Focus()
+	case "esc":
+		m.marked = map[string]bool{}
+		m.filter.SetValue("")
+		m.applyFilter(m.currentName())
+	case "?":
+		m.mode = tuiHelp
+	default:
+		if m.busy != "" {
+			return m, nil
+		}
+		return m, m.action(msg.String())
+	}
+	m.cursor = max(0, min(m.cursor, len(m.visible)-1))
+	return m, nil
+}
+
+// action starts the action bound to key.
+func (m *tuiModel) action(key string) tea.Cmd {
+	b, ok := m.current()
+	switch key {
+	case "n":
+		return m.startCreate()
+	case "u":
+		return m.startUndo()
+	case "m":
+		return m.nextSource()
+	case "R":
+		m.logf("%s %s Reloading backups...", iconInfo, white("INFO:"))
+		return m.reload()
+	case "g":
+		return m.legacy(func(config Config, path string) (Config, string) {
+			return switchProfile(config, path), path
+		})
+	case "s":
+		return m.legacy(settingsMenu)
+	case "x":
+		profile := m.profile
+		return m.legacy(func(config Config, path string) (Config, string) {
+			exportImportMenu(profile)
+			return config, path
+		})
+	}
+	if !ok {
+		return nil
+	}
+	switch key {
+	case "r":
+		return m.startRestore(b)
+	case "d":
+		return m.startDelete()
+	case "c":
+		return m.startCompare(b)
+	case "t":
+		profile := m.profile
+		return m.legacy(func(config Config, path string) (Config, string) {
+			restoreToPrompt(profile, b)
+			return config, path
+		})
+	}
+	if m.source != "" && (key == "p" || key == "e" || key == "enter") {
+		m.logf("%s %s Backups on a mirror can only be restored or compared; switch back with m.", iconError, yellow("INFO:"))
+		return nil
+	}
+	switch key {
+	case "p":
+		profile := m.profile
+		return m.run("Updating", func() {
+			oldKey := b.Key
+			updated, err := applyBackupAction(profile, b, 3)
+			if err != nil {
+				fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+				return
+			}
+			reportMirrorUpdate(profile, oldKey, updated)
+		}, nil)
+	case "e", "enter":
+		profile := m.profile
+		return m.legacy(func(config Config, path string) (Config, string) {
+			manageBackupMenu(profile, b)
+			return config, path
+		})
+	}
+	return nil
+}
+
+// nextSource switches the list to the next mirror, and from the last one
+// back to the backup directory.
+func (m *tuiModel) nextSource() tea.Cmd {
+	if len(m.profile.Mirrors) == 0 {
+		m.logf("%s %s This profile has no mirrors.", iconInfo, yellow("INFO:"))
+		return nil
+	}
+	sources := []string{""}
+	for _, mirror := range m.profile.Mirrors {
+		sources = append(sources, mirror.Name)
+	}
+	next := ""
+	for i, source := range sources {
+		if source == m.source {
+			next = sources[(i+1)%len(sources)]
+		}
+	}
+	m.source = next
+	m.marked = map[string]bool{}
+	return m.reload()
+}
+
+// startCreate follows createBackup: check the save, handle an unchanged save
+// per the duplicate policy, then ask for a name and note.
+func (m *tuiModel) startCreate() tea.Cmd {
+	profile := m.profile
+	if _, err := os.Stat(profile.SavePath); os.IsNotExist(err) {
+		m.logf("%s %s Save file not found at: %s", iconError, red("ERROR:"), profile.SavePath)
+		m.logf("%s %s Please check the path in Settings.", iconError, yellow("INFO:"))
+		return nil
+	}
+	if duplicatePolicy(profile) == DuplicatesAllow {
+		return m.askBackupName()
+	}
+	var dup *Backup
+	return m.run("Checking for changes", func() {
+		if d, err := unchangedSince(profile); err == nil {
+			dup = d
+		}
+	}, func(m *tuiModel) tea.Cmd {
+		if dup == nil {
+			return m.askBackupName()
+		}
+		m.logf("%s %s The save hasn't changed since the latest backup, %s.", iconInfo, yellow("INFO:"), dup.Name)
+		if duplicatePolicy(profile) == DuplicatesSkip {
+			m.logf("%s %s Backup skipped.", iconInfo, yellow("INFO:"))
+			return nil
+		}
+		return m.confirm("Create an identical backup anyway? (y/N)", []string{"The save hasn't changed since " + dup.Name + "."}, "Backup skipped.", func(m *tuiModel) tea.Cmd {
+			return m.askBackupName()
+		})
+	})
+}
+
+func (m *tuiModel) askBackupName() tea.Cmd {
+	profile := m.profile
+	return m.ask("Enter backup name (press Enter for default)", []string{"Saving " + profile.SavePath}, false, "Backup cancelled.", func(m *tuiModel, name string) tea.Cmd {
+		label := name
+		if label == "" {
+			label = "(default)"
+		}
+		return m.ask("Enter a note, e.g. \"before boss fight\" (optional)", []string{"Name: " + label}, false, "Backup cancelled.", func(m *tuiModel, note string) tea.Cmd {
+			return m.withPassphrase(profile.Encryption.Enabled, true, func(m *tuiModel) tea.Cmd {
+				return m.run("Creating backup", func() {
+					finishCreateBackup(profile, name, strings.TrimSpace(note))
+				}, nil)
+			})
+		})
+	})
+}
+
+// startRestore follows restoreBackup: verify, ask to force a damaged backup,
+// check the game, confirm, then restore over the save.
+func (m *tuiModel) startRestore(b Backup) tea.Cmd {
+	var result VerifyResult
+	return m.run("Verifying "+b.Name, func() {
+		result = verifyBackup(b)
+	}, func(m *tuiModel) tea.Cmd {
+		if result.healthy() {
+			return m.restoreChecked(b, false)
+		}
+		warning := fmt.Sprintf("This backup is %s (%s) and may not restore correctly!", result.Status, result.Detail)
+		m.logf("%s %s %s", iconError, red("WARNING:"), warning)
+		return m.ask("Type 'force' to restore it anyway", []string{warning}, false, "Restore cancelled.", func(m *tuiModel, answer string) tea.Cmd {
+			if strings.ToLower(answer) != "force" {
+				m.logf("%s %s Restore cancelled.", iconError, yellow("INFO:"))
+				return nil
+			}
+			return m.restoreChecked(b, true)
+		})
+	})
+}
+
+func (m *tuiModel) restoreChecked(b Backup, force bool) tea.Cmd {
+	profile := m.profile
+	running := false
+	return m.run("Checking the game", func() {
+		if err := checkGameRunning(profile, profile.GameCheck.restorePolicy(), "restore"); err != nil {
+			fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+			running = true
+		}
+	}, func(m *tuiModel) tea.Cmd {
+		if running {
+			return nil
+		}
+		body := []string{"Backup: " + b.Name, "WARNING: This will overwrite your current save file!"}
+		return m.confirm("Are you sure you want to restore this backup? (y/N)", body, "Restore cancelled.", func(m *tuiModel) tea.Cmd {
+			needed := b.Encrypted || (profile.AutoBackup && profile.Encryption.Enabled)
+			return m.withPassphrase(needed, !b.Encrypted, func(m *tuiModel) tea.Cmd {
+				return m.run("Restoring "+b.Name, func() {
+					finishRestore(profile, b, force)
+				}, func(m *tuiModel) tea.Cmd { return m.checkSave() })
+			})
+		})
+	})
+}
+
+// startDelete follows deleteBackups for the marked backups, or the selected
+// one.
+func (m *tuiModel) startDelete() tea.Cmd {
+	if m.source != "" {
+		m.logf("%s %s Backups on a mirror can only be restored or compared; switch back with m.", iconError, yellow("INFO:"))
+		return nil
+	}
+	var selected []Backup
+	for _, b := range m.markedBackups() {
+		if isPinned(b) {
+			m.logf("%s %s Skipped %s: %v", iconError, yellow("INFO:"), b.Name, errPinned)
+			continue
+		}
+		selected = append(selected, b)
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	body := []string{"WARNING: This will permanently delete the selected backups!"}
+	for _, b := range selected {
+		body = append(body, " - "+b.Name)
+	}
+	profile := m.profile
+	return m.confirm("Are you sure? (y/N)", body, "Deletion cancelled.", func(m *tuiModel) tea.Cmd {
+		m.marked = map[string]bool{}
+		return m.run("Deleting", func() {
+			removeBackups(profile, selected)
+		}, nil)
+	})
+}
+
+// startCompare shows what restoring the selected backup would change, or,
+// with one other backup marked, what changed from that backup to this one.
+func (m *tuiModel) startCompare(b Backup) tea.Cmd {
+	profile := m.profile
+	var from *Backup
+	if marked := m.markedBackups(); len(m.marked) == 1 && marked[0].Name != b.Name {
+		from = &marked[0]
+	}
+	needed := b.Encrypted || (from != nil && from.Encrypted)
+	return m.withPassphrase(needed, false, func(m *tuiModel) tea.Cmd {
+		if from != nil {
+			return m.show("Comparing", from.Name+" → "+b.Name, func() {
+				if err := compareBackups(profile, *from, &b); err != nil {
+					fmt.Printf("%s %s Failed to compare: %v\n", iconError, red("ERROR:"), err)
+				}
+			})
+		}
+		return m.show("Comparing", "current save → "+b.Name, func() {
+			if err := compareWithSave(profile, b); err != nil {
+				fmt.Printf("%s %s Failed to compare: %v\n", iconError, red("ERROR:"), err)
+			}
+		})
+	})
+}
+
+// startUndo follows undoMenu.
+func (m *tuiModel) startUndo() tea.Cmd {
+	profile := m.profile
+	var (
+		last    RestoreRecord
+		backup  Backup
+		planned bool
+	)
+	return m.run("Checking history", func() {
+		var err error
+		if last, backup, err = planUndo(profile); err != nil {
+			fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+			return
+		}
+		if err := checkGameRunning(profile, profile.GameCheck.restorePolicy(), "restore"); err != nil {
+			fmt.Printf("%s %s %v\n", iconError, red("ERROR:"), err)
+			return
+		}
+		planned = true
+	}, func(m *tuiModel) tea.Cmd {
+		if !planned {
+			return nil
+		}
+		body := []string{fmt.Sprintf("This will put back the save as it was before %s was restored, from backup %s.", last.Backup, backup.Name)}
+		return m.confirm("Undo the last restore? (y/N)", body, "Undo cancelled.", func(m *tuiModel) tea.Cmd {
+			needed := backup.Encrypted || (profile.AutoBackup && profile.Encryption.Enabled)
+			return m.withPassphrase(needed, !backup.Encrypted, func(m *tuiModel) tea.Cmd {
+				return m.run("Undoing", func() {
+					finishUndo(profile, last)
+				}, func(m *tuiModel) tea.Cmd { return m.checkSave() })
+			})
+		})
+	})
+}
+
+// listRows is how many backups fit in the list pane.
+func (m *tuiModel) listRows() int {
+	return max(1, m.paneHeight()-3)
+}
+
+// paneHeight is the height of the list and detail panes, borders included.
+func (m *tuiModel) paneHeight() int {
+	return max(5, m.height-tuiLogLines-2)
+}
+
+func (m *tuiModel) View() string {
+	if m.width == 0 {
+		return "Loading..."
+	}
+	switch m.mode {
+	case tuiView:
+		title := tuiTitleStyle.Render(" " + m.viewTitle + " ")
+		footer := tuiStatusStyle.Width(m.width).Render(ansi.Truncate(fmt.Sprintf(" ↑/↓ scroll · esc close · %3.f%%", m.view.ScrollPercent()*100), m.width, "…"))
+		return lipgloss.JoinVertical(lipgloss.Left, title, m.view.View(), footer)
+	case tuiHelp:
+		return m.helpView()
+	}
+
+	listWidth := max(20, m.width*3/5)
+	detailWidth := max(10, m.width-listWidth)
+	height := m.paneHeight()
+
+	header := tuiTitleStyle.Render(" GAME SAVE BACKUP MANAGER ") + " " + ansi.Truncate(m.profile.Name+" · "+m.profile.SavePath, max(0, m.width-28), "…")
+
+	var right string
+	if m.mode == tuiModal && m.question != nil {
+		right = m.questionView(detailWidth-2, height-2)
+	} else {
+		right = m.detailView(detailWidth-2, height-2)
+	}
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		tuiPaneStyle.Width(listWidth-2).Height(height-2).Render(m.listView(listWidth-2, height-2)),
+		tuiPaneStyle.Width(detailWidth-2).Height(height-2).Render(right),
+	)
+
+	logLines := make([]string, tuiLogLines)
+	start := max(0, len(m.log)-tuiLogLines)
+	for i, line := range m.log[start:] {
+		logLines[i] = ansi.Truncate(line, m.width, "…")
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, panes, strings.Join(logLines, "\n"), m.statusView())
+}
+
+func (m *tuiModel) listView(width, height int) string {
+	source := "backup directory"
+	if m.source != "" {
+		source = "mirror " + m.source
+	}
+	title := fmt.Sprintf("Backups %d/%d · %s", len(m.visible), len(m.backups), source)
+	if len(m.marked) > 0 {
+		title += fmt.Sprintf(" · %d marked", len(m.marked))
+	}
+	lines := []string{tuiLabelStyle.Render(ansi.Truncate(title, width, "…"))}
+	if m.mode == tuiFilter || m.filter.Value() != "" {
+		lines[0] = ansi.Truncate(m.filter.View()+fmt.Sprintf("  %d/%d", len(m.visible), len(m.backups)), width, "…")
+	}
+	if m.filterErr != nil {
+		lines = append(lines, clipLines([]string{tuiDimStyle.Render(m.filterErr.Error())}, width, height-1))
+		return strings.Join(lines, "\n")
+	}
+
+	rows := height - 1
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.visible)-rows))
+	if len(m.visible) == 0 {
+		lines = append(lines, tuiDimStyle.Render("No backups found."))
+	}
+	for i := m.offset; i < len(m.visible) && i < m.offset+rows; i++ {
+		b := m.backups[m.visible[i]]
+		mark := " "
+		if m.marked[b.Name] {
+			mark = "●"
+		}
+		pin := " "
+		if isPinned(b) {
+			pin = "⚑"
+		}
+		line := ansi.Truncate(fmt.Sprintf("%s%s %s  %s", mark, pin, b.CreatedAt.Format("01/02/2006 03:04 PM"), b.Name), width, "…")
+		if i == m.cursor {
+			line = tuiCursorStyle.Render(line + strings.Repeat(" ", max(0, width-ansi.StringWidth(line))))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *tuiModel) detailView(width, height int) string {
+	b, ok := m.current()
+	if !ok && len(m.backups) > 0 {
+		return tuiDimStyle.Render("No backups match the filter.")
+	}
+	if !ok {
+		return tuiDimStyle.Render("Press n to create a backup.")
+	}
+	field := func(label, value string) string {
+		return tuiLabelStyle.Render(label+":") + " " + value
+	}
+	lines := []string{
+		tuiTitleStyle.Render(b.Name),
+		field("Created", b.CreatedAt.Format("01/02/2006 03:04:05 PM")),
+		field("Format", b.formatLabel()),
+	}
+	if b.Meta == nil {
+		lines = append(lines, tuiDimStyle.Render("No metadata recorded for this backup."))
+	} else {
+		lines = append(lines, field("Size", formatSize(b.Meta.Size)))
+		if b.Meta.Note != "" {
+			lines = append(lines, field("Note", b.Meta.Note))
+		}
+		if len(b.Meta.Tags) > 0 {
+			lines = append(lines, field("Tags", strings.Join(b.Meta.Tags, ", ")))
+		}
+		if b.Meta.Pinned {
+			lines = append(lines, field("Pinned", "yes"))
+		}
+		lines = append(lines,
+			field("Profile", b.Meta.Profile),
+			field("Source", b.Meta.SourcePath),
+			field("SHA-256", b.Meta.SHA256),
+			field("Tool version", b.Meta.ToolVersion),
+		)
+	}
+	return clipLines(lines, width, height)
+}
+
+func (m *tuiModel) questionView(width, height int) string {
+	q := m.question
+	lines := []string{tuiTitleStyle.Render(q.title), ""}
+	lines = append(lines, q.body...)
+	lines = append(lines, "")
+	if q.input != nil {
+		lines = append(lines, q.input.View(), "", tuiDimStyle.Render("enter confirm · esc cancel"))
+	} else {
+		lines = append(lines, tuiDimStyle.Render("y yes · n no"))
+	}
+	return clipLines(lines, width, height)
+}
+
+func (m *tuiModel) statusView() string {
+	left := m.profile.Name + " · save " + m.saveState
+	if m.busy != "" {
+		left = m.busy + "..."
+	}
+	var size int64
+	pinned := 0
+	for _, b := range m.backups {
+		if b.Meta != nil {
+			size += b.Meta.Size
+		}
+		if isPinned(b) {
+			pinned++
+		}
+	}
+	status := fmt.Sprintf(" %s │ %d backups, %s, %d pinned │ ? help · n new · r restore · d delete · c compare · / filter · q quit",
+		left, len(m.backups), formatSize(size), pinned)
+	return tuiStatusStyle.Width(m.width).Render(ansi.Truncate(status, m.width, "…"))
+}
+
+func (m *tuiModel) helpView() string {
+	keys := [][2]string{
+		{"↑/↓ j/k pgup/pgdn home/end", "Move through the backups"},
+		{"/", "Search and filter, e.g. boss after:7d size>1MB is:manual sort:size (esc clears)"},
+		{"space", "Mark a backup for delete or compare"},
+		{"n", "Create a backup"},
+		{"r", "Restore the selected backup over the save"},
+		{"t", "Restore the selected backup to another location"},
+		{"d", "Delete the marked backups, or the selected one"},
+		{"c", "Compare the selected backup with the save, or with the one marked backup"},
+		{"u", "Undo the last restore (needs auto-backup on restore)"},
+		{"p", "Pin or unpin the selected backup"},
+		{"e, enter", "Rename, annotate or pin the selected backup"},
+		{"x", "Export or import backups"},
+		{"m", "Switch the list between the backup directory and the mirrors"},
+		{"g", "Switch game"},
+		{"s", "Settings"},
+		{"R", "Reload the list"},
+		{"esc", "Clear the marks and the filter"},
+		{"q, ctrl+c", "Quit"},
+	}
+	lines := []string{tuiTitleStyle.Render(" KEYS "), ""}
+	for _, k := range keys {
+		lines = append(lines, "  "+tuiLabelStyle.Render(fmt.Sprintf("%-28s", k[0]))+" "+k[1])
+	}
+	lines = append(lines, "", tuiDimStyle.Render("  Press any key to go back. Run with the menu command for the classic menus."))
+	return clipLines(lines, m.width, m.height)
+}
+
+// clipLines wraps lines to width and cuts them to fit height.
+func clipLines(lines []string, width, height int) string {
+	wrapped := strings.Split(lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n")), "\n")
+	if len(wrapped) > height {
+		wrapped = wrapped[:max(0, height)]
+	}
+	return strings.Join(wrapped, "\n")
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestCaptureOutputConcurrently(t *testing.T) {
+	const n = 4
+	var wg sync.WaitGroup
+	got := make([][]string, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			captureOutput(func() {
+				for j := range 3 {
+					fmt.Printf("op %d line %d\n", i, j)
+				}
+			}, func(line string) { got[i] = append(got[i], line) })
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		want := []string{fmt.Sprintf("op %d line 0", i), fmt.Sprintf("op %d line 1", i), fmt.Sprintf("op %d line 2", i)}
+		if !slices.Equal(got[i], want) {
+			t.Errorf("op %d captured %q, want %q", i, got[i], want)
+		}
+	}
+}
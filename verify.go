@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// VerifyStatus is the outcome of checking one backup against its manifest.
+type VerifyStatus string
+
+const (
+	VerifyOK         VerifyStatus = "ok"
+	VerifyCorrupt    VerifyStatus = "corrupt"     // content no longer matches the recorded hash
+	VerifyTruncated  VerifyStatus = "truncated"   // smaller than the recorded size
+	VerifyMissing    VerifyStatus = "missing"     // manifest present but the backup is gone
+	VerifyUnverified VerifyStatus = "no-manifest" // made before hashes were recorded
+)
+
+// VerifyResult describes the integrity of a single backup.
+type VerifyResult struct {
+	Name   string       `json:"name"`
+	Path   string       `json:"path"`
+	Status VerifyStatus `json:"status"`
+	Detail string       `json:"detail"`
+}
+
+// errBackupDamaged is returned when restoring a backup that failed verification.
+var errBackupDamaged = errors.New("backup failed integrity check")
+
+// errAutoBackupFailed is returned when the current save couldn't be backed up
+// before a restore, so the restore was stopped rather than made impossible to
+// undo.
+var errAutoBackupFailed = errors.New("the current save could not be backed up, so it was not overwritten")
+
+// healthy reports whether the backup can be restored without forcing.
+func (r VerifyResult) healthy() bool {
+	return r.Status == VerifyOK || r.Status == VerifyUnverified
+}
+
+// verifyBackup re-hashes a backup and compares it with its manifest.
+func verifyBackup(b Backup) VerifyResult {
+	result := VerifyResult{Name: b.Name, Path: b.Path}
+	if b.MetaErr != nil {
+		result.Status = VerifyCorrupt
+		result.Detail = b.MetaErr.Error()
+		return result
+	}
+	if b.Meta == nil {
+		result.Status = VerifyUnverified
+		result.Detail = "no manifest recorded"
+		return result
+	}
+
+	sum, size, err := hashStored(b.storage, b.Key)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		result.Status = VerifyMissing
+		result.Detail = "backup data not found"
+	case err != nil:
+		result.Status = VerifyCorrupt
+		result.Detail = err.Error()
+	case size < b.Meta.Size:
+		result.Status = VerifyTruncated
+		result.Detail = fmt.Sprintf("%s of %s", formatSize(size), formatSize(b.Meta.Size))
+	case sum != b.Meta.SHA256:
+		result.Status = VerifyCorrupt
+		result.Detail = "SHA-256 mismatch"
+	case b.Format == FormatStore:
+		// The snapshot itself is intact; its content lives in shared blobs.
+		result.Status, result.Detail = verifySnapshotBlobs(b.storage, b.Key)
+	default:
+		result.Status = VerifyOK
+	}
+	return result
+}
+
+// verifyStore checks every backup in the profile's backup directory, including
+// manifests whose backup has disappeared.
+func verifyStore(profile Profile) ([]VerifyResult, error) {
+	backups, err := listBackupsInternal(profile)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]VerifyResult, 0, len(backups))
+	known := make(map[string]bool, len(backups))
+	for _, b := range backups {
+		known[b.Key] = true
+		results = append(results, verifyBackup(b))
+	}
+
+	st, err := openStorage(profile)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := st.List("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir || !strings.HasSuffix(entry.Name, manifestSuffix) {
+			continue
+		}
+		key := strings.TrimSuffix(entry.Name, manifestSuffix)
+		if known[key] {
+			continue
+		}
+		b := newBackup(st, key, key, "", false, time.Time{}, nil)
+		if meta, err := readManifest(st, key); err == nil {
+			b.Name = meta.Name
+		}
+		results = append(results, VerifyResult{Name: b.Name, Path: b.Path, Status: VerifyMissing, Detail: "backup data not found"})
+	}
+	return results, nil
+}
+
+// verifyStatusColor colours a status for terminal output.
+func verifyStatusColor(status VerifyStatus) string {
+	switch status {
+	case VerifyOK:
+		return green(string(status))
+	case VerifyUnverified:
+		return yellow(string(status))
+	default:
+		return red(string(status))
+	}
+}
+
+func verifyBackups(profile Profile) {
+	clearScreen()
+	fmt.Println(cyan("====================================="))
+	fmt.Printf("%s %s VERIFY BACKUPS\n", iconSettings, cyan("VERIFY BACKUPS"))
+	fmt.Println(cyan("====================================="))
+	fmt.Println()
+
+	results, err := verifyStore(profile)
+	if err != nil {
+		fmt.Printf("%s %s Failed to verify backups: %v\n", iconError, red("ERROR:"), err)
+		waitForEnter()
+		return
+	}
+	if len(results) == 0 {
+		fmt.Printf("%s %s No backups found.\n", iconError, red("INFO:"))
+		waitForEnter()
+		return
+	}
+
+	problems := 0
+	for _, r := range results {
+		line := fmt.Sprintf(" - %s: %s", r.Name, verifyStatusColor(r.Status))
+		if r.Detail != "" && r.Status != VerifyOK {
+			line += " (" + r.Detail + ")"
+		}
+		fmt.Println(line)
+		if !r.healthy() {
+			problems++
+		}
+	}
+	fmt.Println()
+	if problems > 0 {
+		fmt.Printf("%s %s %d of %d backup(s) are damaged or missing.\n", iconError, red("WARNING:"), problems, len(results))
+	} else {
+		fmt.Printf("%s %s All %d backup(s) passed verification.\n", iconSuccess, green("SUCCESS:"), len(results))
+	}
+	waitForEnter()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestDamagedManifestIsReportedAsCorrupt(t *testing.T) {
+	profile := newTestProfile(t)
+	backup, err := writeBackup(profile, "boss", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Truncate the manifest as a crash mid-write would.
+	manifest := manifestPath(backup.Path)
+	data := readTestFile(t, manifest)
+	if err := os.WriteFile(manifest, []byte(data[:len(data)/2]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	damaged, err := findBackup(profile, "boss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if damaged.MetaErr == nil {
+		t.Fatal("listing kept no error for the damaged manifest")
+	}
+	if result := verifyBackup(damaged); result.Status != VerifyCorrupt {
+		t.Errorf("verify: got %s (%s), want %s", result.Status, result.Detail, VerifyCorrupt)
+	}
+
+	writeTestFile(t, profile.SavePath, "slot 2")
+	if _, err := applyBackup(profile, damaged, false); !errors.Is(err, errBackupDamaged) {
+		t.Errorf("restore: got %v, want errBackupDamaged", err)
+	}
+	if got := readTestFile(t, profile.SavePath); got != "slot 2" {
+		t.Errorf("save overwritten with %q by a refused restore", got)
+	}
+	if _, err := editBackup(profile, damaged, func(m *Manifest) { m.Pinned = true }); !errors.Is(err, errBackupDamaged) {
+		t.Errorf("pin: got %v, want errBackupDamaged instead of a rewritten manifest", err)
+	}
+}
+
+func TestBackupWithoutManifestIsUnverified(t *testing.T) {
+	profile := newTestProfile(t)
+	backup, err := writeBackup(profile, "legacy", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(manifestPath(backup.Path)); err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := findBackup(profile, "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.MetaErr != nil {
+		t.Errorf("missing manifest reported as %v", legacy.MetaErr)
+	}
+	if result := verifyBackup(legacy); result.Status != VerifyUnverified {
+		t.Errorf("verify: got %s, want %s", result.Status, VerifyUnverified)
+	}
+}
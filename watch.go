@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Defaults used when a profile doesn't set its own watch timings.
+const (
+	defaultWatchDebounce     = 5 * time.Second
+	defaultWatchMinInterval  = time.Minute
+	defaultWatchPollInterval = 2 * time.Second
+)
+
+// WatchSettings control how watch mode turns save changes into backups.
+// Durations use Go syntax such as "10s" or "5m"; empty means the default.
+type WatchSettings struct {
+	Debounce    string `json:"debounce,omitempty"`     // quiet time after the last change before backing up
+	MinInterval string `json:"min_interval,omitempty"` // minimum time between two watch backups
+}
+
+// watchOptions are the resolved settings for one watch session.
+type watchOptions struct {
+	Debounce     time.Duration
+	MinInterval  time.Duration
+	Poll         bool // use polling even if filesystem notifications work
+	PollInterval time.Duration
+}
+
+// parseWatchDuration parses a watch setting, falling back to def when empty.
+func parseWatchDuration(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q (use values like 10s or 5m)", value)
+	}
+	return d, nil
+}
+
+// options resolves the settings, filling in defaults.
+func (s WatchSettings) options() (watchOptions, error) {
+	debounce, err := parseWatchDuration(s.Debounce, defaultWatchDebounce)
+	if err != nil {
+		return watchOptions{}, fmt.Errorf("watch debounce: %w", err)
+	}
+	minInterval, err := parseWatchDuration(s.MinInterval, defaultWatchMinInterval)
+	if err != nil {
+		return watchOptions{}, fmt.Errorf("watch minimum interval: %w", err)
+	}
+	return watchOptions{Debounce: debounce, MinInterval: minInterval, PollInterval: defaultWatchPollInterval}, nil
+}
+
+// describeWatch renders watch settings for display.
+func describeWatch(s WatchSettings) string {
+	opts, err := s.options()
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("debounce %s, minimum interval %s", opts.Debounce, opts.MinInterval)
+}
+
+// watchLog prints a timestamped watch-mode message.
+func watchLog(icon, label, format string, args ...any) {
+	fmt.Printf("[%s] %s %s %s\n", time.Now().Format("15:04:05"), icon, label, fmt.Sprintf(format, args...))
+}
+
+// watchSave monitors the profile's save file or directory until ctx is done and
+// takes a backup once changes have settled for opts.Debounce, never more often
+// than opts.MinInterval.
+func watchSave(ctx context.Context, profile Profile, opts watchOptions) error {
+	if _, err := os.Stat(profile.SavePath); err != nil {
+		return fmt.Errorf("cannot watch save: %w", err)
+	}
+
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	if opts.Poll {
+		go pollSave(ctx, profile, opts.PollInterval, notify)
+		watchLog(iconInfo, white("INFO:"), "Polling %s every %s", profile.SavePath, opts.PollInterval)
+	} else if err := notifySave(ctx, profile, notify); err != nil {
+		watchLog(iconInfo, yellow("INFO:"), "File notifications unavailable (%v), polling every %s instead", err, opts.PollInterval)
+		go pollSave(ctx, profile, opts.PollInterval, notify)
+	} else {
+		watchLog(iconInfo, white("INFO:"), "Watching %s for changes", profile.SavePath)
+	}
+	watchLog(iconInfo, white("INFO:"), "Debounce %s, minimum interval %s. Press Ctrl+C to stop.", opts.Debounce, opts.MinInterval)
+
+	var lastBackup time.Time
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	pending := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			watchLog(iconExit, yellow("INFO:"), "Watch stopped.")
+			return nil
+		case <-changes:
+			// Every change restarts the quiet period.
+			pending = true
+			timer.Reset(opts.Debounce)
+		case <-timer.C:
+			if !pending {
+				continue
+			}
+			if wait := opts.MinInterval - time.Since(lastBackup); !lastBackup.IsZero() && wait > 0 {
+				timer.Reset(wait)
+				continue
+			}
+			pending = false
+			if dup, skip := shouldSkipAutoBackup(profile); skip {
+				watchLog(iconInfo, white("INFO:"), "Save is unchanged since %s, no backup needed.", dup.Name)
+				continue
+			}
+			lastBackup = time.Now()
+			name := fmt.Sprintf("Watch_%s", lastBackup.Format("2006-01-02_15-04-05"))
+			backup, err := writeBackup(profile, name, "Automatic backup after the save changed", []string{"watch"})
+			if err != nil {
+				watchLog(iconError, red("ERROR:"), "Failed to create backup: %v", err)
+				continue
+			}
+			watchLog(iconSuccess, green("SUCCESS:"), "Backup created: %s (%s)", backup.Name, formatSize(backup.Meta.Size))
+			reportAutoPrune(profile)
+			reportMirrors(profile)
+		}
+	}
+}
+
+// notifySave uses filesystem notifications to report changes to the save. A
+// save file is watched through its parent directory so games that replace the
+// file by renaming a new one over it are still noticed.
+func notifySave(ctx context.Context, profile Profile, notify func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(profile.SavePath)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+	isDir := info.IsDir()
+	if isDir {
+		err = addWatchTree(watcher, profile, profile.SavePath)
+	} else {
+		err = watcher.Add(filepath.Dir(profile.SavePath))
+	}
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !isDir {
+					if filepath.Clean(event.Name) == filepath.Clean(profile.SavePath) {
+						notify()
+					}
+					continue
+				}
+				rel, err := filepath.Rel(profile.SavePath, event.Name)
+				if err != nil {
+					continue
+				}
+				rel = filepath.ToSlash(rel)
+				if event.Has(fsnotify.Create) {
+					if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+						addWatchTree(watcher, profile, event.Name)
+						notify()
+						continue
+					}
+				}
+				if includeFile(profile, rel) {
+					notify()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				watchLog(iconError, red("ERROR:"), "Watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// addWatchTree adds root and every non-excluded directory below it to the watcher.
+func addWatchTree(watcher *fsnotify.Watcher, profile Profile, root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != profile.SavePath {
+			rel, err := filepath.Rel(profile.SavePath, p)
+			if err == nil && matchesAny(profile.Exclude, filepath.ToSlash(rel)) {
+				return filepath.SkipDir
+			}
+		}
+		return watcher.Add(p)
+	})
+}
+
+// pollSave checks the save's fingerprint every interval and reports changes.
+// It is the fallback when filesystem notifications aren't available.
+func pollSave(ctx context.Context, profile Profile, interval time.Duration, notify func()) {
+	last := saveFingerprint(profile)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if current := saveFingerprint(profile); current != last {
+				last = current
+				notify()
+			}
+		}
+	}
+}
+
+// saveFingerprint returns the save's current fingerprint, or 0 if it can't be read.
+func saveFingerprint(profile Profile) uint64 {
+	fp, _, _ := saveSnapshot(profile)
+	return fp
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchDebounce(t *testing.T) {
+	profile := newTestProfile(t)
+	opts := watchOptions{Debounce: 300 * time.Millisecond, Poll: true, PollInterval: 10 * time.Millisecond}
+	watchBackups := func() int {
+		n := 0
+		for _, name := range backupNames(t, profile) {
+			if strings.HasPrefix(name, "Watch_") {
+				n++
+			}
+		}
+		return n
+	}
+	// waitFor polls until cond holds or the timeout passes.
+	waitFor := func(timeout time.Duration, cond func() bool) bool {
+		for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if cond() {
+				return true
+			}
+		}
+		return cond()
+	}
+
+	output := captureStdout(t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- watchSave(ctx, profile, opts) }()
+		defer func() {
+			cancel()
+			if err := <-done; err != nil {
+				t.Error(err)
+			}
+		}()
+
+		// A burst of writes, each well within the debounce, makes one backup.
+		for i := range 5 {
+			writeTestFile(t, profile.SavePath, fmt.Sprintf("autosave %d", i))
+			time.Sleep(100 * time.Millisecond)
+		}
+		if n := watchBackups(); n != 0 {
+			t.Fatalf("got %d backups during the burst, want none until it settles", n)
+		}
+		if !waitFor(2*time.Second, func() bool { return watchBackups() == 1 }) {
+			t.Fatalf("got %d backups after the burst settled, want 1", watchBackups())
+		}
+
+		// Quiet afterwards means no more backups; the next change makes one more.
+		time.Sleep(2 * opts.Debounce)
+		if n := watchBackups(); n != 1 {
+			t.Fatalf("got %d backups while the save was untouched, want 1", n)
+		}
+		time.Sleep(time.Second) // backup names have a resolution of one second
+		writeTestFile(t, profile.SavePath, "manual save")
+		if !waitFor(2*time.Second, func() bool { return watchBackups() == 2 }) {
+			t.Fatalf("got %d backups after another change, want 2", watchBackups())
+		}
+	})
+	if !strings.Contains(output, "Backup created: Watch_") {
+		t.Errorf("got output %q, want the backups reported", output)
+	}
+}
+
+func TestWatchMinInterval(t *testing.T) {
+	profile := newTestProfile(t)
+	opts := watchOptions{Debounce: 50 * time.Millisecond, MinInterval: time.Hour, Poll: true, PollInterval: 10 * time.Millisecond}
+
+	captureStdout(t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- watchSave(ctx, profile, opts) }()
+
+		writeTestFile(t, profile.SavePath, "autosave 1")
+		time.Sleep(500 * time.Millisecond)
+		writeTestFile(t, profile.SavePath, "autosave 2")
+		time.Sleep(500 * time.Millisecond)
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	if got := backupNames(t, profile); len(got) != 1 {
+		t.Errorf("got backups %v, want one within the minimum interval", got)
+	}
+}